@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// addressResolver 将Exchange旧式DN（X.500地址，如 /O=EXCHANGELABS/OU=.../CN=RECIPIENTS/CN=...）转换为SMTP地址。
+// Outlook数据源在读取时通过COM解析；.pst、.msg、CSV等离线数据源使用映射文件，
+// 映射文件中也没有的地址保留原样并在报告中列出
+type addressResolver struct {
+	mapping    map[string]string // 规范化的DN -> SMTP地址
+	unresolved map[string]*unresolvedAddress
+}
+
+// unresolvedAddress 是无法解析的DN，Name为邮件中的显示名，便于补充映射
+type unresolvedAddress struct {
+	DN    string
+	Name  string
+	Count int
+}
+
+// 映射文件中DN列和SMTP地址列的列名（经normalizeCSVHeader处理），
+// 如 Get-Recipient | Select LegacyExchangeDN,PrimarySmtpAddress | Export-Csv 的输出
+var (
+	addressMapDNColumns   = []string{"legacyexchangedn", "legacydn", "exchangedn", "x500", "dn"}
+	addressMapSMTPColumns = []string{"primarysmtpaddress", "smtpaddress", "smtp", "windowsemailaddress", "mail", "emailaddress", "email"}
+)
+
+// maxUnresolvedShown 是报告中最多列出的无法解析的地址数
+const maxUnresolvedShown = 10
+
+func newAddressResolver() *addressResolver {
+	return &addressResolver{mapping: make(map[string]string), unresolved: make(map[string]*unresolvedAddress)}
+}
+
+// isExchangeDN 判断地址是否为Exchange旧式DN，可带 EX: 或 X500: 前缀
+func isExchangeDN(address string) bool {
+	return strings.HasPrefix(normalizeDN(address), "/o=")
+}
+
+func normalizeDN(address string) string {
+	address = strings.ToLower(strings.TrimSpace(address))
+	for _, prefix := range []string{"x500:", "ex:"} {
+		address = strings.TrimPrefix(address, prefix)
+	}
+	return address
+}
+
+// loadAddressMap 读取DN到SMTP地址的映射文件（CSV）。有可识别的表头时按列名取值，
+// 否则每行的前两列为DN和SMTP地址
+func loadAddressMap(path string) (*addressResolver, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取地址映射文件失败: %v", err)
+	}
+	reader := csv.NewReader(strings.NewReader(decodeCSVText(data)))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("地址映射文件 %s 无效: %v", path, err)
+	}
+
+	dnColumn, smtpColumn := 0, 1
+	if len(records) > 0 {
+		header := make(map[string]int)
+		for i, name := range records[0] {
+			header[normalizeCSVHeader(name)] = i
+		}
+		dn, hasDN := findColumn(header, addressMapDNColumns)
+		smtp, hasSMTP := findColumn(header, addressMapSMTPColumns)
+		if hasDN && hasSMTP {
+			dnColumn, smtpColumn = dn, smtp
+			records = records[1:]
+		}
+	}
+
+	resolver := newAddressResolver()
+	for _, record := range records {
+		if dnColumn >= len(record) || smtpColumn >= len(record) {
+			continue
+		}
+		dn, smtp := record[dnColumn], strings.TrimSpace(record[smtpColumn])
+		if isExchangeDN(dn) && strings.Contains(smtp, "@") {
+			resolver.mapping[normalizeDN(dn)] = strings.TrimPrefix(strings.TrimPrefix(smtp, "SMTP:"), "smtp:")
+		}
+	}
+	if len(resolver.mapping) == 0 {
+		return nil, fmt.Errorf("地址映射文件 %s 中没有有效的DN和SMTP地址", path)
+	}
+	return resolver, nil
+}
+
+func findColumn(header map[string]int, names []string) (int, bool) {
+	for _, name := range names {
+		if i, ok := header[name]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// resolve 返回DN对应的SMTP地址；不是DN的地址原样返回，无法解析的DN记录下来并原样返回
+func (r *addressResolver) resolve(address, name string) string {
+	if !isExchangeDN(address) {
+		return address
+	}
+	key := normalizeDN(address)
+	if smtp, ok := r.mapping[key]; ok {
+		return smtp
+	}
+	entry := r.unresolved[key]
+	if entry == nil {
+		entry = &unresolvedAddress{DN: strings.TrimSpace(address), Name: name}
+		r.unresolved[key] = entry
+	}
+	if entry.Name == "" {
+		entry.Name = name
+	}
+	entry.Count++
+	return address
+}
+
+// resolveEmails 转换发件人、收件人列表和退信地址中的DN
+func (r *addressResolver) resolveEmails(emails []EmailInfo) {
+	for i := range emails {
+		email := &emails[i]
+		email.SenderEmail = r.resolve(email.SenderEmail, email.SenderName)
+		email.To = r.resolveList(email.To)
+		email.CC = r.resolveList(email.CC)
+		for j, recipient := range email.Recipients {
+			email.Recipients[j].Address = r.resolve(recipient.Address, recipient.Name)
+		}
+		for j, address := range email.BouncedRecipients {
+			email.BouncedRecipients[j] = r.resolve(address, "")
+		}
+	}
+}
+
+// resolveList 转换分号分隔的收件人列表中的DN，列表中只有显示名时不做改动
+func (r *addressResolver) resolveList(list string) string {
+	if !strings.Contains(strings.ToLower(list), "/o=") {
+		return list
+	}
+	parts := strings.Split(list, ";")
+	for i, part := range parts {
+		parts[i] = r.resolve(strings.TrimSpace(part), "")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// unresolvedAddresses 返回无法解析的DN，按出现次数从多到少排列
+func (r *addressResolver) unresolvedAddresses() []unresolvedAddress {
+	var list []unresolvedAddress
+	for _, entry := range r.unresolved {
+		list = append(list, *entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].DN < list[j].DN
+	})
+	return list
+}
+
+func printUnresolvedAddresses(list []unresolvedAddress) {
+	if len(list) == 0 {
+		return
+	}
+	fmt.Printf("⚠️  有 %d 个Exchange内部地址无法转换为SMTP地址，统计中将按原DN显示\n", len(list))
+	fmt.Println("   可用 -address-map 指定映射文件（CSV，含LegacyExchangeDN和PrimarySmtpAddress列）:")
+	for i, entry := range list {
+		if i == maxUnresolvedShown {
+			fmt.Printf("   ... 另有 %d 个\n", len(list)-maxUnresolvedShown)
+			break
+		}
+		name := entry.Name
+		if name == "" {
+			name = "(无显示名)"
+		}
+		fmt.Printf("   - %s: %s (%d 次)\n", name, entry.DN, entry.Count)
+	}
+}
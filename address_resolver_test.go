@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	zhangsanDN = "/o=ExchangeLabs/ou=Exchange Administrative Group (FYDIBOHF23SPDLT)/cn=Recipients/cn=zhangsan"
+	lisiDN     = "/O=EXCHANGELABS/OU=EXCHANGE ADMINISTRATIVE GROUP (FYDIBOHF23SPDLT)/CN=RECIPIENTS/CN=LISI"
+	wangwuDN   = "/o=ExchangeLabs/ou=Exchange Administrative Group (FYDIBOHF23SPDLT)/cn=Recipients/cn=wangwu"
+)
+
+func writeAddressMap(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "address-map.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadAddressMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{"Get-Recipient export with header",
+			"\ufeff\"DisplayName\",\"PrimarySmtpAddress\",\"LegacyExchangeDN\"\r\n" +
+				"\"张三\",\"zhangsan@example.com\",\"" + zhangsanDN + "\"\r\n" +
+				"\"共享邮箱\",\"\",\"" + lisiDN + "\"\r\n",
+			map[string]string{strings.ToLower(zhangsanDN): "zhangsan@example.com"}},
+		{"headerless two columns with prefixes and comments",
+			"# 手工整理\n" +
+				"X500:" + zhangsanDN + ",SMTP:zhangsan@example.com\n" +
+				"EX:" + lisiDN + ",lisi@example.com\n" +
+				"not a dn,nobody@example.com\n" +
+				"short\n",
+			map[string]string{strings.ToLower(zhangsanDN): "zhangsan@example.com", strings.ToLower(lisiDN): "lisi@example.com"}},
+		{"alternative header names",
+			"X500,Mail\n" + lisiDN + ",lisi@example.com\n",
+			map[string]string{strings.ToLower(lisiDN): "lisi@example.com"}},
+	}
+	for _, tt := range tests {
+		resolver, err := loadAddressMap(writeAddressMap(t, tt.content))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(resolver.mapping) != len(tt.want) {
+			t.Errorf("%s: mapping = %v, want %v", tt.name, resolver.mapping, tt.want)
+		}
+		for dn, smtp := range tt.want {
+			if resolver.mapping[dn] != smtp {
+				t.Errorf("%s: mapping[%q] = %q, want %q", tt.name, dn, resolver.mapping[dn], smtp)
+			}
+		}
+	}
+
+	if _, err := loadAddressMap(writeAddressMap(t, "name,email\n张三,zhangsan@example.com\n")); err == nil || !strings.Contains(err.Error(), "没有有效的DN") {
+		t.Errorf("map without DNs: err = %v", err)
+	}
+	if _, err := loadAddressMap(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("missing file loaded")
+	}
+}
+
+func TestIsExchangeDN(t *testing.T) {
+	tests := map[string]bool{
+		zhangsanDN:             true,
+		"  EX:" + lisiDN:       true,
+		"x500:" + wangwuDN:     true,
+		"zhangsan@example.com": false,
+		"":                     false,
+		"张三":                   false,
+	}
+	for address, want := range tests {
+		if got := isExchangeDN(address); got != want {
+			t.Errorf("isExchangeDN(%q) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestResolveEmails(t *testing.T) {
+	resolver, err := loadAddressMap(writeAddressMap(t, "LegacyExchangeDN,PrimarySmtpAddress\n"+zhangsanDN+",zhangsan@example.com\n"+lisiDN+",lisi@example.com\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	emails := []EmailInfo{
+		// DN大小写与映射文件不同
+		{SenderName: "张三", SenderEmail: strings.ToUpper(zhangsanDN), To: "EX:" + strings.ToLower(lisiDN), CC: "王五",
+			Recipients: []Recipient{{Name: "李四", Address: "EX:" + strings.ToLower(lisiDN), Type: RecipientTo}, {Name: "王五", Address: wangwuDN, Type: RecipientCC}}},
+		{SenderName: "王五", SenderEmail: wangwuDN, To: zhangsanDN + "; " + wangwuDN},
+		{SenderEmail: "external@example.org", To: "张三"},
+	}
+	resolver.resolveEmails(emails)
+
+	if emails[0].SenderEmail != "zhangsan@example.com" || emails[0].To != "lisi@example.com" || emails[0].CC != "王五" {
+		t.Errorf("first email = %+v", emails[0])
+	}
+	if emails[0].Recipients[0].Address != "lisi@example.com" || emails[0].Recipients[1].Address != wangwuDN {
+		t.Errorf("recipients = %+v", emails[0].Recipients)
+	}
+	if emails[1].SenderEmail != wangwuDN || emails[1].To != "zhangsan@example.com; "+wangwuDN {
+		t.Errorf("second email = %+v", emails[1])
+	}
+	if emails[2].SenderEmail != "external@example.org" || emails[2].To != "张三" {
+		t.Errorf("third email = %+v", emails[2])
+	}
+
+	// 王五出现3次，显示名取自第一个带名称的位置
+	unresolved := resolver.unresolvedAddresses()
+	if len(unresolved) != 1 || unresolved[0].DN != wangwuDN || unresolved[0].Name != "王五" || unresolved[0].Count != 3 {
+		t.Errorf("unresolved = %+v", unresolved)
+	}
+
+	output := captureStdout(t, func() { printUnresolvedAddresses(unresolved) })
+	if !strings.Contains(output, "有 1 个Exchange内部地址") || !strings.Contains(output, "- 王五: "+wangwuDN+" (3 次)") {
+		t.Errorf("report = %q", output)
+	}
+	if output := captureStdout(t, func() { printUnresolvedAddresses(nil) }); output != "" {
+		t.Errorf("empty report = %q", output)
+	}
+}
+
+func TestUnresolvedAddressesReportLimit(t *testing.T) {
+	resolver := newAddressResolver()
+	for i := 0; i < maxUnresolvedShown+2; i++ {
+		dn := "/o=corp/cn=recipients/cn=user" + string(rune('a'+i))
+		for j := 0; j <= i; j++ {
+			resolver.resolve(dn, "")
+		}
+	}
+	unresolved := resolver.unresolvedAddresses()
+	if len(unresolved) != maxUnresolvedShown+2 || unresolved[0].Count != maxUnresolvedShown+2 || !strings.HasSuffix(unresolved[0].DN, "userl") {
+		t.Fatalf("unresolved = %+v", unresolved)
+	}
+	output := captureStdout(t, func() { printUnresolvedAddresses(unresolved) })
+	if !strings.Contains(output, "(无显示名)") || !strings.Contains(output, "... 另有 2 个") || strings.Contains(output, "usera") {
+		t.Errorf("report = %q", output)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// attentionRole 是收到的邮件中“我”的收件人身份
+type attentionRole int
+
+const (
+	roleDirect attentionRole = iota // 在收件人(To)中
+	roleCC                          // 在抄送(CC)中
+	roleBCC                         // 在密送中，或邮件没有公开收件人
+	roleList                        // 不在收件人和抄送中，通过分发列表或邮件列表收到
+)
+
+var attentionRoleNames = [...]string{roleDirect: "直接收件 (To)", roleCC: "抄送 (CC)", roleBCC: "密送/未公开收件人", roleList: "分发列表/邮件列表"}
+
+func (r attentionRole) String() string {
+	return attentionRoleNames[r]
+}
+
+// attentionGroup 汇总同一身份的邮件数、已读数和已回复数
+type attentionGroup struct {
+	Count     int
+	ReadCount int
+	Replied   int
+}
+
+func (g attentionGroup) readRate() float64 {
+	if g.Count == 0 {
+		return 0
+	}
+	return float64(g.ReadCount) / float64(g.Count) * 100
+}
+
+func (g attentionGroup) replyRate() float64 {
+	if g.Count == 0 {
+		return 0
+	}
+	return float64(g.Replied) / float64(g.Count) * 100
+}
+
+// distributionList 是通过它收到邮件的分发列表或邮件列表
+type distributionList struct {
+	Name   string
+	Count  int
+	Unread int
+}
+
+type attentionStats struct {
+	Known   bool // 能否确定“我”的地址
+	HasSent bool // 有发送邮件数据时才统计回复率
+	Roles   [len(attentionRoleNames)]attentionGroup
+	Lists   []distributionList // 按未读邮件数从多到少排列
+}
+
+// maxDistributionListsShown 是报告中最多列出的分发列表数
+const maxDistributionListsShown = 10
+
+// myIdentities 返回“我”对应的联系人：输入的邮箱地址以及发送邮件的发件人。
+// 使用默认账户时加上数据源提供的当前用户（Outlook的Session.CurrentUser）
+func (oa *OutlookEmailAnalyzer) myIdentities(account string, sentEmails []EmailInfo) map[string]bool {
+	identities := make(map[string]bool)
+	if strings.Contains(account, "@") {
+		identities[oa.contacts.identity("", account)] = true
+	} else if provider, ok := oa.source.(currentUserProvider); ok {
+		if identity := oa.contacts.identity(provider.currentUser()); identity != "" {
+			identities[identity] = true
+		}
+	}
+	for _, email := range sentEmails {
+		if identity := oa.contacts.senderIdentity(email); identity != "" {
+			identities[identity] = true
+		}
+	}
+	return identities
+}
+
+// attentionRoleOf 根据收件人列表判断邮件是直接发给我、抄送我，还是通过密送或分发列表收到。
+// 不在收件人和抄送中时，有公开的收件人视为通过分发列表收到（也可能是密送，无法区分），否则视为密送
+func (oa *OutlookEmailAnalyzer) attentionRoleOf(email EmailInfo, me map[string]bool) attentionRole {
+	cc, bcc := false, false
+	for _, recipient := range email.recipientsOf() {
+		if !me[oa.contacts.identity(recipient.Name, recipient.Address)] {
+			continue
+		}
+		switch recipient.Type {
+		case RecipientTo:
+			return roleDirect
+		case RecipientCC:
+			cc = true
+		case RecipientBCC:
+			bcc = true
+		}
+	}
+	switch {
+	case cc:
+		return roleCC
+	case bcc:
+		return roleBCC
+	case email.ListID == "" && len(email.recipientsOf(RecipientTo, RecipientCC)) == 0:
+		return roleBCC
+	}
+	return roleList
+}
+
+// distributionListNames 返回通过分发列表收到的邮件所经的列表：有List-Id时为邮件列表，
+// 否则为数据源标记为分发列表的收件人和抄送。没有标记时无法区分个人和列表，不计入
+func (oa *OutlookEmailAnalyzer) distributionListNames(email EmailInfo) []string {
+	if email.ListID != "" {
+		_, name := oa.bulkListKey(email)
+		return []string{name}
+	}
+	var lists []Recipient
+	for _, recipient := range email.recipientsOf(RecipientTo, RecipientCC) {
+		if recipient.DistList {
+			lists = append(lists, recipient)
+		}
+	}
+	var names []string
+	for _, identity := range oa.contacts.recipientIdentities(lists) {
+		names = append(names, oa.contacts.label(identity))
+	}
+	return names
+}
+
+// analyzeAttention 按“我”在收件人中的身份统计收到邮件的已读率和回复率，并找出产生未读邮件最多的分发列表
+func (oa *OutlookEmailAnalyzer) analyzeAttention(account string, receivedEmails, sentEmails []EmailInfo) attentionStats {
+	var stats attentionStats
+	me := oa.myIdentities(account, sentEmails)
+	if len(me) == 0 {
+		return stats
+	}
+	stats.Known = true
+	stats.HasSent = len(sentEmails) > 0
+
+	replied := make(map[int]bool)
+	for _, original := range matchReplies(receivedEmails, sentEmails) {
+		if original >= 0 {
+			replied[original] = true
+		}
+	}
+
+	lists := make(map[string]*distributionList)
+	for i, email := range receivedEmails {
+		role := oa.attentionRoleOf(email, me)
+		group := &stats.Roles[role]
+		group.Count++
+		if email.IsRead {
+			group.ReadCount++
+		}
+		if replied[i] {
+			group.Replied++
+		}
+		if role != roleList {
+			continue
+		}
+		for _, name := range oa.distributionListNames(email) {
+			list := lists[name]
+			if list == nil {
+				list = &distributionList{Name: name}
+				lists[name] = list
+			}
+			list.Count++
+			if !email.IsRead {
+				list.Unread++
+			}
+		}
+	}
+
+	for _, list := range lists {
+		if list.Unread > 0 {
+			stats.Lists = append(stats.Lists, *list)
+		}
+	}
+	sort.Slice(stats.Lists, func(i, j int) bool {
+		if stats.Lists[i].Unread != stats.Lists[j].Unread {
+			return stats.Lists[i].Unread > stats.Lists[j].Unread
+		}
+		return stats.Lists[i].Name < stats.Lists[j].Name
+	})
+	return stats
+}
+
+func (oa *OutlookEmailAnalyzer) printAttention(stats attentionStats) {
+	fmt.Printf("\n🎯 11. 直接收件与抄送:\n")
+	if !stats.Known {
+		fmt.Printf("   无法确定您的邮箱地址（没有发送邮件数据，也未输入邮箱地址），跳过\n")
+		return
+	}
+	for role, group := range stats.Roles {
+		if group.Count == 0 {
+			continue
+		}
+		fmt.Printf("   %s: %d 封，已读 %.1f%%", attentionRole(role), group.Count, group.readRate())
+		if stats.HasSent {
+			fmt.Printf("，已回复 %.1f%%", group.replyRate())
+		}
+		fmt.Println()
+	}
+
+	if len(stats.Lists) > 0 {
+		fmt.Printf("   未读邮件最多的分发列表:\n")
+		for i, list := range stats.Lists {
+			if i == maxDistributionListsShown {
+				fmt.Printf("   ... 另有 %d 个\n", len(stats.Lists)-maxDistributionListsShown)
+				break
+			}
+			fmt.Printf("     %d. %s: 未读 %d / %d 封\n", i+1, list.Name, list.Unread, list.Count)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// currentUserSource 是提供当前用户的数据源，如Outlook的默认账户
+type currentUserSource struct {
+	stubMailSource
+	name, address string
+}
+
+func (s *currentUserSource) currentUser() (string, string) {
+	return s.name, s.address
+}
+
+func TestAnalyzeAttention(t *testing.T) {
+	me := Recipient{Name: "李四", Address: "lisi@example.com", Type: RecipientTo, Resolved: true}
+	team := Recipient{Name: "研发部全体", Address: "rd-all@example.com", Type: RecipientTo, Resolved: true, DistList: true}
+	colleague := Recipient{Name: "王五", Address: "wangwu@example.com", Type: RecipientTo, Resolved: true}
+	cc := func(r Recipient) Recipient {
+		r.Type = RecipientCC
+		return r
+	}
+	received := []EmailInfo{
+		{Subject: "直接", SenderEmail: "pm@example.com", Recipients: []Recipient{me}, IsRead: true},
+		{Subject: "抄送", SenderEmail: "pm@example.com", Recipients: []Recipient{colleague, cc(me)}},
+		{Subject: "通讯组", SenderEmail: "pm@example.com", Recipients: []Recipient{team}},
+		{Subject: "通讯组2", SenderEmail: "hr@example.com", Recipients: []Recipient{colleague, cc(team)}, IsRead: true},
+		// 没有标记为分发列表的收件人不当作列表
+		{Subject: "未标记", SenderEmail: "pm@example.com", Recipients: []Recipient{colleague}},
+		{Subject: "邮件列表", SenderEmail: "news@example.com", ListID: `"公司新闻" <news.example.com>`, Recipients: []Recipient{colleague}},
+		{Subject: "密送", SenderEmail: "pm@example.com"},
+	}
+
+	tests := []struct {
+		name    string
+		source  MailSource
+		account string
+		known   bool
+	}{
+		{"default account without current user", &stubMailSource{}, "default", false},
+		{"default account uses current user", &currentUserSource{name: "李四", address: "LiSi@example.com"}, "default", true},
+		{"entered address", &stubMailSource{}, "lisi@example.com", true},
+	}
+	for _, tt := range tests {
+		oa := NewOutlookEmailAnalyzer(tt.source)
+		oa.contacts.observeEmails(received)
+		oa.contacts.finish()
+
+		stats := oa.analyzeAttention(tt.account, received, nil)
+		if stats.Known != tt.known {
+			t.Errorf("%s: known = %v, want %v", tt.name, stats.Known, tt.known)
+			continue
+		}
+		if !stats.Known {
+			continue
+		}
+		var counts []int
+		for _, group := range stats.Roles {
+			counts = append(counts, group.Count)
+		}
+		if fmt.Sprint(counts) != "[1 1 1 4]" {
+			t.Errorf("%s: role counts = %v", tt.name, counts)
+		}
+		var lists []string
+		for _, list := range stats.Lists {
+			lists = append(lists, fmt.Sprintf("%s:%d/%d", list.Name, list.Unread, list.Count))
+		}
+		if fmt.Sprint(lists) != "[公司新闻:1/1 研发部全体 <rd-all@example.com>:1/2]" {
+			t.Errorf("%s: lists = %v", tt.name, lists)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// autoKind 是自动发送的邮件类型。这些邮件不是人写的，会虚增发件人排名、
+// 被当作对方的回复，因此单独统计，不计入读取和回复指标
+type autoKind int
+
+const (
+	notAuto autoKind = iota
+	autoReply
+	autoBounce
+	autoReceipt
+)
+
+var autoKindNames = [...]string{notAuto: "", autoReply: "自动回复", autoBounce: "退信", autoReceipt: "回执"}
+
+func (k autoKind) String() string {
+	return autoKindNames[k]
+}
+
+var (
+	// 自动回复的主题前缀：Outlook的“自动答复:”/“Automatic reply:”以及常见的外出回复
+	autoReplySubjectPattern = regexp.MustCompile(`(?i)^\s*(automatic reply|auto[- ]?reply|auto[- ]?response|out of (the )?office( reply)?\s*[:：]|自动回复|自动答复|自動回覆|自動回復)`)
+	// 退信的主题前缀
+	bounceSubjectPattern = regexp.MustCompile(`(?i)^\s*(undeliverable|undelivered mail|delivery status notification \(failure\)|mail delivery (failed|failure)|returned mail|failure notice|delivery failure|系统退信|退信|无法送达|未能送达|無法傳遞)`)
+	emailAddressPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+)
+
+// bouncedAddress 是退信中投递失败的地址
+type bouncedAddress struct {
+	Address string
+	Count   int
+	Last    time.Time
+}
+
+// autoEmailStats 汇总收到和发出的自动邮件
+type autoEmailStats struct {
+	Received int
+	Sent     int
+	Counts   [len(autoKindNames)]int // 收到的自动邮件按类型计数
+	Bounced  []bouncedAddress
+}
+
+// autoKindOf 依次根据Outlook邮件类型、multipart/report、自动回复相关的头、发件人和主题判断邮件类型
+func autoKindOf(email EmailInfo) autoKind {
+	class := strings.ToLower(email.MessageClass)
+	_, isReply := normalizeSubject(email.Subject)
+	switch {
+	case strings.HasPrefix(class, "report.") && strings.HasSuffix(class, ".ndr"),
+		email.ReportType == "delivery-status", len(email.BouncedRecipients) > 0:
+		return autoBounce
+	// 其他REPORT类为送达和已读回执
+	case strings.HasPrefix(class, "report."), email.ReportType == "disposition-notification":
+		return autoReceipt
+	case strings.HasPrefix(class, "ipm.note.rules.ooftemplate"), strings.HasPrefix(class, "ipm.note.rules.replytemplate"),
+		strings.HasPrefix(email.AutoSubmitted, "auto-replied"), email.Precedence == "auto_reply",
+		autoReplySubjectPattern.MatchString(email.Subject):
+		return autoReply
+	case isDaemonAddress(email.SenderEmail), bounceSubjectPattern.MatchString(email.Subject):
+		return autoBounce
+	// 系统通知也带有这两个头，只有回复其他邮件时才算自动回复
+	case (email.AutoSubmitted != "" && email.AutoSubmitted != "no" || email.AutoResponseSuppress != "") &&
+		(isReply || email.InReplyTo != ""):
+		return autoReply
+	}
+	return notAuto
+}
+
+// isDaemonAddress 判断地址是否为发送退信的系统地址（MAILER-DAEMON、postmaster）
+func isDaemonAddress(address string) bool {
+	local := strings.ToLower(address)
+	if at := strings.LastIndex(local, "@"); at >= 0 {
+		local = local[:at]
+	}
+	switch local {
+	case "mailer-daemon", "mail-daemon", "maildaemon", "postmaster":
+		return true
+	}
+	return false
+}
+
+// separateAutoEmails 把自动回复、退信和回执从邮件中分出来，返回其余邮件和自动邮件
+func separateAutoEmails(emails []EmailInfo) ([]EmailInfo, []EmailInfo) {
+	var kept, auto []EmailInfo
+	for _, email := range emails {
+		if autoKindOf(email) != notAuto {
+			auto = append(auto, email)
+		} else {
+			kept = append(kept, email)
+		}
+	}
+	return kept, auto
+}
+
+// deliveryStatusRecipients 从multipart/report中的message/delivery-status部分提取投递失败的地址
+func deliveryStatusRecipients(boundary string, raw []byte) []string {
+	if boundary == "" {
+		return nil
+	}
+	reader := multipart.NewReader(bytes.NewReader(raw), boundary)
+	for {
+		part, err := reader.NextRawPart()
+		if err != nil {
+			return nil
+		}
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if mediaType != "message/delivery-status" && mediaType != "message/global-delivery-status" {
+			continue
+		}
+		status, err := decodePartBody(part.Header, part, "utf-8", false)
+		if err != nil {
+			return nil
+		}
+		return failedRecipients(status)
+	}
+}
+
+// failedRecipients 解析RFC 3464投递状态：每个收件人一段，以空行分隔，
+// 取Action为failed的段中的Final-Recipient
+func failedRecipients(status string) []string {
+	var recipients []string
+	var recipient, action string
+	flush := func() {
+		if recipient != "" && action == "failed" {
+			recipients = append(recipients, recipient)
+		}
+		recipient, action = "", ""
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(status, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "final-recipient":
+			// 格式为 地址类型; 地址，如 rfc822; user@example.com
+			if _, address, ok := strings.Cut(value, ";"); ok {
+				value = address
+			}
+			recipient = strings.TrimSpace(value)
+		case "action":
+			action = strings.ToLower(value)
+		}
+	}
+	flush()
+	return recipients
+}
+
+// bouncedAddresses 返回退信中投递失败的地址。数据源没有提供时（如Outlook的报告、只取了部分正文的IMAP邮件），
+// 从正文中查找邮件地址，排除发件人和系统地址
+func bouncedAddresses(email EmailInfo) []string {
+	if len(email.BouncedRecipients) > 0 {
+		return email.BouncedRecipients
+	}
+	var addresses []string
+	for _, address := range emailAddressPattern.FindAllString(email.Body, -1) {
+		if strings.EqualFold(address, email.SenderEmail) || isDaemonAddress(address) || containsFold(addresses, address) {
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeAutoEmails 按类型统计收到的自动邮件，并汇总退信中投递失败的地址
+func (oa *OutlookEmailAnalyzer) analyzeAutoEmails(received, sent []EmailInfo) autoEmailStats {
+	stats := autoEmailStats{Received: len(received), Sent: len(sent)}
+	bounced := make(map[string]*bouncedAddress)
+	for _, email := range received {
+		kind := autoKindOf(email)
+		stats.Counts[kind]++
+		if kind != autoBounce {
+			continue
+		}
+		for _, address := range bouncedAddresses(email) {
+			key := strings.ToLower(address)
+			if bounced[key] == nil {
+				bounced[key] = &bouncedAddress{Address: address}
+			}
+			bounced[key].Count++
+			if email.ReceivedTime.After(bounced[key].Last) {
+				bounced[key].Last = email.ReceivedTime
+			}
+		}
+	}
+
+	for _, address := range bounced {
+		stats.Bounced = append(stats.Bounced, *address)
+	}
+	sort.Slice(stats.Bounced, func(i, j int) bool {
+		if stats.Bounced[i].Count != stats.Bounced[j].Count {
+			return stats.Bounced[i].Count > stats.Bounced[j].Count
+		}
+		return strings.ToLower(stats.Bounced[i].Address) < strings.ToLower(stats.Bounced[j].Address)
+	})
+	return stats
+}
+
+func (oa *OutlookEmailAnalyzer) printAutoEmails(stats autoEmailStats) {
+	fmt.Printf("\n🤖 10. 自动回复与退信 (不计入读取和回复统计):\n")
+	if stats.Received == 0 && stats.Sent == 0 {
+		fmt.Printf("   无\n")
+		return
+	}
+	fmt.Printf("   收到: %s %d 封，%s %d 封，%s %d 封\n",
+		autoReply, stats.Counts[autoReply], autoBounce, stats.Counts[autoBounce], autoReceipt, stats.Counts[autoReceipt])
+	if stats.Sent > 0 {
+		fmt.Printf("   发出的自动邮件: %d 封\n", stats.Sent)
+	}
+	if len(stats.Bounced) > 0 {
+		fmt.Printf("   投递失败的地址:\n")
+		for _, address := range stats.Bounced {
+			fmt.Printf("     - %s: %d 次，最近一次 %s\n", address.Address, address.Count,
+				address.Last.In(oa.location).Format("2006-01-02 15:04"))
+		}
+	}
+}
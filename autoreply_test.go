@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFailedRecipients(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   []string
+	}{
+		{"per-recipient groups",
+			"Reporting-MTA: dns; mx.example.com\r\n\r\n" +
+				"Final-Recipient: rfc822; alice@example.com\r\nAction: failed\r\nStatus: 5.1.1\r\n\r\n" +
+				"Final-Recipient: rfc822; bob@example.com\r\nAction: delayed\r\nStatus: 4.4.7\r\n\r\n" +
+				"Final-Recipient: rfc822;carol@example.com\r\nAction: Failed\r\nStatus: 5.2.2\r\n",
+			[]string{"alice@example.com", "carol@example.com"}},
+		{"header names are case-insensitive, no address type",
+			"final-recipient: dave@example.com\naction: failed\n",
+			[]string{"dave@example.com"}},
+		{"blank line with whitespace ends a group",
+			"Final-Recipient: rfc822; erin@example.com\n  \nAction: failed\n",
+			nil},
+		{"action without recipient", "Action: failed\n", nil},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		if got := failedRecipients(tt.status); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: failedRecipients = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDeliveryStatusRecipients(t *testing.T) {
+	raw := "--b1\r\nContent-Type: text/plain\r\n\r\nFinal-Recipient: rfc822; wrong@example.com\r\nAction: failed\r\n" +
+		"--b1\r\nContent-Type: message/delivery-status\r\n\r\n" +
+		"Reporting-MTA: dns; mx.example.com\r\n\r\nFinal-Recipient: rfc822; alice@example.com\r\nAction: failed\r\n" +
+		"--b1--\r\n"
+	if got := deliveryStatusRecipients("b1", []byte(raw)); !reflect.DeepEqual(got, []string{"alice@example.com"}) {
+		t.Errorf("deliveryStatusRecipients = %q", got)
+	}
+	if got := deliveryStatusRecipients("", []byte(raw)); got != nil {
+		t.Errorf("no boundary = %q", got)
+	}
+}
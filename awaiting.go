@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+var (
+	// 请求或提问的常见说法
+	requestPattern = regexp.MustCompile(`(?i)[?？]|请|麻烦|能否|可否|是否|能不能|可不可以|帮忙|烦请|盼复|\bplease\b|\bcould you\b|\bcan you\b|\bwould you\b|\bkindly\b|\blet me know\b|\bany update\b`)
+	// 正文中引用原邮件的起始行
+	quoteStartPattern = regexp.MustCompile(`(?im)^\s*(>|-{3,}\s*(original message|原始邮件|原始郵件)|(发件人|寄件者|from)\s*[:：]|on .+ wrote:|在 .+ 写道[:：])`)
+)
+
+// awaitingEmail 是一封发出的提问或请求，超过设定的工作日仍未收到回复
+type awaitingEmail struct {
+	Email EmailInfo
+	Age   float64 // 已等待的工作日数
+}
+
+// awaitingRecipient 是按收件人分组的待回复邮件，一封邮件有多个收件人时出现在每个收件人下
+type awaitingRecipient struct {
+	Recipient string
+	Emails    []awaitingEmail
+}
+
+// findAwaitingResponse 找出发出后在会话中没有收到任何回复、且已超过oa.waitDays个工作日的提问或请求。
+// receivedEmails应包含分析期间结束后收到的邮件，否则期间之后才收到的回复不会被计入
+func (oa *OutlookEmailAnalyzer) findAwaitingResponse(receivedEmails, sentEmails []EmailInfo, now time.Time) []awaitingRecipient {
+	answered := make(map[int]bool)
+	// 直接回复（与回复统计相同的匹配方式，只是方向相反）
+	for _, original := range matchReplies(sentEmails, receivedEmails) {
+		if original >= 0 {
+			answered[original] = true
+		}
+	}
+	// 会话中更晚的回复：收到的邮件的任一祖先是发出的邮件
+	all := make([]EmailInfo, 0, len(sentEmails)+len(receivedEmails))
+	all = append(all, sentEmails...)
+	all = append(all, receivedEmails...)
+	for _, container := range threadMessages(all) {
+		if container.message < len(sentEmails) {
+			continue
+		}
+		for parent, depth := container.parent, 0; parent != nil && depth < 100; parent, depth = parent.parent, depth+1 {
+			if parent.message >= 0 && parent.message < len(sentEmails) {
+				answered[parent.message] = true
+			}
+		}
+	}
+
+	groups := make(map[string]*awaitingRecipient)
+	for i, email := range sentEmails {
+		if answered[i] || !looksLikeRequest(email) {
+			continue
+		}
+		age := oa.calendar.businessDays(email.SentTime, now)
+		if age < oa.waitDays {
+			continue
+		}
+		for _, recipient := range oa.contacts.recipientIdentities(email.recipientsOf(RecipientTo)) {
+			if groups[recipient] == nil {
+				groups[recipient] = &awaitingRecipient{Recipient: oa.contacts.label(recipient)}
+			}
+			groups[recipient].Emails = append(groups[recipient].Emails, awaitingEmail{Email: email, Age: age})
+		}
+	}
+
+	var result []awaitingRecipient
+	for _, group := range groups {
+		sort.Slice(group.Emails, func(i, j int) bool { return group.Emails[i].Age > group.Emails[j].Age })
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].Emails) != len(result[j].Emails) {
+			return len(result[i].Emails) > len(result[j].Emails)
+		}
+		return result[i].Recipient < result[j].Recipient
+	})
+	return result
+}
+
+// looksLikeRequest 判断发出的邮件是否在提问或提出请求，只检查主题和正文中自己写的部分（不含引用的原邮件）
+func looksLikeRequest(email EmailInfo) bool {
+	body := email.Body
+	if loc := quoteStartPattern.FindStringIndex(body); loc != nil {
+		body = body[:loc[0]]
+	}
+	subject, _ := normalizeSubject(email.Subject)
+	return requestPattern.MatchString(subject) || requestPattern.MatchString(body)
+}
+
+func (oa *OutlookEmailAnalyzer) printAwaitingResponse(awaiting []awaitingRecipient, repliesUntilNow bool) {
+	fmt.Printf("\n⏳ 8. 等待对方回复 (发出的提问或请求，超过 %g 个工作日未收到回复):\n", oa.waitDays)
+	if !repliesUntilNow {
+		fmt.Printf("   注意: 未能读取分析期间之后的邮件，只在期间内查找回复，期间结束后才收到回复的邮件也会列出\n")
+	}
+	if len(awaiting) == 0 {
+		fmt.Printf("   无\n")
+		return
+	}
+	for _, group := range awaiting {
+		fmt.Printf("   %s (%d 封):\n", group.Recipient, len(group.Emails))
+		for _, item := range group.Emails {
+			fmt.Printf("     - %s | 发送: %s | 已等待 %.1f 个工作日\n",
+				item.Email.Subject, item.Email.SentTime.In(oa.location).Format("2006-01-02 15:04"), item.Age)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFindAwaitingResponseUsesLaterReplies(t *testing.T) {
+	at := func(day, hour int) time.Time { return time.Date(2025, 3, day, hour, 0, 0, 0, time.UTC) }
+	pm := []Recipient{{Name: "项目经理", Address: "pm@example.com", Type: RecipientTo, Resolved: true}}
+	me := []Recipient{{Name: "李四", Address: "lisi@example.com", Type: RecipientTo, Resolved: true}}
+
+	// Outlook默认账户下发送的邮件可能没有发件人信息
+	request := EmailInfo{Subject: "请确认上线时间", SentTime: at(3, 10), Recipients: pm}
+	signed := EmailInfo{Subject: "能否提供测试报告？", SenderEmail: "lisi@example.com", SenderName: "李四", SentTime: at(3, 11), Recipients: pm}
+	reply := EmailInfo{Subject: "RE: 请确认上线时间", SenderEmail: "pm@example.com", ReceivedTime: at(10, 9), Recipients: me}
+	// 转发给别人的邮件不算对李四的回复
+	forwarded := EmailInfo{Subject: "RE: 能否提供测试报告？", SenderEmail: "pm@example.com", ReceivedTime: at(10, 9),
+		Recipients: []Recipient{{Name: "测试组", Address: "qa@example.com", Type: RecipientTo, Resolved: true}}}
+	// 请求发出之前收到的同主题邮件不是对它的回复
+	earlier := EmailInfo{Subject: "RE: 请确认上线时间", SenderEmail: "pm@example.com", ReceivedTime: at(1, 9), Recipients: me}
+
+	tests := []struct {
+		name     string
+		received []EmailInfo
+		want     string
+	}{
+		{"period only", nil, "[项目经理 <pm@example.com>: 请确认上线时间, 能否提供测试报告？]"},
+		{"later reply by subject", []EmailInfo{reply, forwarded}, "[项目经理 <pm@example.com>: 能否提供测试报告？]"},
+		{"same subject before request", []EmailInfo{earlier}, "[项目经理 <pm@example.com>: 请确认上线时间, 能否提供测试报告？]"},
+	}
+	for _, tt := range tests {
+		oa := NewOutlookEmailAnalyzer(&stubMailSource{})
+		oa.calendar = newDefaultCalendar(time.UTC)
+		sent := []EmailInfo{request, signed}
+		oa.contacts.observeEmails(sent)
+		oa.contacts.observeEmails(tt.received)
+		oa.contacts.finish()
+
+		var got []string
+		for _, group := range oa.findAwaitingResponse(tt.received, sent, at(12, 10)) {
+			subjects := ""
+			for i, item := range group.Emails {
+				if i > 0 {
+					subjects += ", "
+				}
+				subjects += item.Email.Subject
+			}
+			got = append(got, group.Recipient+": "+subjects)
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%s: awaiting = %v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAddressedTo(t *testing.T) {
+	original := EmailInfo{SenderEmail: "zhangsan@example.com", SenderName: "张三"}
+	tests := []struct {
+		name     string
+		reply    EmailInfo
+		original EmailInfo
+		want     bool
+	}{
+		{"by address", EmailInfo{Recipients: []Recipient{{Address: "ZhangSan@example.com", Type: RecipientTo}}}, original, true},
+		{"by display name in CC", EmailInfo{Recipients: []Recipient{{Name: " 张三 ", Type: RecipientCC}}}, original, true},
+		{"someone else", EmailInfo{Recipients: []Recipient{{Name: "王五", Address: "wangwu@example.com", Type: RecipientTo}}}, original, false},
+		{"no recipients", EmailInfo{}, original, true},
+		{"original without sender", EmailInfo{Recipients: []Recipient{{Address: "wangwu@example.com", Type: RecipientTo}}}, EmailInfo{}, true},
+	}
+	for _, tt := range tests {
+		if got := addressedTo(tt.reply, tt.original); got != tt.want {
+			t.Errorf("%s: addressedTo = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
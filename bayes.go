@@ -0,0 +1,436 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// bayesModel 是多项式朴素贝叶斯模型，按JSON保存到磁盘。
+// 词项来自tokenize：英文按单词，中文按相邻两字（二元组）切分，不需要词典
+type bayesModel struct {
+	Classes    []bayesClass `json:"classes"`
+	Vocabulary int          `json:"vocabulary"`
+}
+
+// bayesClass 是模型中的一个分类。Action为是否需要处理，训练时确定并随模型保存；
+// 较早的模型中没有该字段，加载时沿用规则分类中同名分类的设置
+type bayesClass struct {
+	Name      string         `json:"name"`
+	Action    *bool          `json:"action,omitempty"`
+	Documents int            `json:"documents"`
+	Tokens    int            `json:"tokens"`
+	Counts    map[string]int `json:"counts"`
+}
+
+// bayesSample 是训练/评估文件（JSONL）中的一行，category也可以写作label；
+// 属于多个分类时使用categories数组。action标注这些分类是否需要处理，不写时沿用规则分类中同名分类的设置
+type bayesSample struct {
+	Subject    string   `json:"subject"`
+	Body       string   `json:"body"`
+	Category   string   `json:"category"`
+	Label      string   `json:"label"`
+	Categories []string `json:"categories"`
+	Action     *bool    `json:"action"`
+}
+
+// bayesLabelThreshold 是主要分类以外的分类成为标签所需的最低后验概率
+const bayesLabelThreshold = 0.3
+
+// bayesScore 是某个分类的后验概率
+type bayesScore struct {
+	Class       string
+	Probability float64
+}
+
+// tokenize 将文本切分为词项：连续的字母数字为一个词（忽略单个字符和纯数字），
+// 连续的汉字按相邻两字切分，只有一个汉字时保留该字
+func tokenize(text string) []string {
+	var tokens []string
+	var word, han []rune
+	flushWord := func() {
+		if len(word) > 1 && strings.TrimFunc(string(word), unicode.IsDigit) != "" {
+			tokens = append(tokens, string(word))
+		}
+		word = word[:0]
+	}
+	flushHan := func() {
+		if len(han) == 1 {
+			tokens = append(tokens, string(han))
+		}
+		for i := 0; i+1 < len(han); i++ {
+			tokens = append(tokens, string(han[i:i+2]))
+		}
+		han = han[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return tokens
+}
+
+// emailTokens 使用与规则分类相同的字段：去掉回复前缀的主题和不含引用的正文
+func emailTokens(email EmailInfo) []string {
+	fields := emailFields(email)
+	return tokenize(fields["subject"] + "\n" + fields["body"])
+}
+
+func readBayesSamples(path string) ([]bayesSample, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开样本文件: %v", err)
+	}
+	defer file.Close()
+
+	var samples []bayesSample
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var sample bayesSample
+		if err := json.Unmarshal([]byte(line), &sample); err != nil {
+			return nil, fmt.Errorf("样本文件第%d行: %v", lineNo, err)
+		}
+		if sample.Category == "" {
+			sample.Category = sample.Label
+		}
+		if len(sample.Categories) == 0 && sample.Category != "" {
+			sample.Categories = []string{sample.Category}
+		}
+		if len(sample.Categories) == 0 {
+			return nil, fmt.Errorf("样本文件第%d行没有category", lineNo)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取样本文件失败: %v", err)
+	}
+	return samples, nil
+}
+
+func (s bayesSample) email() EmailInfo {
+	return EmailInfo{Subject: s.Subject, Body: s.Body}
+}
+
+// trainBayes 按样本在文件中首次出现的顺序建立分类，属于多个分类的样本计入每个分类。
+// 分类是否需要处理取自样本的action，没有标注时取自rules中的同名分类
+func trainBayes(samples []bayesSample, rules *emailClassifier) *bayesModel {
+	model := &bayesModel{}
+	index := make(map[string]int)
+	vocabulary := make(map[string]bool)
+	for _, sample := range samples {
+		tokens := emailTokens(sample.email())
+		for _, name := range sample.Categories {
+			i, ok := index[name]
+			if !ok {
+				i = len(model.Classes)
+				index[name] = i
+				model.Classes = append(model.Classes, bayesClass{Name: name, Counts: make(map[string]int)})
+			}
+			class := &model.Classes[i]
+			if sample.Action != nil {
+				action := *sample.Action
+				class.Action = &action
+			}
+			class.Documents++
+			for _, token := range tokens {
+				class.Counts[token]++
+				class.Tokens++
+				vocabulary[token] = true
+			}
+		}
+	}
+	model.Vocabulary = len(vocabulary)
+	model.inheritActions(rules)
+	return model
+}
+
+// inheritActions 为没有Action的分类沿用规则分类中同名分类的设置，规则中也没有的不需要处理
+func (m *bayesModel) inheritActions(rules *emailClassifier) {
+	actions := make(map[string]bool)
+	for _, category := range rules.categories() {
+		actions[category.Name] = category.Action
+	}
+	for i := range m.Classes {
+		if m.Classes[i].Action == nil {
+			action := actions[m.Classes[i].Name]
+			m.Classes[i].Action = &action
+		}
+	}
+}
+
+func (m *bayesModel) totalDocuments() int {
+	total := 0
+	for _, class := range m.Classes {
+		total += class.Documents
+	}
+	return total
+}
+
+func loadBayesModel(path string) (*bayesModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取模型文件失败: %v", err)
+	}
+	var model bayesModel
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("模型文件 %s 无效: %v", path, err)
+	}
+	if len(model.Classes) == 0 {
+		return nil, fmt.Errorf("模型文件 %s 中没有分类", path)
+	}
+	for _, class := range model.Classes {
+		if class.Documents < 0 || class.Tokens < 0 {
+			return nil, fmt.Errorf("模型文件 %s 中分类 %s 的计数无效", path, class.Name)
+		}
+	}
+	if model.totalDocuments() == 0 {
+		return nil, fmt.Errorf("模型文件 %s 中没有训练样本", path)
+	}
+	return &model, nil
+}
+
+func (m *bayesModel) save(path string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("保存模型失败: %v", err)
+	}
+	return nil
+}
+
+// predict 返回各分类的后验概率，从高到低排列。使用加一平滑，训练中未出现的词项忽略；
+// 没有训练样本时各分类的先验概率相同
+func (m *bayesModel) predict(email EmailInfo) []bayesScore {
+	totalDocuments := m.totalDocuments()
+
+	tokens := emailTokens(email)
+	logs := make([]float64, len(m.Classes))
+	maxLog := math.Inf(-1)
+	for i, class := range m.Classes {
+		logP := -math.Log(float64(len(m.Classes)))
+		if totalDocuments > 0 {
+			logP = math.Log(float64(class.Documents) / float64(totalDocuments))
+		}
+		denominator := math.Log(float64(class.Tokens + m.Vocabulary))
+		for _, token := range tokens {
+			if !m.known(token) {
+				continue
+			}
+			logP += math.Log(float64(class.Counts[token]+1)) - denominator
+		}
+		logs[i] = logP
+		if logP > maxLog {
+			maxLog = logP
+		}
+	}
+
+	// 按log-sum-exp归一化，避免下溢
+	sum := 0.0
+	for _, logP := range logs {
+		sum += math.Exp(logP - maxLog)
+	}
+	scores := make([]bayesScore, len(m.Classes))
+	for i, class := range m.Classes {
+		scores[i] = bayesScore{Class: class.Name, Probability: math.Exp(logs[i]-maxLog) / sum}
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Probability > scores[j].Probability })
+	return scores
+}
+
+func (m *bayesModel) known(token string) bool {
+	for _, class := range m.Classes {
+		if class.Counts[token] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// bayesClassifier 用模型为邮件分类，分类是否需要处理取自模型
+type bayesClassifier struct {
+	model *bayesModel
+	list  []emailCategory
+	index map[string]*emailCategory
+}
+
+// newBayesClassifier 创建模型分类器，rules只用于较早的、没有保存Action的模型
+func newBayesClassifier(model *bayesModel, rules *emailClassifier) *bayesClassifier {
+	model.inheritActions(rules)
+	bc := &bayesClassifier{model: model, index: make(map[string]*emailCategory)}
+	for _, class := range model.Classes {
+		bc.list = append(bc.list, emailCategory{Name: class.Name, Action: *class.Action})
+	}
+	for i := range bc.list {
+		bc.index[bc.list[i].Name] = &bc.list[i]
+	}
+	return bc
+}
+
+// labels 返回后验概率最高的分类，以及概率不低于bayesLabelThreshold的其他分类
+func (bc *bayesClassifier) labels(email EmailInfo) []emailLabel {
+	var labels []emailLabel
+	for i, score := range bc.model.predict(email) {
+		if i > 0 && score.Probability < bayesLabelThreshold {
+			break
+		}
+		labels = append(labels, emailLabel{Category: bc.index[score.Class], Confidence: score.Probability})
+	}
+	return labels
+}
+
+func (bc *bayesClassifier) categories() []*emailCategory {
+	categories := make([]*emailCategory, 0, len(bc.list))
+	for i := range bc.list {
+		categories = append(categories, &bc.list[i])
+	}
+	return categories
+}
+
+// runBayesTraining 从样本文件训练模型并保存，没有标注action的分类沿用rules中的设置
+func runBayesTraining(samplesPath, modelPath string, rules *emailClassifier) error {
+	samples, err := readBayesSamples(samplesPath)
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return fmt.Errorf("样本文件为空")
+	}
+	model := trainBayes(samples, rules)
+	if err := model.save(modelPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ 已用 %d 个样本训练模型: %d 个分类，%d 个词项\n", len(samples), len(model.Classes), model.Vocabulary)
+	for _, class := range model.Classes {
+		action := ""
+		if *class.Action {
+			action = "（需要处理）"
+		}
+		fmt.Printf("   %s: %d 个样本%s\n", class.Name, class.Documents, action)
+	}
+	fmt.Printf("✓ 模型已保存到 %s\n", modelPath)
+	return nil
+}
+
+// runBayesEvaluation 在留出的样本上评估模型，输出每个分类的精确率、召回率和F1
+func runBayesEvaluation(samplesPath, modelPath string) error {
+	model, err := loadBayesModel(modelPath)
+	if err != nil {
+		return err
+	}
+	samples, err := readBayesSamples(samplesPath)
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return fmt.Errorf("样本文件为空")
+	}
+
+	// 多标签评估：预测的标签集合与标注的分类集合逐个比较
+	truePositive := make(map[string]int)
+	predicted := make(map[string]int)
+	actual := make(map[string]int)
+	var names []string
+	for _, class := range model.Classes {
+		names = append(names, class.Name)
+	}
+	exact := 0
+	for _, sample := range samples {
+		var guesses []string
+		for i, score := range model.predict(sample.email()) {
+			if i > 0 && score.Probability < bayesLabelThreshold {
+				break
+			}
+			guesses = append(guesses, score.Class)
+			predicted[score.Class]++
+			if containsString(sample.Categories, score.Class) {
+				truePositive[score.Class]++
+			}
+		}
+		for _, name := range sample.Categories {
+			if !containsString(names, name) {
+				// 模型中没有的分类也要计入召回率
+				names = append(names, name)
+			}
+			actual[name]++
+		}
+		if sameStringSet(guesses, sample.Categories) {
+			exact++
+		}
+	}
+
+	fmt.Printf("📈 模型评估 (%d 个样本):\n", len(samples))
+	fmt.Printf("   %s   精确率   召回率       F1 样本数\n", padDisplay("分类", 16))
+	var macroPrecision, macroRecall, macroF1 float64
+	for _, name := range names {
+		precision := ratio(truePositive[name], predicted[name])
+		recall := ratio(truePositive[name], actual[name])
+		f1 := 0.0
+		if precision+recall > 0 {
+			f1 = 2 * precision * recall / (precision + recall)
+		}
+		macroPrecision += precision
+		macroRecall += recall
+		macroF1 += f1
+		fmt.Printf("   %s %8.3f %8.3f %8.3f %6d\n", padDisplay(name, 16), precision, recall, f1, actual[name])
+	}
+	n := float64(len(names))
+	fmt.Printf("   %s %8.3f %8.3f %8.3f %6d\n", padDisplay("宏平均", 16), macroPrecision/n, macroRecall/n, macroF1/n, len(samples))
+	fmt.Printf("   完全匹配率: %.1f%% (%d/%d)\n", ratio(exact, len(samples))*100, exact, len(samples))
+	return nil
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sameStringSet 判断两个列表包含的元素是否相同（忽略顺序）
+func sameStringSet(a, b []string) bool {
+	for _, item := range a {
+		if !containsString(b, item) {
+			return false
+		}
+	}
+	for _, item := range b {
+		if !containsString(a, item) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Please APPROVE the Q3 budget", []string{"please", "approve", "the", "q3", "budget"}},
+		{"请审批预算", []string{"请审", "审批", "批预", "预算"}},
+		{"会议 a 2025 年", []string{"会议", "年"}},
+		{"Re:项目v2上线", []string{"re", "项目", "v2", "上线"}},
+		{"发票-invoice#123", []string{"发票", "invoice"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEmailTokensSkipPrefixAndQuote(t *testing.T) {
+	email := EmailInfo{Subject: "RE: 预算", Body: "收到\n> 请审批"}
+	if got := emailTokens(email); !reflect.DeepEqual(got, []string{"预算", "收到"}) {
+		t.Errorf("emailTokens = %q", got)
+	}
+}
+
+const bayesSamplesJSONL = `{"subject":"请审批采购申请","body":"请批准本月采购预算","category":"需要批准"}
+{"subject":"Approval needed","body":"please approve the purchase order","label":"需要批准"}
+
+{"subject":"周报","body":"本周项目进展顺利","category":"信息类"}
+{"subject":"Newsletter","body":"monthly company news and updates","category":"信息类"}
+{"subject":"请审批并回复意见","body":"请批准后回复","categories":["需要批准","需要回复"]}
+`
+
+func TestBayesTrainPredictAndSave(t *testing.T) {
+	dir := t.TempDir()
+	samplesPath := filepath.Join(dir, "samples.jsonl")
+	if err := os.WriteFile(samplesPath, []byte(bayesSamplesJSONL), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	samples, err := readBayesSamples(samplesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 5 || samples[1].Category != "需要批准" || !reflect.DeepEqual(samples[4].Categories, []string{"需要批准", "需要回复"}) {
+		t.Fatalf("samples = %+v", samples)
+	}
+
+	model := trainBayes(samples, newDefaultClassifier())
+	var names []string
+	for _, class := range model.Classes {
+		names = append(names, class.Name)
+	}
+	if strings.Join(names, ",") != "需要批准,信息类,需要回复" {
+		t.Errorf("classes = %v, want order of first appearance", names)
+	}
+	if model.Classes[0].Documents != 3 || model.Classes[2].Documents != 1 {
+		t.Errorf("documents = %d / %d", model.Classes[0].Documents, model.Classes[2].Documents)
+	}
+	if model.Classes[0].Counts["审批"] != 2 {
+		t.Errorf("count(审批) = %d", model.Classes[0].Counts["审批"])
+	}
+
+	modelPath := filepath.Join(dir, "model.json")
+	if err := model.save(modelPath); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadBayesModel(modelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, model) {
+		t.Errorf("loaded model differs from saved model")
+	}
+
+	scores := loaded.predict(EmailInfo{Subject: "请审批", Body: "approve budget"})
+	if scores[0].Class != "需要批准" || scores[0].Probability < 0.5 {
+		t.Errorf("predict = %+v", scores)
+	}
+	total := 0.0
+	for _, score := range scores {
+		total += score.Probability
+	}
+	if total < 0.999 || total > 1.001 {
+		t.Errorf("probabilities sum to %v", total)
+	}
+	// 全部是未知词项时按先验概率
+	prior := loaded.predict(EmailInfo{Subject: "zzz"})
+	if prior[0].Class != "需要批准" || prior[0].Probability < 0.49 || prior[0].Probability > 0.51 {
+		t.Errorf("prior = %+v", prior)
+	}
+
+	rules := newDefaultClassifier()
+	classifier := newBayesClassifier(loaded, rules)
+	labels := classifier.labels(EmailInfo{Subject: "请审批采购申请"})
+	if labels[0].Category.Name != "需要批准" || !labels[0].Category.Action {
+		t.Errorf("labels = %+v", labels[0].Category)
+	}
+	for _, category := range classifier.categories() {
+		if category.Name == "信息类" && category.Action {
+			t.Error("信息类 should not require action")
+		}
+	}
+}
+
+func TestReadBayesSamplesErrors(t *testing.T) {
+	tests := map[string]string{
+		`{"subject":"x"}`:          "第1行没有category",
+		"\n{\"subject\":":          "样本文件第2行",
+		`{"subject":"x","body":1}`: "样本文件第1行",
+	}
+	for content, want := range tests {
+		path := filepath.Join(t.TempDir(), "samples.jsonl")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readBayesSamples(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err = %v, want %q", content, err, want)
+		}
+	}
+	if _, err := loadBayesModel(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing model loaded")
+	}
+
+	models := map[string]string{
+		`{"classes":[]}`: "没有分类",
+		`{"classes":[{"name":"a","documents":0},{"name":"b","documents":0}]}`:  "没有训练样本",
+		`{"classes":[{"name":"a","documents":2},{"name":"b","documents":-2}]}`: "计数无效",
+	}
+	for content, want := range models {
+		path := filepath.Join(t.TempDir(), "model.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadBayesModel(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", content, err, want)
+		}
+	}
+}
+
+func TestBayesActions(t *testing.T) {
+	yes, no := true, false
+	samples := []bayesSample{
+		{Subject: "请审批", Categories: []string{"需要批准"}},
+		// 规则中没有的分类用样本标注
+		{Subject: "服务器告警", Categories: []string{"紧急"}, Action: &yes},
+		{Subject: "服务器恢复", Categories: []string{"紧急"}},
+		// 样本标注优先于规则中的同名分类
+		{Subject: "请回复", Categories: []string{"需要回复"}, Action: &no},
+		{Subject: "周报", Categories: []string{"周报"}},
+	}
+	model := trainBayes(samples, newDefaultClassifier())
+	actions := make(map[string]bool)
+	for _, class := range model.Classes {
+		actions[class.Name] = *class.Action
+	}
+	if !reflect.DeepEqual(actions, map[string]bool{"需要批准": true, "紧急": true, "需要回复": false, "周报": false}) {
+		t.Errorf("actions = %v", actions)
+	}
+
+	// 没有保存Action的模型沿用规则中同名分类的设置
+	legacy := &bayesModel{Classes: []bayesClass{{Name: "需要批准", Documents: 1}, {Name: "紧急", Documents: 1}}}
+	classifier := newBayesClassifier(legacy, newDefaultClassifier())
+	if categories := classifier.categories(); !categories[0].Action || categories[1].Action {
+		t.Errorf("legacy categories = %+v, %+v", categories[0], categories[1])
+	}
+
+	// 没有训练样本时按相同的先验概率
+	empty := &bayesModel{Classes: []bayesClass{{Name: "a"}, {Name: "b"}}}
+	for _, score := range empty.predict(EmailInfo{Subject: "zzz"}) {
+		if score.Probability != 0.5 {
+			t.Errorf("empty model scores = %+v", empty.predict(EmailInfo{Subject: "zzz"}))
+			break
+		}
+	}
+}
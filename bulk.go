@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// angleBracketPattern 匹配 List-Id: "说明" <列表标识> 和 List-Unsubscribe: <mailto:...>, <https://...> 中尖括号内的部分
+var angleBracketPattern = regexp.MustCompile(`<([^<>]+)>`)
+
+// bulkList 是同一邮件列表（按List-Id）或同一群发发件人的邮件
+type bulkList struct {
+	Key         string
+	Name        string
+	Count       int
+	ReadCount   int
+	Unsubscribe []string // 最近一封邮件中的退订链接
+}
+
+func (l bulkList) readRate() float64 {
+	if l.Count == 0 {
+		return 0
+	}
+	return float64(l.ReadCount) / float64(l.Count) * 100
+}
+
+type bulkStats struct {
+	Total      int // 收到的邮件总数
+	BulkCount  int
+	Lists      []bulkList
+	Candidates []bulkList // 从未打开的列表，建议退订
+}
+
+const (
+	maxBulkListsShown = 15
+	// 至少收到这么多封且一封都没打开，才列为退订候选
+	minUnsubscribeMessages = 2
+)
+
+// isBulkEmail 根据邮件头判断是否为群发邮件：带List-Id或List-Unsubscribe、
+// Precedence为bulk/list/junk，或Auto-Submitted为auto-generated（系统通知）
+func isBulkEmail(email EmailInfo) bool {
+	if email.ListID != "" || email.ListUnsubscribe != "" {
+		return true
+	}
+	switch email.Precedence {
+	case "bulk", "list", "junk":
+		return true
+	}
+	return strings.HasPrefix(email.AutoSubmitted, "auto-generated")
+}
+
+// bulkListKey 返回分组的键和显示名称：有List-Id时按列表标识，否则按发件人所属的联系人，
+// 同一发件人的不同地址和显示名合为一组
+func (oa *OutlookEmailAnalyzer) bulkListKey(email EmailInfo) (string, string) {
+	if email.ListID != "" {
+		// 没有尖括号时整个值即为标识
+		id := email.ListID
+		name := ""
+		if match := angleBracketPattern.FindStringSubmatchIndex(id); match != nil {
+			name = strings.Trim(strings.TrimSpace(id[:match[0]]), `"`)
+			id = id[match[2]:match[3]]
+		}
+		if name == "" {
+			name = id
+		}
+		return "list:" + strings.ToLower(strings.TrimSpace(id)), name
+	}
+	sender := oa.contacts.senderIdentity(email)
+	return "sender:" + sender, oa.contacts.label(sender)
+}
+
+// parseUnsubscribe 提取List-Unsubscribe中的链接，https链接排在mailto之前
+func parseUnsubscribe(value string) []string {
+	var links []string
+	for _, match := range angleBracketPattern.FindAllStringSubmatch(value, -1) {
+		links = append(links, strings.TrimSpace(match[1]))
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		return !strings.HasPrefix(strings.ToLower(links[i]), "mailto:") && strings.HasPrefix(strings.ToLower(links[j]), "mailto:")
+	})
+	return links
+}
+
+// analyzeBulkMail 按邮件列表或发件人汇总群发邮件的数量和已读率，找出从未打开的列表
+func (oa *OutlookEmailAnalyzer) analyzeBulkMail(emails []EmailInfo) bulkStats {
+	stats := bulkStats{Total: len(emails)}
+	lists := make(map[string]*bulkList)
+	latest := make(map[string]EmailInfo)
+	for _, email := range emails {
+		if !isBulkEmail(email) {
+			continue
+		}
+		stats.BulkCount++
+		key, name := oa.bulkListKey(email)
+		list := lists[key]
+		if list == nil {
+			list = &bulkList{Key: key, Name: name}
+			lists[key] = list
+		}
+		list.Count++
+		if email.IsRead {
+			list.ReadCount++
+		}
+		if links := parseUnsubscribe(email.ListUnsubscribe); len(links) > 0 {
+			if previous, ok := latest[key]; !ok || email.ReceivedTime.After(previous.ReceivedTime) {
+				latest[key] = email
+				list.Unsubscribe = links
+			}
+		}
+	}
+
+	for _, list := range lists {
+		stats.Lists = append(stats.Lists, *list)
+		if list.ReadCount == 0 && list.Count >= minUnsubscribeMessages {
+			stats.Candidates = append(stats.Candidates, *list)
+		}
+	}
+	for _, l := range [][]bulkList{stats.Lists, stats.Candidates} {
+		sort.Slice(l, func(i, j int) bool {
+			if l[i].Count != l[j].Count {
+				return l[i].Count > l[j].Count
+			}
+			return l[i].Key < l[j].Key
+		})
+	}
+	return stats
+}
+
+func (oa *OutlookEmailAnalyzer) printBulkMail(stats bulkStats) {
+	fmt.Printf("\n📰 9. 群发邮件与邮件列表:\n")
+	if stats.BulkCount == 0 {
+		fmt.Printf("   未发现群发邮件\n")
+		return
+	}
+	fmt.Printf("   群发邮件: %d 封 (占收到邮件的 %.1f%%)，来自 %d 个列表/发件人\n",
+		stats.BulkCount, float64(stats.BulkCount)/float64(stats.Total)*100, len(stats.Lists))
+	for i, list := range stats.Lists {
+		if i == maxBulkListsShown {
+			fmt.Printf("   ... 另有 %d 个\n", len(stats.Lists)-maxBulkListsShown)
+			break
+		}
+		fmt.Printf("   %d. %s: %d 封，已读 %.1f%%\n", i+1, list.Name, list.Count, list.readRate())
+	}
+
+	if len(stats.Candidates) > 0 {
+		fmt.Printf("   可考虑退订 (收到至少 %d 封且从未打开):\n", minUnsubscribeMessages)
+		for _, list := range stats.Candidates {
+			fmt.Printf("     - %s (%d 封)\n", list.Name, list.Count)
+			if len(list.Unsubscribe) == 0 {
+				fmt.Printf("       退订: 邮件中没有List-Unsubscribe链接\n")
+			}
+			for _, link := range list.Unsubscribe {
+				fmt.Printf("       退订: %s\n", link)
+			}
+		}
+	}
+}
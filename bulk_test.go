@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsBulkEmail(t *testing.T) {
+	header := func(raw string) EmailInfo {
+		msg, err := mail.ReadMessage(strings.NewReader(raw + "\r\n\r\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var email EmailInfo
+		applyListHeaders(&email, msg.Header)
+		return email
+	}
+	tests := []struct {
+		name  string
+		email EmailInfo
+		want  bool
+	}{
+		{"List-Id", header("List-Id: <dev.lists.example.com>"), true},
+		{"List-Unsubscribe only", header("List-Unsubscribe: <mailto:leave@example.com>"), true},
+		{"Precedence: Bulk", header("Precedence: Bulk"), true},
+		{"Precedence: list", header("Precedence: list"), true},
+		{"Precedence: junk", header("Precedence:  JUNK "), true},
+		{"Auto-Submitted: auto-generated", header("Auto-Submitted: Auto-Generated"), true},
+		{"Auto-Submitted with comment", header("Auto-Submitted: auto-generated (monitor)"), true},
+		// 自动回复不是群发邮件
+		{"Auto-Submitted: auto-replied", header("Auto-Submitted: auto-replied"), false},
+		{"Auto-Submitted: no", header("Auto-Submitted: no"), false},
+		{"Precedence: first-class", header("Precedence: first-class"), false},
+		{"personal mail", header("Subject: hello"), false},
+	}
+	for _, tt := range tests {
+		if got := isBulkEmail(tt.email); got != tt.want {
+			t.Errorf("%s: isBulkEmail = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBulkListKey(t *testing.T) {
+	oa := NewOutlookEmailAnalyzer(&stubMailSource{})
+	tests := []struct {
+		listID   string
+		key      string
+		listName string
+	}{
+		{`"Company News" <News.Corp.com>`, "list:news.corp.com", "Company News"},
+		{`公司新闻 <news.corp.com>`, "list:news.corp.com", "公司新闻"},
+		{"<dev.lists.example.com>", "list:dev.lists.example.com", "dev.lists.example.com"},
+		{"dev.lists.example.com", "list:dev.lists.example.com", "dev.lists.example.com"},
+		{`"" <ops.example.com>`, "list:ops.example.com", "ops.example.com"},
+	}
+	for _, tt := range tests {
+		key, name := oa.bulkListKey(EmailInfo{ListID: tt.listID, SenderEmail: "sender@corp.com"})
+		if key != tt.key || name != tt.listName {
+			t.Errorf("bulkListKey(%q) = %q, %q, want %q, %q", tt.listID, key, name, tt.key, tt.listName)
+		}
+	}
+}
+
+func TestParseUnsubscribe(t *testing.T) {
+	tests := map[string][]string{
+		"<mailto:leave@corp.com?subject=unsubscribe>, <https://corp.com/unsub>": {"https://corp.com/unsub", "mailto:leave@corp.com?subject=unsubscribe"},
+		"<MAILTO:a@corp.com>, <http://corp.com/u>, <https://corp.com/u>":        {"http://corp.com/u", "https://corp.com/u", "MAILTO:a@corp.com"},
+		"< https://corp.com/unsub >":                                            {"https://corp.com/unsub"},
+		"https://corp.com/unsub":                                                nil,
+		"":                                                                      nil,
+	}
+	for value, want := range tests {
+		if got := parseUnsubscribe(value); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseUnsubscribe(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestUnsubscribeCandidatesThreshold(t *testing.T) {
+	var emails []EmailInfo
+	for i := 0; i < minUnsubscribeMessages; i++ {
+		emails = append(emails, EmailInfo{ListID: "<unread.corp.com>"})
+	}
+	emails = append(emails,
+		// 不够阈值
+		EmailInfo{ListID: "<once.corp.com>"},
+		// 打开过一封
+		EmailInfo{ListID: "<read.corp.com>"},
+		EmailInfo{ListID: "<read.corp.com>", IsRead: true},
+		EmailInfo{ListID: "<read.corp.com>"},
+	)
+	oa := NewOutlookEmailAnalyzer(&stubMailSource{})
+	stats := oa.analyzeBulkMail(emails)
+	var candidates []string
+	for _, list := range stats.Candidates {
+		candidates = append(candidates, list.Key)
+	}
+	if !reflect.DeepEqual(candidates, []string{"list:unread.corp.com"}) {
+		t.Errorf("candidates = %v", candidates)
+	}
+	if len(stats.Lists) != 3 || stats.Lists[0].Key != "list:read.corp.com" {
+		t.Errorf("lists = %+v", stats.Lists)
+	}
+}
+
+func TestAnalyzeBulkMailGroupsByContact(t *testing.T) {
+	emails := []EmailInfo{
+		{SenderName: "IT服务台", SenderEmail: "it@corp.com", Precedence: "bulk"},
+		{SenderName: "IT服务台", SenderEmail: "IT@Corp.com", AutoSubmitted: "auto-generated", IsRead: true},
+		// 只有显示名的邮件并入同名的联系人
+		{SenderName: "IT服务台", Precedence: "bulk"},
+		{SenderEmail: "news@corp.com", ListID: `"公司新闻" <news.corp.com>`, ListUnsubscribe: "<mailto:leave@corp.com>, <https://corp.com/unsub>"},
+		{SenderEmail: "news@corp.com", ListID: "<NEWS.corp.com>"},
+		{SenderName: "张三", SenderEmail: "zhangsan@corp.com"},
+	}
+	oa := NewOutlookEmailAnalyzer(&stubMailSource{})
+	oa.contacts.observeEmails(emails)
+	oa.contacts.finish()
+
+	stats := oa.analyzeBulkMail(emails)
+	var lists []string
+	for _, list := range stats.Lists {
+		lists = append(lists, fmt.Sprintf("%s:%d/%d", list.Name, list.ReadCount, list.Count))
+	}
+	if stats.Total != 6 || stats.BulkCount != 5 || fmt.Sprint(lists) != "[IT服务台 <it@corp.com>:1/3 公司新闻:0/2]" {
+		t.Errorf("bulk = %d/%d %v", stats.BulkCount, stats.Total, lists)
+	}
+	if len(stats.Candidates) != 1 || fmt.Sprint(stats.Candidates[0].Unsubscribe) != "[https://corp.com/unsub mailto:leave@corp.com]" {
+		t.Errorf("candidates = %+v", stats.Candidates)
+	}
+}
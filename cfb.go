@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// cfbFile 是复合文档二进制格式（Compound File Binary, MS-CFB）的只读实现，
+// .msg文件即以此格式存储。整个文件读入内存，适用于单封邮件这种小文件
+type cfbFile struct {
+	data           []byte
+	majorVersion   uint16
+	sectorSize     int
+	miniSectorSize int
+	miniCutoff     uint64
+	fat            []uint32
+	miniFAT        []uint32
+	miniStream     []byte
+	entries        []cfbEntry
+}
+
+type cfbEntry struct {
+	name  string
+	kind  byte // 1 storage, 2 stream, 5 root
+	left  uint32
+	right uint32
+	child uint32
+	start uint32
+	size  uint64
+}
+
+const (
+	cfbEndOfChain = 0xFFFFFFFE
+	cfbFreeSect   = 0xFFFFFFFF
+	cfbNoStream   = 0xFFFFFFFF
+
+	cfbStorage = 1
+	cfbStream  = 2
+	cfbRoot    = 5
+)
+
+var cfbSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+func openCFB(data []byte) (*cfbFile, error) {
+	if len(data) < 512 || !bytes.Equal(data[:8], cfbSignature) {
+		return nil, fmt.Errorf("不是复合文档格式")
+	}
+
+	cf := &cfbFile{data: data}
+	cf.majorVersion = binary.LittleEndian.Uint16(data[0x1A:])
+	sectorShift := binary.LittleEndian.Uint16(data[0x1E:])
+	miniSectorShift := binary.LittleEndian.Uint16(data[0x20:])
+	if sectorShift != 9 && sectorShift != 12 {
+		return nil, fmt.Errorf("无效的扇区大小: 2^%d", sectorShift)
+	}
+	if miniSectorShift != 6 {
+		return nil, fmt.Errorf("无效的迷你扇区大小: 2^%d", miniSectorShift)
+	}
+	cf.sectorSize = 1 << sectorShift
+	cf.miniSectorSize = 1 << miniSectorShift
+	cf.miniCutoff = uint64(binary.LittleEndian.Uint32(data[0x38:]))
+
+	numFATSectors := binary.LittleEndian.Uint32(data[0x2C:])
+	firstDirSector := binary.LittleEndian.Uint32(data[0x30:])
+	firstMiniFATSector := binary.LittleEndian.Uint32(data[0x3C:])
+	firstDIFATSector := binary.LittleEndian.Uint32(data[0x44:])
+	numDIFATSectors := binary.LittleEndian.Uint32(data[0x48:])
+
+	// 收集FAT扇区位置：前109个在文件头中，其余在DIFAT扇区链中
+	var fatSectors []uint32
+	for i := 0; i < 109; i++ {
+		sid := binary.LittleEndian.Uint32(data[0x4C+i*4:])
+		if sid == cfbFreeSect {
+			continue
+		}
+		fatSectors = append(fatSectors, sid)
+	}
+	difat := firstDIFATSector
+	perSector := cf.sectorSize/4 - 1
+	for i := uint32(0); i < numDIFATSectors && difat != cfbEndOfChain && difat != cfbFreeSect; i++ {
+		sector, err := cf.sector(difat)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < perSector; j++ {
+			sid := binary.LittleEndian.Uint32(sector[j*4:])
+			if sid != cfbFreeSect {
+				fatSectors = append(fatSectors, sid)
+			}
+		}
+		difat = binary.LittleEndian.Uint32(sector[perSector*4:])
+	}
+	if uint32(len(fatSectors)) > numFATSectors {
+		fatSectors = fatSectors[:numFATSectors]
+	}
+
+	for _, sid := range fatSectors {
+		sector, err := cf.sector(sid)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < cf.sectorSize; j += 4 {
+			cf.fat = append(cf.fat, binary.LittleEndian.Uint32(sector[j:]))
+		}
+	}
+
+	dirData, err := cf.readChain(firstDirSector, 0)
+	if err != nil {
+		return nil, fmt.Errorf("读取目录失败: %v", err)
+	}
+	for off := 0; off+128 <= len(dirData); off += 128 {
+		cf.entries = append(cf.entries, cf.parseEntry(dirData[off:off+128]))
+	}
+	if len(cf.entries) == 0 || cf.entries[0].kind != cfbRoot {
+		return nil, fmt.Errorf("缺少根目录项")
+	}
+
+	if firstMiniFATSector != cfbEndOfChain && firstMiniFATSector != cfbFreeSect {
+		miniFATData, err := cf.readChain(firstMiniFATSector, 0)
+		if err != nil {
+			return nil, fmt.Errorf("读取迷你FAT失败: %v", err)
+		}
+		for j := 0; j+4 <= len(miniFATData); j += 4 {
+			cf.miniFAT = append(cf.miniFAT, binary.LittleEndian.Uint32(miniFATData[j:]))
+		}
+	}
+
+	// 根目录项的起始扇区和大小描述迷你流
+	root := cf.entries[0]
+	if root.start != cfbEndOfChain && root.size > 0 {
+		cf.miniStream, err = cf.readChain(root.start, root.size)
+		if err != nil {
+			return nil, fmt.Errorf("读取迷你流失败: %v", err)
+		}
+	}
+
+	return cf, nil
+}
+
+func (cf *cfbFile) parseEntry(raw []byte) cfbEntry {
+	nameLen := int(binary.LittleEndian.Uint16(raw[0x40:]))
+	if nameLen > 64 {
+		nameLen = 64
+	}
+	entry := cfbEntry{
+		name:  utf16String(raw[:nameLen]),
+		kind:  raw[0x42],
+		left:  binary.LittleEndian.Uint32(raw[0x44:]),
+		right: binary.LittleEndian.Uint32(raw[0x48:]),
+		child: binary.LittleEndian.Uint32(raw[0x4C:]),
+		start: binary.LittleEndian.Uint32(raw[0x74:]),
+		size:  binary.LittleEndian.Uint64(raw[0x78:]),
+	}
+	// 版本3的文件只使用大小字段的低32位
+	if cf.majorVersion == 3 {
+		entry.size &= 0xFFFFFFFF
+	}
+	return entry
+}
+
+func (cf *cfbFile) sector(sid uint32) ([]byte, error) {
+	off := (int64(sid) + 1) * int64(cf.sectorSize)
+	if off < 0 || off+int64(cf.sectorSize) > int64(len(cf.data)) {
+		// 最后一个扇区可能被截断
+		if off < int64(len(cf.data)) {
+			buf := make([]byte, cf.sectorSize)
+			copy(buf, cf.data[off:])
+			return buf, nil
+		}
+		return nil, fmt.Errorf("扇区 %d 超出文件范围", sid)
+	}
+	return cf.data[off : off+int64(cf.sectorSize)], nil
+}
+
+// readChain 沿FAT链读取数据，size为0时读取整条链
+func (cf *cfbFile) readChain(start uint32, size uint64) ([]byte, error) {
+	var buf []byte
+	sid := start
+	for steps := 0; sid != cfbEndOfChain; steps++ {
+		if steps > len(cf.fat) || int(sid) >= len(cf.fat) {
+			return nil, fmt.Errorf("FAT链损坏")
+		}
+		sector, err := cf.sector(sid)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, sector...)
+		if size > 0 && uint64(len(buf)) >= size {
+			break
+		}
+		sid = cf.fat[sid]
+	}
+	if size > 0 && uint64(len(buf)) > size {
+		buf = buf[:size]
+	}
+	return buf, nil
+}
+
+func (cf *cfbFile) readMiniChain(start uint32, size uint64) ([]byte, error) {
+	var buf []byte
+	sid := start
+	for steps := 0; sid != cfbEndOfChain && uint64(len(buf)) < size; steps++ {
+		if steps > len(cf.miniFAT) || int(sid) >= len(cf.miniFAT) {
+			return nil, fmt.Errorf("迷你FAT链损坏")
+		}
+		off := int(sid) * cf.miniSectorSize
+		if off+cf.miniSectorSize > len(cf.miniStream) {
+			return nil, fmt.Errorf("迷你扇区 %d 超出范围", sid)
+		}
+		buf = append(buf, cf.miniStream[off:off+cf.miniSectorSize]...)
+		sid = cf.miniFAT[sid]
+	}
+	if uint64(len(buf)) > size {
+		buf = buf[:size]
+	}
+	return buf, nil
+}
+
+// stream 读取目录项对应的流内容，小于阈值的流存放在迷你流中
+func (cf *cfbFile) stream(index uint32) ([]byte, error) {
+	entry := cf.entries[index]
+	if entry.kind != cfbStream {
+		return nil, fmt.Errorf("%s 不是流", entry.name)
+	}
+	if entry.size == 0 {
+		return nil, nil
+	}
+	if entry.size < cf.miniCutoff {
+		return cf.readMiniChain(entry.start, entry.size)
+	}
+	return cf.readChain(entry.start, entry.size)
+}
+
+// children 返回存储下的所有直接子项。子项以红黑树组织，这里按左右兄弟指针遍历
+func (cf *cfbFile) children(index uint32) []uint32 {
+	var result []uint32
+	visited := make(map[uint32]bool)
+	var walk func(id uint32)
+	walk = func(id uint32) {
+		if id == cfbNoStream || int(id) >= len(cf.entries) || visited[id] {
+			return
+		}
+		visited[id] = true
+		walk(cf.entries[id].left)
+		result = append(result, id)
+		walk(cf.entries[id].right)
+	}
+	walk(cf.entries[index].child)
+	return result
+}
+
+// child 按名称（不区分大小写）查找直接子项
+func (cf *cfbFile) child(index uint32, name string) (uint32, bool) {
+	for _, id := range cf.children(index) {
+		if strings.EqualFold(cf.entries[id].name, name) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+func utf16String(raw []byte) string {
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		units = append(units, binary.LittleEndian.Uint16(raw[i:]))
+	}
+	for len(units) > 0 && units[len(units)-1] == 0 {
+		units = units[:len(units)-1]
+	}
+	return string(utf16.Decode(units))
+}
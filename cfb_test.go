@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// testCFBEntry 描述测试用复合文档中的一个目录项，entries[0]必须是根目录
+type testCFBEntry struct {
+	name   string
+	kind   byte
+	parent int
+	data   []byte
+}
+
+// buildCFB 生成版本3（512字节扇区）的复合文档：小于4096字节的流放在迷你流中，其余放在普通扇区中
+func buildCFB(entries []testCFBEntry) []byte {
+	const sectorSize, miniSize, cutoff = 512, 64, 4096
+	sectors := func(n int) int { return (n + sectorSize - 1) / sectorSize }
+
+	starts := make([]uint32, len(entries))
+	var miniStream []byte
+	var miniFAT []uint32
+	for i, e := range entries {
+		starts[i] = cfbEndOfChain
+		if e.kind != cfbStream || len(e.data) == 0 || len(e.data) >= cutoff {
+			continue
+		}
+		first := uint32(len(miniFAT))
+		count := (len(e.data) + miniSize - 1) / miniSize
+		for j := 0; j < count; j++ {
+			miniFAT = append(miniFAT, first+uint32(j)+1)
+		}
+		miniFAT[len(miniFAT)-1] = cfbEndOfChain
+		starts[i] = first
+		padded := make([]byte, count*miniSize)
+		copy(padded, e.data)
+		miniStream = append(miniStream, padded...)
+	}
+
+	// 需要分配普通扇区的数据：目录、迷你FAT、迷你流和大流
+	type chain struct {
+		entry int // -1 表示不属于目录项
+		data  []byte
+	}
+	dir := make([]byte, ((len(entries)+3)/4)*sectorSize)
+	miniFATData := make([]byte, sectors(len(miniFAT)*4)*sectorSize)
+	for i := range miniFATData[len(miniFAT)*4:] {
+		miniFATData[len(miniFAT)*4+i] = 0xFF
+	}
+	for i, sid := range miniFAT {
+		binary.LittleEndian.PutUint32(miniFATData[i*4:], sid)
+	}
+	chains := []chain{{-1, dir}, {-1, miniFATData}, {0, miniStream}}
+	for i, e := range entries {
+		if e.kind == cfbStream && len(e.data) >= cutoff {
+			chains = append(chains, chain{i, e.data})
+		}
+	}
+
+	dataSectors := 0
+	for _, c := range chains {
+		dataSectors += sectors(len(c.data))
+	}
+	fatSectors := 1
+	for fatSectors*sectorSize/4 < fatSectors+dataSectors {
+		fatSectors++
+	}
+
+	fat := make([]uint32, fatSectors*sectorSize/4)
+	for i := range fat {
+		fat[i] = cfbFreeSect
+	}
+	for i := 0; i < fatSectors; i++ {
+		fat[i] = 0xFFFFFFFD
+	}
+	body := make([]byte, 0, dataSectors*sectorSize)
+	next := uint32(fatSectors)
+	chainStarts := make([]uint32, len(chains))
+	for ci, c := range chains {
+		n := sectors(len(c.data))
+		chainStarts[ci] = cfbEndOfChain
+		if n == 0 {
+			continue
+		}
+		chainStarts[ci] = next
+		for j := 0; j < n; j++ {
+			fat[next] = next + 1
+			next++
+		}
+		fat[next-1] = cfbEndOfChain
+		padded := make([]byte, n*sectorSize)
+		copy(padded, c.data)
+		body = append(body, padded...)
+		if c.entry > 0 {
+			starts[c.entry] = chainStarts[ci]
+		}
+	}
+	starts[0] = chainStarts[2]
+
+	// 目录：每个存储的第一个子项挂在child上，其余子项沿right串起来
+	lastChild := make(map[int]int)
+	child := make([]uint32, len(entries))
+	right := make([]uint32, len(entries))
+	for i := range entries {
+		child[i], right[i] = cfbNoStream, cfbNoStream
+	}
+	for i := 1; i < len(entries); i++ {
+		p := entries[i].parent
+		if prev, ok := lastChild[p]; ok {
+			right[prev] = uint32(i)
+		} else {
+			child[p] = uint32(i)
+		}
+		lastChild[p] = i
+	}
+	for i, e := range entries {
+		raw := dir[i*128 : (i+1)*128]
+		name := utf16.Encode([]rune(e.name))
+		for j, u := range name {
+			binary.LittleEndian.PutUint16(raw[j*2:], u)
+		}
+		binary.LittleEndian.PutUint16(raw[0x40:], uint16(len(name)*2+2))
+		raw[0x42] = e.kind
+		raw[0x43] = 1
+		binary.LittleEndian.PutUint32(raw[0x44:], cfbNoStream)
+		binary.LittleEndian.PutUint32(raw[0x48:], right[i])
+		binary.LittleEndian.PutUint32(raw[0x4C:], child[i])
+		binary.LittleEndian.PutUint32(raw[0x74:], starts[i])
+		size := uint64(len(e.data))
+		if i == 0 {
+			size = uint64(len(miniStream))
+		}
+		binary.LittleEndian.PutUint64(raw[0x78:], size)
+	}
+	for i := len(entries); i < len(dir)/128; i++ {
+		raw := dir[i*128 : (i+1)*128]
+		binary.LittleEndian.PutUint32(raw[0x44:], cfbNoStream)
+		binary.LittleEndian.PutUint32(raw[0x48:], cfbNoStream)
+		binary.LittleEndian.PutUint32(raw[0x4C:], cfbNoStream)
+	}
+	// 目录在写入body之后才填好，重新复制一遍
+	copy(body, dir)
+
+	header := make([]byte, sectorSize)
+	copy(header, cfbSignature)
+	binary.LittleEndian.PutUint16(header[0x18:], 0x3E)
+	binary.LittleEndian.PutUint16(header[0x1A:], 3)
+	binary.LittleEndian.PutUint16(header[0x1C:], 0xFFFE)
+	binary.LittleEndian.PutUint16(header[0x1E:], 9)
+	binary.LittleEndian.PutUint16(header[0x20:], 6)
+	binary.LittleEndian.PutUint32(header[0x2C:], uint32(fatSectors))
+	binary.LittleEndian.PutUint32(header[0x30:], chainStarts[0])
+	binary.LittleEndian.PutUint32(header[0x38:], cutoff)
+	binary.LittleEndian.PutUint32(header[0x3C:], chainStarts[1])
+	binary.LittleEndian.PutUint32(header[0x40:], uint32(sectors(len(miniFAT)*4)))
+	binary.LittleEndian.PutUint32(header[0x44:], cfbEndOfChain)
+	for i := 0; i < 109; i++ {
+		sid := uint32(cfbFreeSect)
+		if i < fatSectors {
+			sid = uint32(i)
+		}
+		binary.LittleEndian.PutUint32(header[0x4C+i*4:], sid)
+	}
+
+	var out bytes.Buffer
+	out.Write(header)
+	for _, sid := range fat {
+		binary.Write(&out, binary.LittleEndian, sid)
+	}
+	out.Write(body)
+	return out.Bytes()
+}
+
+func TestOpenCFBStreams(t *testing.T) {
+	small := []byte("mini stream content that spans more than one 64-byte mini sector of the file")
+	large := bytes.Repeat([]byte("0123456789"), 500)
+	data := buildCFB([]testCFBEntry{
+		{name: "Root Entry", kind: cfbRoot},
+		{name: "Small", kind: cfbStream, parent: 0, data: small},
+		{name: "Folder", kind: cfbStorage, parent: 0},
+		{name: "Large", kind: cfbStream, parent: 2, data: large},
+	})
+
+	cf, err := openCFB(data)
+	if err != nil {
+		t.Fatalf("openCFB: %v", err)
+	}
+	if got := len(cf.children(0)); got != 2 {
+		t.Fatalf("root has %d children, want 2", got)
+	}
+
+	id, ok := cf.child(0, "small")
+	if !ok {
+		t.Fatal("child lookup is not case-insensitive")
+	}
+	got, err := cf.stream(id)
+	if err != nil || !bytes.Equal(got, small) {
+		t.Errorf("mini stream = %q, %v", got, err)
+	}
+
+	folder, ok := cf.child(0, "Folder")
+	if !ok {
+		t.Fatal("storage Folder not found")
+	}
+	id, ok = cf.child(folder, "Large")
+	if !ok {
+		t.Fatal("stream Large not found")
+	}
+	got, err = cf.stream(id)
+	if err != nil || !bytes.Equal(got, large) {
+		t.Errorf("regular stream: %d bytes, %v", len(got), err)
+	}
+}
+
+func TestOpenCFBRejectsInvalidInput(t *testing.T) {
+	valid := buildCFB([]testCFBEntry{{name: "Root Entry", kind: cfbRoot}})
+
+	badShift := append([]byte(nil), valid...)
+	binary.LittleEndian.PutUint16(badShift[0x1E:], 7)
+	badDir := append([]byte(nil), valid...)
+	binary.LittleEndian.PutUint32(badDir[0x30:], 1000)
+
+	tests := map[string][]byte{
+		"short":        valid[:100],
+		"signature":    append([]byte("not a cfb"), valid[9:]...),
+		"sector shift": badShift,
+		"directory":    badDir,
+	}
+	for name, data := range tests {
+		if _, err := openCFB(data); err == nil {
+			t.Errorf("%s: openCFB accepted invalid input", name)
+		}
+	}
+}
+
+func TestUTF16String(t *testing.T) {
+	raw := []byte{'_', 0, '_', 0, 0x1a, 0x4f, 0, 0}
+	if got := utf16String(raw); got != "__会" {
+		t.Errorf("utf16String = %q", got)
+	}
+}
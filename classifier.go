@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// mailClassifier 为邮件打上一个或多个分类标签。labels至少返回一个标签，第一个为主要分类；
+// categories按报告中的顺序返回所有分类，默认分类在最后
+type mailClassifier interface {
+	labels(email EmailInfo) []emailLabel
+	categories() []*emailCategory
+}
+
+// emailLabel 是邮件的一个分类标签，Confidence在0到1之间
+type emailLabel struct {
+	Category   *emailCategory
+	Confidence float64
+}
+
+// emailClassifier 按规则文件对邮件分类。每个分类由若干规则组成，命中规则的得分之和达到阈值、
+// 所有必需规则都命中且没有命中排除规则时该分类成立。一封邮件可以同时属于多个分类，
+// 按优先级从高到低排列；都不成立时归入默认分类
+type emailClassifier struct {
+	Default    string          `json:"default"`
+	Categories []emailCategory `json:"categories"`
+}
+
+type emailCategory struct {
+	Name      string           `json:"name"`
+	Priority  int              `json:"priority"`
+	Threshold float64          `json:"threshold"` // 默认为1
+	Action    bool             `json:"action"`    // 是否需要处理（回复、批准等），用于待回复清单
+	Rules     []classifierRule `json:"rules"`
+}
+
+// classifierRule 在指定字段中查找匹配项。words按单词边界匹配（中文按子串），contains按子串匹配，
+// regex为正则表达式，均不区分大小写。每命中一个不同的词加一次weight；negate规则命中时排除该分类，
+// required规则未命中时该分类不成立
+type classifierRule struct {
+	Fields   []string `json:"fields"` // 默认为 subject 和 body
+	Words    []string `json:"words"`
+	Contains []string `json:"contains"`
+	Regex    []string `json:"regex"`
+	Weight   float64  `json:"weight"` // 默认为1，可为负数
+	Negate   bool     `json:"negate"`
+	Required bool     `json:"required"`
+
+	pattern *regexp.Regexp
+}
+
+// categoryCount 是分类统计中的一行
+type categoryCount struct {
+	Category *emailCategory
+	Count    int
+}
+
+// 规则可匹配的字段
+var classifierFields = map[string]bool{
+	"subject": true, "body": true, "sender": true, "sender_domain": true, "to": true, "cc": true,
+}
+
+// defaultClassifierJSON 是未指定规则文件时使用的规则
+const defaultClassifierJSON = `{
+	"default": "信息类",
+	"categories": [
+		{
+			"name": "需要批准", "priority": 20, "action": true,
+			"rules": [
+				{"words": ["approve", "approval", "authorize", "authorization", "confirm", "confirmation"],
+				 "contains": ["批准", "审批", "确认", "同意", "授权", "核准", "签核"]}
+			]
+		},
+		{
+			"name": "需要回复", "priority": 10, "action": true,
+			"rules": [
+				{"words": ["reply", "response", "feedback", "urgent"],
+				 "contains": ["回复", "回应", "反馈", "意见", "建议", "紧急", "请回覆"]}
+			]
+		}
+	]
+}`
+
+func newDefaultClassifier() *emailClassifier {
+	classifier, err := parseClassifier([]byte(defaultClassifierJSON))
+	if err != nil {
+		panic(err)
+	}
+	return classifier
+}
+
+func loadClassifier(path string) (*emailClassifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取规则文件失败: %v", err)
+	}
+	// YAML规则与JSON规则结构相同，转换为JSON后解析
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		value, err := parseYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("规则文件 %s 无效: %v", path, err)
+		}
+		if data, err = json.Marshal(value); err != nil {
+			return nil, fmt.Errorf("规则文件 %s 无效: %v", path, err)
+		}
+	}
+	classifier, err := parseClassifier(data)
+	if err != nil {
+		return nil, fmt.Errorf("规则文件 %s 无效: %v", path, err)
+	}
+	return classifier, nil
+}
+
+func parseClassifier(data []byte) (*emailClassifier, error) {
+	var classifier emailClassifier
+	if err := json.Unmarshal(data, &classifier); err != nil {
+		return nil, err
+	}
+	if classifier.Default == "" {
+		classifier.Default = "其他"
+	}
+
+	names := map[string]bool{classifier.Default: true}
+	for i := range classifier.Categories {
+		category := &classifier.Categories[i]
+		if category.Name == "" {
+			return nil, fmt.Errorf("第%d个分类没有名称", i+1)
+		}
+		if names[category.Name] {
+			return nil, fmt.Errorf("分类名称重复: %s", category.Name)
+		}
+		names[category.Name] = true
+		if category.Threshold == 0 {
+			category.Threshold = 1
+		}
+		if len(category.Rules) == 0 {
+			return nil, fmt.Errorf("分类 %s 没有规则", category.Name)
+		}
+		for j := range category.Rules {
+			if err := category.Rules[j].compile(); err != nil {
+				return nil, fmt.Errorf("分类 %s 的第%d条规则: %v", category.Name, j+1, err)
+			}
+		}
+	}
+	// 默认分类放在最后，不含规则
+	classifier.Categories = append(classifier.Categories, emailCategory{Name: classifier.Default})
+	return &classifier, nil
+}
+
+// compile 将所有匹配项合并为一个正则表达式
+func (r *classifierRule) compile() error {
+	if len(r.Fields) == 0 {
+		r.Fields = []string{"subject", "body"}
+	}
+	for _, field := range r.Fields {
+		if !classifierFields[field] {
+			return fmt.Errorf("未知字段 %s（可选 subject、body、sender、sender_domain、to、cc）", field)
+		}
+	}
+	if r.Weight == 0 {
+		r.Weight = 1
+	}
+
+	var alternatives []string
+	for _, word := range r.Words {
+		alternatives = append(alternatives, wordPattern(word))
+	}
+	for _, text := range r.Contains {
+		alternatives = append(alternatives, regexp.QuoteMeta(text))
+	}
+	for _, expr := range r.Regex {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("无效的正则表达式 %s: %v", expr, err)
+		}
+		alternatives = append(alternatives, "(?:"+expr+")")
+	}
+	if len(alternatives) == 0 {
+		return fmt.Errorf("没有words、contains或regex匹配项")
+	}
+	pattern, err := regexp.Compile("(?i)" + strings.Join(alternatives, "|"))
+	if err != nil {
+		return err
+	}
+	r.pattern = pattern
+	return nil
+}
+
+// wordPattern 在以字母或数字开头/结尾的一侧加上单词边界，使 "info" 不匹配 "information"。
+// 中文没有单词边界，按子串匹配
+func wordPattern(word string) string {
+	pattern := regexp.QuoteMeta(word)
+	runes := []rune(word)
+	if len(runes) == 0 {
+		return pattern
+	}
+	isWordRune := func(r rune) bool { return r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r)) }
+	if isWordRune(runes[0]) {
+		pattern = `\b` + pattern
+	}
+	if isWordRune(runes[len(runes)-1]) {
+		pattern += `\b`
+	}
+	return pattern
+}
+
+// matchCount 返回各字段中命中的不同匹配项的个数
+func (r *classifierRule) matchCount(fields map[string]string) int {
+	found := make(map[string]bool)
+	for _, field := range r.Fields {
+		for _, match := range r.pattern.FindAllString(fields[field], -1) {
+			found[strings.ToLower(match)] = true
+		}
+	}
+	return len(found)
+}
+
+// emailFields 提取规则匹配使用的字段。主题去掉回复/转发前缀，正文去掉引用的原邮件，
+// 避免 "Re:"、"回复:" 以及引用内容影响分类
+func emailFields(email EmailInfo) map[string]string {
+	subject, _ := normalizeSubject(email.Subject)
+	body := email.Body
+	if loc := quoteStartPattern.FindStringIndex(body); loc != nil {
+		body = body[:loc[0]]
+	}
+	domain := ""
+	if at := strings.LastIndex(email.SenderEmail, "@"); at >= 0 {
+		domain = strings.ToLower(email.SenderEmail[at+1:])
+	}
+	return map[string]string{
+		"subject":       subject,
+		"body":          body,
+		"sender":        email.SenderName + " " + email.SenderEmail,
+		"sender_domain": domain,
+		"to":            email.To,
+		"cc":            email.CC,
+	}
+}
+
+// labels 返回所有成立的分类，按优先级从高到低排列。置信度按得分与阈值之比 r 计算：
+// 分类成立时为 (r+1)/(r+2)，刚好达到阈值时为2/3，命中的词越多越接近1；
+// 归入默认分类时按最接近阈值的分类计算 (2-r)/(3-r)，没有任何命中时为2/3，接近阈值时降到1/2
+func (c *emailClassifier) labels(email EmailInfo) []emailLabel {
+	fields := emailFields(email)
+
+	var labels []emailLabel
+	bestPartial := 0.0
+	for i := range c.Categories[:len(c.Categories)-1] {
+		category := &c.Categories[i]
+		score, ok := category.score(fields)
+		if score <= 0 {
+			continue
+		}
+		ratio := score / category.Threshold
+		if !ok {
+			if ratio > bestPartial {
+				bestPartial = ratio
+			}
+			continue
+		}
+		labels = append(labels, emailLabel{Category: category, Confidence: (ratio + 1) / (ratio + 2)})
+	}
+	if len(labels) == 0 {
+		// 有分类接近阈值时，归入默认分类的把握相应降低
+		return []emailLabel{{Category: c.defaultCategory(), Confidence: (2 - bestPartial) / (3 - bestPartial)}}
+	}
+	sort.SliceStable(labels, func(i, j int) bool {
+		if labels[i].Category.Priority != labels[j].Category.Priority {
+			return labels[i].Category.Priority > labels[j].Category.Priority
+		}
+		return labels[i].Confidence > labels[j].Confidence
+	})
+	return labels
+}
+
+func (c *emailClassifier) defaultCategory() *emailCategory {
+	return &c.Categories[len(c.Categories)-1]
+}
+
+// score 返回规则得分之和，以及分类是否成立
+func (category *emailCategory) score(fields map[string]string) (float64, bool) {
+	score := 0.0
+	for i := range category.Rules {
+		rule := &category.Rules[i]
+		count := rule.matchCount(fields)
+		switch {
+		case rule.Negate:
+			if count > 0 {
+				return 0, false
+			}
+		case count > 0:
+			score += rule.Weight * float64(count)
+		case rule.Required:
+			return 0, false
+		}
+	}
+	return score, score >= category.Threshold
+}
+
+func (c *emailClassifier) categories() []*emailCategory {
+	categories := make([]*emailCategory, 0, len(c.Categories))
+	for i := range c.Categories {
+		categories = append(categories, &c.Categories[i])
+	}
+	return categories
+}
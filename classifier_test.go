@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testClassifierJSON = `{
+	"default": "其他",
+	"categories": [
+		{
+			"name": "财务", "priority": 5, "threshold": 2,
+			"rules": [
+				{"contains": ["发票", "报销", "预算"]},
+				{"fields": ["sender_domain"], "words": ["finance.example.com"], "weight": 2},
+				{"words": ["newsletter"], "negate": true}
+			]
+		},
+		{
+			"name": "需要批准", "priority": 20, "action": true,
+			"rules": [
+				{"words": ["approve", "approval"], "contains": ["审批", "批准"]},
+				{"fields": ["subject"], "regex": ["^(待办|TODO)[:：]"], "required": true}
+			]
+		},
+		{
+			"name": "信息", "priority": 1,
+			"rules": [{"words": ["info"], "contains": ["通知"]}]
+		}
+	]
+}`
+
+func TestRuleClassifierLabels(t *testing.T) {
+	classifier, err := parseClassifier([]byte(testClassifierJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		email EmailInfo
+		want  string // 按优先级排列的 分类:置信度
+	}{
+		{"two hits reach threshold",
+			EmailInfo{Subject: "三月报销", Body: "发票已附上"}, "财务:0.67"},
+		{"sender domain weight",
+			EmailInfo{Subject: "预算", SenderEmail: "cfo@Finance.Example.com"}, "财务:0.71"},
+		{"below threshold is default with reduced confidence",
+			EmailInfo{Subject: "发票"}, "其他:0.60"},
+		{"negate excludes category",
+			EmailInfo{Subject: "Finance newsletter: 预算 发票"}, "其他:0.67"},
+		{"required rule missing",
+			EmailInfo{Subject: "请审批采购单"}, "其他:0.67"},
+		{"required rule present, multiple labels sorted by priority",
+			EmailInfo{Subject: "RE: 待办：请审批 预算 和 发票", Body: "approval needed, info"}, "需要批准:0.80 财务:0.67 信息:0.67"},
+		{"word boundary",
+			EmailInfo{Subject: "information about approvals"}, "其他:0.67"},
+		{"quoted text ignored",
+			EmailInfo{Subject: "收到", Body: "好的\n-----Original Message-----\n请报销这张发票"}, "其他:0.67"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, label := range classifier.labels(tt.email) {
+			got = append(got, fmt.Sprintf("%s:%.2f", label.Category.Name, label.Confidence))
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s: labels = %v, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if names := categoryNames(classifier.categories()); names != "财务,需要批准,信息,其他" {
+		t.Errorf("categories = %s", names)
+	}
+}
+
+func categoryNames(categories []*emailCategory) string {
+	var names []string
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// testClassifierYAML 与testClassifierJSON内容相同
+const testClassifierYAML = `default: 其他
+categories:
+  - name: 财务
+    priority: 5
+    threshold: 2
+    rules:
+      - contains: [发票, 报销, 预算]
+      - fields: [sender_domain]
+        words: [finance.example.com]
+        weight: 2
+      - words: [newsletter]
+        negate: true
+
+  # 需要处理的分类
+  - name: 需要批准
+    priority: 20
+    action: true
+    rules:
+      - words: [approve, approval]
+        contains: [审批, 批准]
+      - fields: [subject]
+        regex: ["^(待办|TODO)[:：]"]
+        required: true
+  - name: 信息
+    priority: 1
+    rules:
+    - words: [info]
+      contains: [通知]
+`
+
+func TestLoadClassifierYAML(t *testing.T) {
+	want, err := parseClassifier([]byte(testClassifierJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"rules.yaml", "rules.YML"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(testClassifierYAML), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := loadClassifier(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: classifier = %+v, want %+v", name, got, want)
+		}
+	}
+
+	path := filepath.Join(dir, "broken.yaml")
+	if err := os.WriteFile(path, []byte("categories:\n  - name: a\n   rules: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadClassifier(path); err == nil || !strings.Contains(err.Error(), "第3行") {
+		t.Errorf("broken YAML: err = %v", err)
+	}
+}
+
+func TestParseClassifierErrors(t *testing.T) {
+	tests := []struct {
+		json string
+		want string
+	}{
+		{`{"categories":[{"rules":[{"words":["a"]}]}]}`, "第1个分类没有名称"},
+		{`{"categories":[{"name":"a","rules":[{"words":["a"]}]},{"name":"a","rules":[{"words":["b"]}]}]}`, "分类名称重复: a"},
+		{`{"default":"a","categories":[{"name":"a","rules":[{"words":["a"]}]}]}`, "分类名称重复: a"},
+		{`{"categories":[{"name":"a"}]}`, "分类 a 没有规则"},
+		{`{"categories":[{"name":"a","rules":[{"fields":["subjct"],"words":["a"]}]}]}`, "未知字段 subjct"},
+		{`{"categories":[{"name":"a","rules":[{"regex":["("]}]}]}`, "无效的正则表达式"},
+		{`{"categories":[{"name":"a","rules":[{}]}]}`, "没有words、contains或regex匹配项"},
+		{`{"categories":`, "unexpected end of JSON input"},
+	}
+	for _, tt := range tests {
+		if _, err := parseClassifier([]byte(tt.json)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseClassifier(%s) err = %v, want %q", tt.json, err, tt.want)
+		}
+	}
+}
+
+func TestDefaultClassifier(t *testing.T) {
+	classifier := newDefaultClassifier()
+	tests := map[string]string{
+		"Please approve the budget": "需要批准",
+		"请尽快回复":                     "需要回复",
+		"本周会议纪要":                    "信息类",
+		"Information only":          "信息类",
+	}
+	for subject, want := range tests {
+		if got := classifier.labels(EmailInfo{Subject: subject})[0].Category.Name; got != want {
+			t.Errorf("%q: category = %s, want %s", subject, got, want)
+		}
+	}
+}
+
+func TestWordPattern(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		{"info", `\binfo\b`},
+		{"C++", `\bC\+\+`},
+		{"批准", `批准`},
+		{"OK批准", `\bOK批准`},
+		{"", ``},
+	}
+	for _, tt := range tests {
+		if got := wordPattern(tt.word); got != tt.want {
+			t.Errorf("wordPattern(%q) = %s, want %s", tt.word, got, tt.want)
+		}
+	}
+}
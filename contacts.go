@@ -0,0 +1,456 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// contactResolver 把同一个人的不同地址和显示名归为一个联系人，按联系人统计发件人、收件人等。
+// 地址统一大小写、去掉+后缀并换算别名域名；同一域名下地址的用户名与显示名一致（如 zhang.san 与 "Zhang San"）
+// 的视为同一人，只是显示名相同的不合并，可能是重名的不同人；
+// 只有显示名（如Outlook的收件人列表）或Exchange DN的，在该显示名只对应一个联系人时并入该联系人。
+// 别名文件可以把任意地址和显示名指定为同一人
+type contactResolver struct {
+	aliasDomains map[string]string // 别名域名 -> 主域名
+	// 并查集，键为 addr:地址、name:域名:显示名、bare:显示名（没有域名）和 compact:域名:连写的姓名
+	parent map[string]string
+	// 别名文件中指定的名称，键为该组的任一成员
+	primary map[string]string
+	// 别名文件中列出的显示名，这些显示名在所有域名下都并入同一人
+	forced map[string]bool
+	// 显示名 -> 使用该显示名的键（带域名的显示名键或地址键），用于把只有显示名的联系人并入唯一对应的联系人
+	namedKeys map[string]map[string]bool
+	bareNames map[string]bool
+
+	// 每个键出现过的显示名和地址及次数，用于生成联系人的显示名称
+	names     map[string]map[string]int
+	addresses map[string]map[string]int
+	labels    map[string]string
+}
+
+// knownAliasDomains 是内置的别名域名
+var knownAliasDomains = map[string]string{"googlemail.com": "gmail.com"}
+
+func newContactResolver() *contactResolver {
+	cr := &contactResolver{
+		aliasDomains: make(map[string]string),
+		parent:       make(map[string]string),
+		primary:      make(map[string]string),
+		forced:       make(map[string]bool),
+		namedKeys:    make(map[string]map[string]bool),
+		bareNames:    make(map[string]bool),
+		names:        make(map[string]map[string]int),
+		addresses:    make(map[string]map[string]int),
+	}
+	for alias, domain := range knownAliasDomains {
+		cr.aliasDomains[alias] = domain
+	}
+	return cr
+}
+
+// loadContactAliases 读取别名文件（CSV），每行是同一个人：
+//
+//	Zhang San,zhangsan@corp.com,San.Zhang@corp.cn,张三
+//
+// 第一列不是地址时作为该联系人显示的名称。所有项都以@开头的行是别名域名，第一项为主域名：
+//
+//	@corp.com,@corp.cn,@corp.com.cn
+func loadContactAliases(path string) (*contactResolver, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取别名文件失败: %v", err)
+	}
+	reader := csv.NewReader(strings.NewReader(decodeCSVText(data)))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("别名文件 %s 无效: %v", path, err)
+	}
+
+	cr := newContactResolver()
+	var people [][]string
+	for _, record := range records {
+		var cells []string
+		for _, cell := range record {
+			if cell = strings.TrimSpace(cell); cell != "" {
+				cells = append(cells, cell)
+			}
+		}
+		if len(cells) < 2 {
+			continue
+		}
+		if isDomainRow(cells) {
+			domain := strings.ToLower(cells[0][1:])
+			for _, alias := range cells[1:] {
+				cr.aliasDomains[strings.ToLower(alias[1:])] = domain
+			}
+			continue
+		}
+		people = append(people, cells)
+	}
+	// 别名域名可能写在后面，地址在读完所有域名后再换算
+	for _, cells := range people {
+		first := cr.aliasKey(cells[0])
+		if !strings.Contains(cells[0], "@") && !isExchangeDN(cells[0]) {
+			cr.primary[first] = cells[0]
+		}
+		for _, cell := range cells[1:] {
+			cr.union(first, cr.aliasKey(cell))
+		}
+	}
+	return cr, nil
+}
+
+func isDomainRow(cells []string) bool {
+	for _, cell := range cells {
+		if !strings.HasPrefix(cell, "@") || len(cell) == 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// aliasKey 返回别名文件中一项的键，显示名在所有域名下都视为同一人
+func (cr *contactResolver) aliasKey(cell string) string {
+	if strings.Contains(cell, "@") || isExchangeDN(cell) {
+		address, _ := cr.canonicalAddress(cell)
+		return "addr:" + address
+	}
+	name := normalizeContactName(cell)
+	cr.forced[name] = true
+	cr.bareNames[name] = true
+	return "bare:" + name
+}
+
+// canonicalAddress 返回统一后的地址和域名：小写、去掉+后缀、别名域名换算为主域名，
+// Gmail地址忽略用户名中的点。Exchange DN没有域名
+func (cr *contactResolver) canonicalAddress(address string) (string, string) {
+	address = strings.ToLower(strings.Trim(strings.TrimSpace(address), "<>"))
+	address = strings.TrimPrefix(address, "smtp:")
+	if isExchangeDN(address) {
+		return normalizeDN(address), ""
+	}
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return address, ""
+	}
+	local, domain := address[:at], address[at+1:]
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+	if canonical, ok := cr.aliasDomains[domain]; ok {
+		domain = canonical
+	}
+	if domain == "gmail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + domain, domain
+}
+
+// normalizeContactName 统一显示名：去掉引号和括号中的说明，忽略大小写和标点；
+// 拉丁字母的名字忽略姓名顺序（"Zhang San" 与 "San Zhang"、"Doe, Jane" 与 "Jane Doe"），中文忽略空格
+func normalizeContactName(name string) string {
+	if open := strings.IndexAny(name, "(（"); open > 0 {
+		name = name[:open]
+	}
+	name = strings.ToLower(name)
+	tokens := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(tokens) == 0 {
+		return ""
+	}
+	for _, token := range tokens {
+		for _, r := range token {
+			if r >= 0x80 {
+				return strings.Join(tokens, "")
+			}
+		}
+	}
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+func (cr *contactResolver) find(key string) string {
+	parent, ok := cr.parent[key]
+	if !ok {
+		cr.parent[key] = key
+		return key
+	}
+	if parent == key {
+		return key
+	}
+	root := cr.find(parent)
+	cr.parent[key] = root
+	return root
+}
+
+func (cr *contactResolver) union(a, b string) {
+	rootA, rootB := cr.find(a), cr.find(b)
+	if rootA != rootB {
+		cr.parent[rootB] = rootA
+	}
+}
+
+func countValue(counts map[string]map[string]int, key, value string) {
+	if counts[key] == nil {
+		counts[key] = make(map[string]int)
+	}
+	counts[key][value]++
+}
+
+// observe 记录一次出现的显示名和地址，二者都可以为空
+func (cr *contactResolver) observe(name, address string) {
+	name = strings.Trim(strings.TrimSpace(name), `"'`)
+	if strings.Contains(name, "@") && address == "" {
+		name, address = "", name
+	}
+	normalized := normalizeContactName(name)
+	if address == "" {
+		if normalized == "" {
+			return
+		}
+		key := "bare:" + normalized
+		cr.find(key)
+		cr.bareNames[normalized] = true
+		countValue(cr.names, key, name)
+		return
+	}
+
+	canonical, domain := cr.canonicalAddress(address)
+	key := "addr:" + canonical
+	cr.find(key)
+	countValue(cr.addresses, key, strings.TrimSpace(address))
+	local := ""
+	if domain != "" {
+		// 用户名本身给出姓名：san.zhang 对应 "Zhang San"；zhangsan 在有显示名时才能拆分，先按连写的姓名关联
+		local = canonical[:strings.LastIndex(canonical, "@")]
+		if localName := normalizeContactName(local); strings.Contains(localName, " ") {
+			cr.linkName(key, domain, localName)
+		} else if !strings.ContainsAny(local, "._-") {
+			cr.union(key, "compact:"+domain+":"+local)
+		}
+	}
+	if normalized == "" || strings.Contains(name, "@") {
+		return
+	}
+	countValue(cr.names, key, name)
+	if domain == "" {
+		// Exchange DN没有域名，按只有显示名处理
+		cr.union(key, "bare:"+normalized)
+		cr.bareNames[normalized] = true
+		return
+	}
+	if !localSpellsName(local, normalized) {
+		// 只是显示名相同，不与其他地址合并
+		cr.noteName(key, normalized)
+		return
+	}
+	cr.linkName(key, domain, normalized)
+	if tokens := strings.Fields(normalized); len(tokens) >= 2 {
+		cr.union(key, "compact:"+domain+":"+strings.Join(tokens, ""))
+		reversed := make([]string, len(tokens))
+		for i, token := range tokens {
+			reversed[len(tokens)-1-i] = token
+		}
+		cr.union(key, "compact:"+domain+":"+strings.Join(reversed, ""))
+	}
+}
+
+// localSpellsName 判断地址的用户名是否由显示名的各部分组成（顺序不限，可用 . _ - 分隔），
+// 如 zhangsan、san.zhang 对应 "Zhang San"；normalized 为 normalizeContactName 的结果
+func localSpellsName(local, normalized string) bool {
+	compact := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, local)
+	return compact != "" && spellsTokens(compact, strings.Fields(normalized))
+}
+
+func spellsTokens(s string, tokens []string) bool {
+	if len(tokens) == 0 {
+		return s == ""
+	}
+	for i, token := range tokens {
+		if strings.HasPrefix(s, token) {
+			rest := append(append([]string{}, tokens[:i]...), tokens[i+1:]...)
+			if spellsTokens(s[len(token):], rest) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// linkName 把地址并入同一域名下的显示名
+func (cr *contactResolver) linkName(key, domain, name string) {
+	scoped := "name:" + domain + ":" + name
+	cr.union(key, scoped)
+	cr.noteName(scoped, name)
+}
+
+// noteName 记录使用该显示名的键
+func (cr *contactResolver) noteName(key, name string) {
+	if cr.namedKeys[name] == nil {
+		cr.namedKeys[name] = make(map[string]bool)
+	}
+	cr.namedKeys[name][key] = true
+}
+
+// observeEmails 记录邮件中的发件人和收件人
+func (cr *contactResolver) observeEmails(emails []EmailInfo) {
+	for _, email := range emails {
+		cr.observe(email.SenderName, email.SenderEmail)
+		for _, recipient := range email.recipientsOf() {
+			cr.observe(recipient.Name, recipient.Address)
+		}
+	}
+}
+
+// parseRecipient 解析收件人列表中的一项，可能是 "名称 <地址>"、地址或只有名称
+func parseRecipient(recipient string) (string, string) {
+	recipient = strings.TrimSpace(recipient)
+	if address, err := parseAddress(recipient); err == nil {
+		return address.Name, address.Address
+	}
+	if strings.Contains(recipient, "@") || isExchangeDN(recipient) {
+		return "", recipient
+	}
+	return recipient, ""
+}
+
+// finish 在记录完所有邮件后调用：把只有显示名的联系人并入唯一对应的联系人，并生成显示名称
+func (cr *contactResolver) finish() {
+	for name := range cr.bareNames {
+		roots := make(map[string]string)
+		for key := range cr.namedKeys[name] {
+			roots[cr.find(key)] = key
+		}
+		if len(roots) == 1 || cr.forced[name] {
+			for _, key := range roots {
+				cr.union("bare:"+name, key)
+			}
+		}
+	}
+
+	// 显示名称：别名文件中的名称，否则为最常见的显示名和地址
+	names := make(map[string]map[string]int)
+	addresses := make(map[string]map[string]int)
+	primary := make(map[string]string)
+	for key, counts := range cr.names {
+		root := cr.find(key)
+		for value, count := range counts {
+			if names[root] == nil {
+				names[root] = make(map[string]int)
+			}
+			names[root][value] += count
+		}
+	}
+	for key, counts := range cr.addresses {
+		root := cr.find(key)
+		for value, count := range counts {
+			if addresses[root] == nil {
+				addresses[root] = make(map[string]int)
+			}
+			addresses[root][strings.ToLower(value)] += count
+		}
+	}
+	for key, name := range cr.primary {
+		primary[cr.find(key)] = name
+	}
+
+	cr.labels = make(map[string]string)
+	for key := range cr.parent {
+		root := cr.find(key)
+		if _, done := cr.labels[root]; done {
+			continue
+		}
+		name := primary[root]
+		if name == "" {
+			name = mostFrequent(names[root])
+		}
+		address := mostFrequentSMTP(addresses[root])
+		switch {
+		case name != "" && address != "":
+			cr.labels[root] = fmt.Sprintf("%s <%s>", name, address)
+		case name != "":
+			cr.labels[root] = name
+		default:
+			cr.labels[root] = address
+		}
+	}
+}
+
+// mostFrequent 返回出现次数最多的值，次数相同时取字典序最小的，保证结果稳定
+func mostFrequent(counts map[string]int) string {
+	best, bestCount := "", 0
+	for value, count := range counts {
+		if count > bestCount || count == bestCount && value < best {
+			best, bestCount = value, count
+		}
+	}
+	return best
+}
+
+// mostFrequentSMTP 优先使用SMTP地址，只有Exchange DN时才显示DN
+func mostFrequentSMTP(counts map[string]int) string {
+	smtp := make(map[string]int)
+	for value, count := range counts {
+		if !isExchangeDN(value) {
+			smtp[value] = count
+		}
+	}
+	if len(smtp) > 0 {
+		return mostFrequent(smtp)
+	}
+	return mostFrequent(counts)
+}
+
+// identity 返回显示名和地址所属的联系人，都为空时返回空字符串
+func (cr *contactResolver) identity(name, address string) string {
+	name = strings.Trim(strings.TrimSpace(name), `"'`)
+	if address == "" && strings.Contains(name, "@") {
+		address = name
+	}
+	if strings.TrimSpace(address) != "" {
+		canonical, _ := cr.canonicalAddress(address)
+		return cr.find("addr:" + canonical)
+	}
+	if normalized := normalizeContactName(name); normalized != "" {
+		return cr.find("bare:" + normalized)
+	}
+	return ""
+}
+
+// label 返回联系人的显示名称，如 "Zhang San <zhangsan@corp.com>"
+func (cr *contactResolver) label(identity string) string {
+	if label, ok := cr.labels[cr.find(identity)]; ok && label != "" {
+		return label
+	}
+	_, value, _ := strings.Cut(identity, ":")
+	return value
+}
+
+// senderIdentity 返回邮件发件人所属的联系人
+func (cr *contactResolver) senderIdentity(email EmailInfo) string {
+	return cr.identity(email.SenderName, email.SenderEmail)
+}
+
+// recipientIdentities 返回收件人对应的联系人，同一人只出现一次
+func (cr *contactResolver) recipientIdentities(recipients []Recipient) []string {
+	var identities []string
+	for _, recipient := range recipients {
+		if identity := cr.identity(recipient.Name, recipient.Address); identity != "" && !containsString(identities, identity) {
+			identities = append(identities, identity)
+		}
+	}
+	return identities
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonicalAddress(t *testing.T) {
+	cr := newContactResolver()
+	cr.aliasDomains["corp.cn"] = "corp.com"
+	tests := []struct {
+		address, canonical, domain string
+	}{
+		{" <ZhangSan@Corp.com> ", "zhangsan@corp.com", "corp.com"},
+		{"zhangsan+newsletter@corp.com", "zhangsan@corp.com", "corp.com"},
+		{"zhangsan@corp.cn", "zhangsan@corp.com", "corp.com"},
+		{"SMTP:zhangsan@corp.cn", "zhangsan@corp.com", "corp.com"},
+		{"Zhang.San+x@googlemail.com", "zhangsan@gmail.com", "gmail.com"},
+		// +开头的用户名不是后缀
+		{"+1@corp.com", "+1@corp.com", "corp.com"},
+		{"EX:/O=CORP/OU=EXCHANGE/CN=RECIPIENTS/CN=ZHANGSAN", "/o=corp/ou=exchange/cn=recipients/cn=zhangsan", ""},
+	}
+	for _, tt := range tests {
+		canonical, domain := cr.canonicalAddress(tt.address)
+		if canonical != tt.canonical || domain != tt.domain {
+			t.Errorf("canonicalAddress(%q) = %q, %q, want %q, %q", tt.address, canonical, domain, tt.canonical, tt.domain)
+		}
+	}
+}
+
+func TestNormalizeContactName(t *testing.T) {
+	tests := map[string]string{
+		"Zhang San":             "san zhang",
+		"San Zhang":             "san zhang",
+		"Doe, Jane":             "doe jane",
+		"Jane Doe (Finance)":    "doe jane",
+		"张 三":                   "张三",
+		"张三（财务部）":               "张三",
+		"  ":                    "",
+		"zhang.san":             "san zhang",
+		"Help-Desk 2nd Line":    "2nd desk help line",
+		"HELPDESK":              "helpdesk",
+		"\"Zhang San\" (Sales)": "san zhang",
+	}
+	for name, want := range tests {
+		if got := normalizeContactName(name); got != want {
+			t.Errorf("normalizeContactName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLocalSpellsName(t *testing.T) {
+	tests := []struct {
+		local, name string
+		want        bool
+	}{
+		{"zhangsan", "Zhang San", true},
+		{"sanzhang", "Zhang San", true},
+		{"zhang.san", "San Zhang", true},
+		{"jane_doe", "Doe, Jane", true},
+		{"zs1", "Zhang San", false},
+		{"zhangsan2", "Zhang San", false},
+		{"zhang", "Zhang San", false},
+		{"zhangsan", "张三", false},
+		{"", "Zhang San", false},
+	}
+	for _, tt := range tests {
+		if got := localSpellsName(tt.local, normalizeContactName(tt.name)); got != tt.want {
+			t.Errorf("localSpellsName(%q, %q) = %v, want %v", tt.local, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestContactResolverMerges(t *testing.T) {
+	cr := newContactResolver()
+	for _, observed := range [][2]string{
+		// 姓名顺序不同、用户名拼出姓名的地址合并，连写的用户名也并入
+		{"Zhang San", "zhang.san@corp.com"},
+		{"San Zhang", "San.Zhang+jira@corp.com"},
+		{"", "zhangsan@corp.com"},
+		// 只是显示名相同的不合并
+		{"Zhang San", "zs1@corp.com"},
+		{"王伟", "wangwei@corp.com"},
+		{"王伟", "wangwei2@corp.com"},
+		{"王伟", ""},
+		// Exchange DN和只有显示名的收件人并入唯一同名的联系人
+		{"李四", "/o=Corp/ou=Exchange/cn=Recipients/cn=lisi"},
+		{"李四", "lisi@corp.com"},
+		{"李四", ""},
+		// 其他域名下的同名联系人不合并
+		{"Zhang San", "zhangsan@other.com"},
+	} {
+		cr.observe(observed[0], observed[1])
+	}
+	cr.finish()
+
+	same := func(a, b [2]string) bool {
+		return cr.identity(a[0], a[1]) == cr.identity(b[0], b[1])
+	}
+	tests := []struct {
+		name string
+		a, b [2]string
+		want bool
+	}{
+		{"reversed name", [2]string{"", "zhang.san@corp.com"}, [2]string{"", "san.zhang@corp.com"}, true},
+		{"plus addressing", [2]string{"", "san.zhang@corp.com"}, [2]string{"", "san.zhang+other@corp.com"}, true},
+		{"compact local part", [2]string{"", "zhang.san@corp.com"}, [2]string{"", "ZhangSan@corp.com"}, true},
+		{"same name, unrelated address", [2]string{"", "zhang.san@corp.com"}, [2]string{"", "zs1@corp.com"}, false},
+		{"namesakes", [2]string{"", "wangwei@corp.com"}, [2]string{"", "wangwei2@corp.com"}, false},
+		{"ambiguous bare name", [2]string{"王伟", ""}, [2]string{"", "wangwei@corp.com"}, false},
+		{"X.500 address", [2]string{"", "EX:/O=CORP/OU=EXCHANGE/CN=RECIPIENTS/CN=LISI"}, [2]string{"", "lisi@corp.com"}, true},
+		{"bare name", [2]string{"李四", ""}, [2]string{"", "lisi@corp.com"}, true},
+		{"other domain", [2]string{"", "zhangsan@other.com"}, [2]string{"", "zhangsan@corp.com"}, false},
+	}
+	for _, tt := range tests {
+		if got := same(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: %v and %v merged = %v, want %v", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	if label := cr.label(cr.identity("", "/o=corp/ou=exchange/cn=recipients/cn=lisi")); label != "李四 <lisi@corp.com>" {
+		t.Errorf("X.500 label = %q", label)
+	}
+	if label := cr.label(cr.identity("", "zs1@corp.com")); label != "Zhang San <zs1@corp.com>" {
+		t.Errorf("namesake label = %q", label)
+	}
+}
+
+func TestLoadContactAliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.csv")
+	content := "# 姓名,地址...\n" +
+		"Zhang San,zhangsan@corp.com,zs1@corp.cn,张三\n" +
+		"wangwei@corp.com,王伟（北京）\n" +
+		"single\n" +
+		// 别名域名写在后面也对前面的地址生效
+		"@corp.com, @corp.cn ,@corp.com.cn\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cr, err := loadContactAliases(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cr.aliasDomains["corp.cn"] != "corp.com" || cr.aliasDomains["corp.com.cn"] != "corp.com" || cr.aliasDomains["googlemail.com"] != "gmail.com" {
+		t.Errorf("alias domains = %v", cr.aliasDomains)
+	}
+
+	cr.observeEmails([]EmailInfo{
+		{SenderName: "张三", SenderEmail: "zs1@corp.com.cn"},
+		{SenderName: "Zhang San", SenderEmail: "zhangsan@corp.cn", Recipients: []Recipient{
+			{Name: "王伟", Address: "wangwei+list@corp.com", Type: RecipientTo},
+			{Name: "张三", Type: RecipientCC},
+		}},
+		// 别名文件中的显示名在其他域名下也并入
+		{SenderName: "张三", SenderEmail: "zhangsan@gmail.com"},
+	})
+	cr.finish()
+
+	zhangsan := cr.identity("", "zhangsan@corp.com")
+	for _, other := range [][2]string{{"", "zs1@corp.com"}, {"张三", ""}, {"", "zhangsan@gmail.com"}, {"", "ZhangSan+x@corp.com.cn"}} {
+		if got := cr.identity(other[0], other[1]); got != zhangsan {
+			t.Errorf("%v not merged with zhangsan@corp.com", other)
+		}
+	}
+	if label := cr.label(zhangsan); label != "Zhang San <zhangsan@corp.cn>" {
+		t.Errorf("alias label = %q", label)
+	}
+	if cr.identity("王伟", "") != cr.identity("", "wangwei@corp.com") {
+		t.Error("王伟 not merged with wangwei@corp.com")
+	}
+
+	if _, err := loadContactAliases(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("missing alias file loaded")
+	}
+}
@@ -2,6 +2,9 @@ module outlook-analyzer
 
 go 1.19
 
-require github.com/go-ole/go-ole v1.3.0
+require (
+	github.com/go-ole/go-ole v1.3.0
+	golang.org/x/text v0.14.0
+)
 
-require github.com/go-ole/go-ole v1.3.0 // indirect 
\ No newline at end of file
+require golang.org/x/sys v0.5.0 // indirect
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 热力图按周一至周日排列，与time.Weekday的顺序不同
+var (
+	heatmapWeekdayNames = [7]string{"周一", "周二", "周三", "周四", "周五", "周六", "周日"}
+	heatmapWeekdayKeys  = [7]string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+	// 按数量从少到多的色块，0封为空白
+	heatmapShades = []string{"  ", "░░", "▒▒", "▓▓", "██"}
+)
+
+// trafficMatrix 是7×24的邮件数量，行为周一至周日，列为0-23时
+type trafficMatrix [7][24]int
+
+func (m *trafficMatrix) add(t time.Time) {
+	day := (int(t.Weekday()) + 6) % 7 // 周一为0
+	m[day][t.Hour()]++
+}
+
+func (m trafficMatrix) max() int {
+	result := 0
+	for _, row := range m {
+		for _, count := range row {
+			if count > result {
+				result = count
+			}
+		}
+	}
+	return result
+}
+
+// trafficShare 是一类邮件中落在非工作时间和周末的数量
+type trafficShare struct {
+	Total      int
+	AfterHours int // 不在工作日历的工作时段内，包括周末和节假日
+	Weekend    int // 热力图时区的周六和周日
+}
+
+func (s trafficShare) afterHoursRate() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.AfterHours) / float64(s.Total) * 100
+}
+
+func (s trafficShare) weekendRate() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Weekend) / float64(s.Total) * 100
+}
+
+// trafficHeatmap 统计收到和发出邮件在一周各天、各小时的分布
+type trafficHeatmap struct {
+	Location      *time.Location
+	Received      trafficMatrix
+	Sent          trafficMatrix
+	ReceivedShare trafficShare
+	SentShare     trafficShare
+}
+
+// analyzeTraffic 按指定时区把收到邮件的接收时间和发出邮件的发送时间计入热力图，
+// 非工作时间按工作日历判断
+func (oa *OutlookEmailAnalyzer) analyzeTraffic(receivedEmails, sentEmails []EmailInfo, loc *time.Location) trafficHeatmap {
+	heatmap := trafficHeatmap{Location: loc}
+	count := func(t time.Time, matrix *trafficMatrix, share *trafficShare) {
+		if t.IsZero() {
+			return
+		}
+		t = t.In(loc)
+		matrix.add(t)
+		share.Total++
+		if !oa.calendar.isWorkingTime(t) {
+			share.AfterHours++
+		}
+		if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+			share.Weekend++
+		}
+	}
+	for _, email := range receivedEmails {
+		count(email.ReceivedTime, &heatmap.Received, &heatmap.ReceivedShare)
+	}
+	for _, email := range sentEmails {
+		count(email.SentTime, &heatmap.Sent, &heatmap.SentShare)
+	}
+	return heatmap
+}
+
+func (oa *OutlookEmailAnalyzer) printTraffic(heatmap trafficHeatmap) {
+	fmt.Printf("\n🕒 12. 收发时间分布 (时区: %s):\n", heatmap.Location)
+	if heatmap.ReceivedShare.Total == 0 && heatmap.SentShare.Total == 0 {
+		fmt.Printf("   无\n")
+		return
+	}
+	printTrafficMatrix("收到的邮件", heatmap.Received, heatmap.ReceivedShare)
+	printTrafficMatrix("发出的邮件", heatmap.Sent, heatmap.SentShare)
+	fmt.Printf("   色块: ░ 较少  ▒ 中等  ▓ 较多  █ 最多 (按各自的最大值分级)\n")
+}
+
+func printTrafficMatrix(title string, matrix trafficMatrix, share trafficShare) {
+	if share.Total == 0 {
+		return
+	}
+	fmt.Printf("   %s: %d 封，非工作时间 %.1f%%，周末 %.1f%%\n", title, share.Total, share.afterHoursRate(), share.weekendRate())
+	// 每格两个字符宽，每3小时标一次刻度
+	var header strings.Builder
+	for hour := 0; hour < 24; hour += 3 {
+		fmt.Fprintf(&header, "%-6d", hour)
+	}
+	fmt.Printf("        %s\n", header.String())
+	peak := matrix.max()
+	for day, row := range matrix {
+		var line strings.Builder
+		for _, count := range row {
+			line.WriteString(heatmapShade(count, peak))
+		}
+		fmt.Printf("   %s %s\n", heatmapWeekdayNames[day], line.String())
+	}
+}
+
+// heatmapShade 把数量按最大值分为四级，有邮件的格子至少为最浅的一级
+func heatmapShade(count, peak int) string {
+	if count == 0 || peak == 0 {
+		return heatmapShades[0]
+	}
+	level := (count*(len(heatmapShades)-1) + peak - 1) / peak
+	return heatmapShades[level]
+}
+
+// exportTraffic 按扩展名把热力图导出为CSV（每行一个方向、星期和小时）或JSON
+func exportTraffic(heatmap trafficHeatmap, path string) error {
+	var data []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		var builder strings.Builder
+		writer := csv.NewWriter(&builder)
+		writer.Write([]string{"direction", "weekday", "hour", "count"})
+		for _, matrix := range []struct {
+			direction string
+			counts    trafficMatrix
+		}{{"received", heatmap.Received}, {"sent", heatmap.Sent}} {
+			for day, row := range matrix.counts {
+				for hour, count := range row {
+					writer.Write([]string{matrix.direction, heatmapWeekdayKeys[day], strconv.Itoa(hour), strconv.Itoa(count)})
+				}
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return fmt.Errorf("导出时间分布失败: %v", err)
+		}
+		data = []byte(builder.String())
+	case ".json":
+		share := func(s trafficShare) map[string]interface{} {
+			result := map[string]interface{}{"total": s.Total, "after_hours": s.AfterHours, "weekend": s.Weekend}
+			if s.Total > 0 {
+				result["after_hours_share"] = s.afterHoursRate() / 100
+				result["weekend_share"] = s.weekendRate() / 100
+			}
+			return result
+		}
+		var err error
+		data, err = json.Marshal(map[string]interface{}{
+			"timezone":       heatmap.Location.String(),
+			"weekdays":       heatmapWeekdayKeys,
+			"received":       heatmap.Received,
+			"sent":           heatmap.Sent,
+			"received_share": share(heatmap.ReceivedShare),
+			"sent_share":     share(heatmap.SentShare),
+		})
+		if err != nil {
+			return fmt.Errorf("导出时间分布失败: %v", err)
+		}
+	default:
+		return fmt.Errorf("不支持的导出格式 %s（可用 .csv 或 .json）", path)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("导出时间分布失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeatmapShade(t *testing.T) {
+	tests := []struct {
+		count, peak int
+		want        string
+	}{
+		{0, 0, "  "},
+		{0, 10, "  "},
+		{1, 1, "██"},
+		// 非零数量至少为最浅的色块
+		{1, 100, "░░"},
+		{25, 100, "░░"},
+		{26, 100, "▒▒"},
+		{50, 100, "▒▒"},
+		{51, 100, "▓▓"},
+		{75, 100, "▓▓"},
+		{76, 100, "██"},
+		{100, 100, "██"},
+		{1, 3, "▒▒"},
+		{2, 3, "▓▓"},
+	}
+	for _, tt := range tests {
+		if got := heatmapShade(tt.count, tt.peak); got != tt.want {
+			t.Errorf("heatmapShade(%d, %d) = %q, want %q", tt.count, tt.peak, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeTraffic(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	oa := NewOutlookEmailAnalyzer(&stubMailSource{})
+	oa.calendar = newDefaultCalendar(shanghai)
+
+	// 2025-03-07 是周五
+	received := []EmailInfo{
+		{ReceivedTime: time.Date(2025, 3, 7, 2, 0, 0, 0, time.UTC)},  // 周五10:00
+		{ReceivedTime: time.Date(2025, 3, 7, 2, 30, 0, 0, time.UTC)}, // 周五10:30
+		{ReceivedTime: time.Date(2025, 3, 7, 14, 0, 0, 0, time.UTC)}, // 周五22:00
+		{ReceivedTime: time.Date(2025, 3, 7, 17, 0, 0, 0, time.UTC)}, // 周六01:00
+		{Subject: "没有时间"},
+	}
+	sent := []EmailInfo{{SentTime: time.Date(2025, 3, 9, 1, 0, 0, 0, time.UTC)}} // 周日09:00
+
+	heatmap := oa.analyzeTraffic(received, sent, shanghai)
+	if heatmap.Received[4][10] != 2 || heatmap.Received[4][22] != 1 || heatmap.Received[5][1] != 1 || heatmap.Sent[6][9] != 1 {
+		t.Errorf("received = %v, sent = %v", heatmap.Received, heatmap.Sent)
+	}
+	if heatmap.Received.max() != 2 {
+		t.Errorf("max = %d", heatmap.Received.max())
+	}
+	if heatmap.ReceivedShare != (trafficShare{Total: 4, AfterHours: 2, Weekend: 1}) || heatmap.SentShare != (trafficShare{Total: 1, AfterHours: 1, Weekend: 1}) {
+		t.Errorf("shares = %+v / %+v", heatmap.ReceivedShare, heatmap.SentShare)
+	}
+	if heatmap.ReceivedShare.afterHoursRate() != 50 || heatmap.ReceivedShare.weekendRate() != 25 {
+		t.Errorf("rates = %v / %v", heatmap.ReceivedShare.afterHoursRate(), heatmap.ReceivedShare.weekendRate())
+	}
+	if empty := (trafficShare{}); empty.afterHoursRate() != 0 || empty.weekendRate() != 0 {
+		t.Errorf("empty share rates = %v / %v", empty.afterHoursRate(), empty.weekendRate())
+	}
+
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "traffic.csv")
+	if err := exportTraffic(heatmap, csvPath); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1+2*7*24 || lines[0] != "direction,weekday,hour,count" || lines[1+4*24+10] != "received,Fri,10,2" {
+		t.Errorf("csv has %d lines, header %q", len(lines), lines[0])
+	}
+
+	jsonPath := filepath.Join(dir, "traffic.json")
+	if err := exportTraffic(heatmap, jsonPath); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported struct {
+		Timezone      string             `json:"timezone"`
+		Sent          [7][24]int         `json:"sent"`
+		ReceivedShare map[string]float64 `json:"received_share"`
+	}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatal(err)
+	}
+	if exported.Timezone != "CST" || exported.Sent[6][9] != 1 || exported.ReceivedShare["after_hours_share"] != 0.5 {
+		t.Errorf("json = %+v", exported)
+	}
+
+	if err := exportTraffic(heatmap, filepath.Join(dir, "traffic.txt")); err == nil || !strings.Contains(err.Error(), "不支持的导出格式") {
+		t.Errorf("txt export err = %v", err)
+	}
+}
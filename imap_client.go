@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// imapClient 是IMAP4rev1协议的最小客户端实现，只包含本工具需要的命令
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+	caps map[string]bool
+}
+
+// IMAP响应中的原子（如 OK、FETCH、NIL、\Seen），用于和带引号的字符串区分
+type imapAtom string
+
+const imapTimeout = 2 * time.Minute
+
+// 字面量长度由服务器给出，超过此上限时视为响应错误。FETCH只取邮件头和正文开头，正常不会接近上限
+const imapMaxLiteral = 16 << 20
+
+func dialIMAP(address string, useTLS bool) (*imapClient, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if useTLS {
+		host, _, _ := net.SplitHostPort(address)
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(imapTimeout))
+	greeting, err := c.readResponse()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("读取服务器问候失败: %v", err)
+	}
+	if len(greeting) < 2 || imapString(greeting[1]) == "BYE" {
+		conn.Close()
+		return nil, fmt.Errorf("服务器拒绝连接")
+	}
+
+	if err := c.capability(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *imapClient) Close() {
+	c.command("LOGOUT")
+	c.conn.Close()
+}
+
+func (c *imapClient) capability() error {
+	responses, err := c.command("CAPABILITY")
+	if err != nil {
+		return err
+	}
+	c.caps = make(map[string]bool)
+	for _, resp := range responses {
+		if len(resp) > 1 && strings.EqualFold(imapString(resp[1]), "CAPABILITY") {
+			for _, capability := range resp[2:] {
+				c.caps[strings.ToUpper(imapString(capability))] = true
+			}
+		}
+	}
+	return nil
+}
+
+// login 优先使用AUTHENTICATE PLAIN，服务器不支持时使用LOGIN
+func (c *imapClient) login(username, password string) error {
+	if c.caps["AUTH=PLAIN"] {
+		credentials := base64.StdEncoding.EncodeToString([]byte("\x00" + username + "\x00" + password))
+		if c.caps["SASL-IR"] {
+			_, err := c.command("AUTHENTICATE PLAIN " + credentials)
+			return err
+		}
+		_, err := c.commandWithContinuation("AUTHENTICATE PLAIN", credentials)
+		return err
+	}
+	if c.caps["LOGINDISABLED"] {
+		return fmt.Errorf("服务器禁止明文登录，请使用TLS连接")
+	}
+	_, err := c.command("LOGIN " + imapQuote(username) + " " + imapQuote(password))
+	return err
+}
+
+// command 发送一条带标签的命令，返回该命令期间收到的所有未标记响应
+func (c *imapClient) command(cmd string) ([][]interface{}, error) {
+	return c.commandWithContinuation(cmd, "")
+}
+
+func (c *imapClient) commandWithContinuation(cmd, continuation string) ([][]interface{}, error) {
+	c.tag++
+	tag := fmt.Sprintf("A%03d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(imapTimeout))
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, cmd); err != nil {
+		return nil, err
+	}
+
+	var untagged [][]interface{}
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return untagged, err
+		}
+		if len(resp) == 0 {
+			continue
+		}
+		switch first := imapString(resp[0]); first {
+		case "*":
+			untagged = append(untagged, resp)
+		case "+":
+			if _, err := fmt.Fprintf(c.conn, "%s\r\n", continuation); err != nil {
+				return untagged, err
+			}
+		case tag:
+			if len(resp) > 1 && strings.EqualFold(imapString(resp[1]), "OK") {
+				return untagged, nil
+			}
+			return untagged, fmt.Errorf("%s 失败: %s", strings.Fields(cmd)[0], imapJoin(resp[1:]))
+		}
+	}
+}
+
+// readResponse 读取并解析一行完整的响应（包括其中的字面量{n}）
+func (c *imapClient) readResponse() ([]interface{}, error) {
+	return c.parseList(0)
+}
+
+func (c *imapClient) parseList(depth int) ([]interface{}, error) {
+	var list []interface{}
+	for {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return list, err
+		}
+		switch b {
+		case ' ':
+			continue
+		case '\r':
+			continue
+		case '\n':
+			if depth == 0 {
+				return list, nil
+			}
+		case '(':
+			sub, err := c.parseList(depth + 1)
+			if err != nil {
+				return list, err
+			}
+			list = append(list, sub)
+		case ')':
+			if depth > 0 {
+				return list, nil
+			}
+		case '"':
+			s, err := c.parseQuoted()
+			if err != nil {
+				return list, err
+			}
+			list = append(list, s)
+		case '{':
+			s, err := c.parseLiteral()
+			if err != nil {
+				return list, err
+			}
+			list = append(list, s)
+		default:
+			c.r.UnreadByte()
+			atom, err := c.parseAtom()
+			if err != nil {
+				return list, err
+			}
+			list = append(list, imapAtom(atom))
+		}
+	}
+}
+
+func (c *imapClient) parseQuoted() (string, error) {
+	var sb strings.Builder
+	for {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch b {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			b, err = c.r.ReadByte()
+			if err != nil {
+				return "", err
+			}
+		}
+		sb.WriteByte(b)
+	}
+}
+
+func (c *imapClient) parseLiteral() (string, error) {
+	sizeStr, err := c.r.ReadString('}')
+	if err != nil {
+		return "", err
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(sizeStr, "}"), "+"))
+	if err != nil {
+		return "", fmt.Errorf("无效的字面量长度: %q", sizeStr)
+	}
+	if size < 0 || size > imapMaxLiteral {
+		return "", fmt.Errorf("字面量长度超出范围: %d", size)
+	}
+	if line, err := c.r.ReadString('\n'); err != nil || strings.TrimSpace(line) != "" {
+		return "", fmt.Errorf("字面量格式错误")
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(c.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// parseAtom 读取原子。方括号内的内容（如 BODY[HEADER.FIELDS (SUBJECT)]、[READ-ONLY]）整体归入原子
+func (c *imapClient) parseAtom() (string, error) {
+	var sb strings.Builder
+	brackets := 0
+	for {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return sb.String(), err
+		}
+		switch {
+		case b == '[':
+			brackets++
+		case b == ']' && brackets > 0:
+			brackets--
+		case brackets == 0 && (b == ' ' || b == '(' || b == ')' || b == '\r' || b == '\n'):
+			c.r.UnreadByte()
+			return sb.String(), nil
+		}
+		sb.WriteByte(b)
+	}
+}
+
+func imapString(v interface{}) string {
+	switch s := v.(type) {
+	case imapAtom:
+		return string(s)
+	case string:
+		return s
+	}
+	return ""
+}
+
+// imapNString 读取可为NIL的字符串
+func imapNString(v interface{}) string {
+	if atom, ok := v.(imapAtom); ok && strings.EqualFold(string(atom), "NIL") {
+		return ""
+	}
+	return imapString(v)
+}
+
+func imapJoin(values []interface{}) string {
+	var parts []string
+	for _, v := range values {
+		parts = append(parts, imapString(v))
+	}
+	return strings.Join(parts, " ")
+}
+
+func imapQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// decodeModifiedUTF7 解码IMAP文件夹名称使用的修改版UTF-7（RFC 3501 5.1.3），如 "&XfJSIJZk-" 为 "已删除"
+func decodeModifiedUTF7(name string) string {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '&' {
+			sb.WriteByte(name[i])
+			continue
+		}
+		end := strings.IndexByte(name[i:], '-')
+		if end < 0 {
+			sb.WriteString(name[i:])
+			break
+		}
+		encoded := name[i+1 : i+end]
+		i += end
+		if encoded == "" {
+			sb.WriteByte('&')
+			continue
+		}
+		data, err := base64.RawStdEncoding.DecodeString(strings.ReplaceAll(encoded, ",", "/"))
+		if err != nil {
+			sb.WriteString("&" + encoded + "-")
+			continue
+		}
+		units := make([]uint16, 0, len(data)/2)
+		for j := 0; j+1 < len(data); j += 2 {
+			units = append(units, uint16(data[j])<<8|uint16(data[j+1]))
+		}
+		sb.WriteString(string(utf16.Decode(units)))
+	}
+	return sb.String()
+}
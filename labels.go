@@ -0,0 +1,82 @@
+package main
+
+import "fmt"
+
+// classificationStats 汇总多标签分类的结果
+type classificationStats struct {
+	Total         int
+	Counts        []categoryCount
+	CoOccurrence  []labelPair
+	LowConfidence []lowConfidenceEmail
+}
+
+// labelPair 是同时出现在一封邮件上的两个分类
+type labelPair struct {
+	First  *emailCategory
+	Second *emailCategory
+	Count  int
+}
+
+// lowConfidenceEmail 是主要分类置信度低于设定值、需要人工复核的邮件
+type lowConfidenceEmail struct {
+	Email  EmailInfo
+	Labels []emailLabel
+}
+
+// maxLowConfidenceShown 是报告中最多列出的低置信度邮件数
+const maxLowConfidenceShown = 20
+
+// actionLabel 返回第一个需要处理的标签，没有时返回nil
+func actionLabel(labels []emailLabel) *emailCategory {
+	for _, label := range labels {
+		if label.Category.Action {
+			return label.Category
+		}
+	}
+	return nil
+}
+
+func formatLabels(labels []emailLabel) string {
+	text := ""
+	for i, label := range labels {
+		if i > 0 {
+			text += " / "
+		}
+		text += fmt.Sprintf("%s %.0f%%", label.Category.Name, label.Confidence*100)
+	}
+	return text
+}
+
+func (oa *OutlookEmailAnalyzer) printClassification(stats classificationStats) {
+	fmt.Printf("\n📋 6. 邮件分类统计 (一封邮件可属于多个分类，比例之和可能超过100%%):\n")
+	// 收到的邮件可能全部是自动邮件
+	if stats.Total == 0 {
+		fmt.Printf("   无\n")
+		return
+	}
+	for i, count := range stats.Counts {
+		fmt.Printf("   %c. %s: %d 封 (%.1f%%)\n", 'a'+i, count.Category.Name, count.Count, float64(count.Count)/float64(stats.Total)*100)
+	}
+
+	if len(stats.CoOccurrence) > 0 {
+		fmt.Printf("   同时属于多个分类:\n")
+		for _, pair := range stats.CoOccurrence {
+			fmt.Printf("     %s + %s: %d 封\n", pair.First.Name, pair.Second.Name, pair.Count)
+		}
+	}
+
+	if len(stats.LowConfidence) > 0 {
+		fmt.Printf("   低置信度邮件 (主要分类置信度低于 %.0f%%，建议人工复核，共 %d 封):\n", oa.minConfidence*100, len(stats.LowConfidence))
+		for i, item := range stats.LowConfidence {
+			if i == maxLowConfidenceShown {
+				fmt.Printf("     ... 另有 %d 封\n", len(stats.LowConfidence)-maxLowConfidenceShown)
+				break
+			}
+			sender := item.Email.SenderEmail
+			if sender == "" {
+				sender = item.Email.SenderName
+			}
+			fmt.Printf("     %d. %s | %s | %s\n", i+1, item.Email.Subject, sender, formatLabels(item.Labels))
+		}
+	}
+}
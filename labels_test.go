@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestClassifyEmails(t *testing.T) {
+	classifier, err := parseClassifier([]byte(testClassifierJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oa := NewOutlookEmailAnalyzer(&stubMailSource{})
+	oa.classifier = classifier
+	oa.minConfidence = 0.7
+
+	emails := []EmailInfo{
+		{Subject: "RE: 待办：请审批 预算 和 发票", Body: "approval needed, info"}, // 需要批准 0.80 + 财务 0.67 + 信息 0.67
+		{Subject: "三月报销", Body: "发票已附上"},                               // 财务 0.67
+		{Subject: "发票"}, // 其他 0.60，财务只命中一半
+		{Subject: "预算 通知", SenderEmail: "cfo@finance.example.com"}, // 财务 0.71 + 信息 0.67
+		{Subject: "周报"}, // 其他 0.67
+	}
+	stats := oa.classifyEmails(emails)
+
+	var counts []string
+	for _, count := range stats.Counts {
+		counts = append(counts, fmt.Sprintf("%s:%d", count.Category.Name, count.Count))
+	}
+	if stats.Total != 5 || strings.Join(counts, " ") != "财务:3 需要批准:1 信息:2 其他:2" {
+		t.Errorf("counts = %v (total %d)", counts, stats.Total)
+	}
+
+	// 按次数从多到少，次数相同时按分类顺序；每对中的分类也按分类顺序
+	var pairs []string
+	for _, pair := range stats.CoOccurrence {
+		pairs = append(pairs, fmt.Sprintf("%s+%s:%d", pair.First.Name, pair.Second.Name, pair.Count))
+	}
+	if strings.Join(pairs, " ") != "财务+信息:2 财务+需要批准:1 需要批准+信息:1" {
+		t.Errorf("co-occurrence = %v", pairs)
+	}
+
+	// 主要分类置信度从低到高
+	var low []string
+	for _, item := range stats.LowConfidence {
+		low = append(low, fmt.Sprintf("%s:%.2f", item.Email.Subject, item.Labels[0].Confidence))
+	}
+	if strings.Join(low, " ") != "发票:0.60 三月报销:0.67 周报:0.67" {
+		t.Errorf("low confidence = %v", low)
+	}
+}
+
+func TestClassifyEmailsDefaultConfidence(t *testing.T) {
+	// 默认规则和默认的 -min-confidence 下，命中一个关键词或没有命中的普通邮件都不需要复核
+	oa := NewOutlookEmailAnalyzer(&stubMailSource{})
+	stats := oa.classifyEmails([]EmailInfo{{Subject: "请审批采购单"}, {Subject: "请尽快回复"}, {Subject: "周报"}})
+	if len(stats.LowConfidence) != 0 {
+		t.Errorf("low confidence = %+v", stats.LowConfidence)
+	}
+}
+
+// captureStdout 返回 f 执行期间写到标准输出的内容
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		done <- string(data)
+	}()
+	f()
+	writer.Close()
+	return <-done
+}
+
+func TestPrintClassificationWithoutEmails(t *testing.T) {
+	// 收到的邮件全部是自动邮件时，分类统计没有邮件
+	oa := NewOutlookEmailAnalyzer(&stubMailSource{})
+	stats := oa.classifyEmails(nil)
+	output := captureStdout(t, func() { oa.printClassification(stats) })
+	if strings.Contains(output, "NaN") || !strings.Contains(output, "   无\n") {
+		t.Errorf("output = %q", output)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// MailSource 是邮件数据来源的抽象，分析逻辑只依赖此接口。
+// account 为用户输入的邮箱地址，"default" 表示默认账户。
+type MailSource interface {
+	// ReceivedEmails 返回收件箱及其所有子文件夹中在日期范围内收到的邮件
+	ReceivedEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error)
+	// SentEmails 返回日期范围内发送的邮件
+	SentEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error)
+	Close()
+}
+
+// 以下为可选能力，数据源实现时runAnalysis才会调用
+type securityChecker interface {
+	checkOutlookSecurity()
+}
+
+type accountLister interface {
+	listAvailableAccounts() error
+}
+
+// currentUserProvider 返回默认账户的显示名和地址，用于确定收件人中哪个是“我”
+type currentUserProvider interface {
+	currentUser() (string, string)
+}
+
+// undatedChecker 报告读取的邮件是否没有日期（如没有日期列的CSV）。这样的数据源按日期范围读取时返回所有邮件，
+// 不需要再读取分析期间之后的邮件
+type undatedChecker interface {
+	isUndated() bool
+}
+
+// sourceOptions 保存命令行中与数据源相关的参数
+type sourceOptions struct {
+	Kind       string
+	Path       string // 文件类数据源的路径
+	SentPath   string // 单独存放发送邮件的文件或目录（可选）
+	MboxFormat string
+	Server     string // 服务器类数据源的地址
+	User       string
+	Password   string
+	Token      string // OAuth访问令牌
+	Tenant     string
+	ClientID   string
+}
+
+func openMailSource(opts sourceOptions) (MailSource, error) {
+	switch opts.Kind {
+	case "outlook":
+		return NewOutlookSource()
+	case "mbox":
+		return NewMboxSource(opts.Path, opts.SentPath, opts.MboxFormat)
+	case "maildir":
+		return NewMaildirSource(opts.Path, opts.SentPath)
+	case "eml":
+		return NewEmlSource(opts.Path, opts.SentPath)
+	case "msg":
+		return NewMsgSource(opts.Path, opts.SentPath)
+	case "pst":
+		return NewPstSource(opts.Path)
+	case "csv":
+		return NewCsvSource(opts.Path, opts.SentPath)
+	case "imap":
+		return NewImapSource(opts.Server, opts.User, sourcePassword(opts))
+	case "graph":
+		token := opts.Token
+		if token == "" {
+			token = os.Getenv("GRAPH_TOKEN")
+		}
+		return NewGraphSource(opts.Server, token, opts.Tenant, opts.ClientID)
+	case "ews":
+		return NewEwsSource(opts.Server, opts.User, sourcePassword(opts))
+	default:
+		return nil, fmt.Errorf("不支持的数据源: %s", opts.Kind)
+	}
+}
+
+// sourcePassword 依次使用 -password 参数、MAIL_PASSWORD 环境变量，都没有时提示输入
+func sourcePassword(opts sourceOptions) string {
+	if opts.Password != "" {
+		return opts.Password
+	}
+	if password := os.Getenv("MAIL_PASSWORD"); password != "" {
+		return password
+	}
+	fmt.Printf("请输入 %s 的密码: ", opts.User)
+	password, _ := stdinReader.ReadString('\n')
+	return strings.TrimRight(password, "\r\n")
+}
+
+// setFolder 记录邮件所在的文件夹，用于在报告中指出邮件的位置
+func setFolder(emails []EmailInfo, folder string) {
+	for i := range emails {
+		emails[i].Folder = folder
+	}
+}
+
+// inDateRange 判断时间是否落在[startDate, endDate+1天]范围内，与Outlook过滤条件保持一致
+func inDateRange(t, startDate, endDate time.Time) bool {
+	return !t.Before(startDate) && !t.After(endDate.AddDate(0, 0, 1))
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 与Outlook数据源保持一致，只保留正文前500个字符用于分类
+const bodyPreviewLen = 500
+
+var headerDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// parseMessage 解析RFC 5322格式的原始邮件，供mbox等文件数据源使用。
+// 同时返回邮件头，便于数据源读取各自特有的头字段
+func parseMessage(r io.Reader) (EmailInfo, mail.Header, error) {
+	var emailInfo EmailInfo
+
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return emailInfo, nil, err
+	}
+
+	// 未编码的8位邮件头按正文声明的字符集解码
+	_, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	charset := params["charset"]
+	for key, values := range msg.Header {
+		for i := range values {
+			values[i] = decodeRawHeader(values[i], charset)
+		}
+		msg.Header[key] = values
+	}
+
+	emailInfo.Subject = decodeHeader(msg.Header.Get("Subject"))
+
+	if from, err := parseAddress(msg.Header.Get("From")); err == nil {
+		emailInfo.SenderEmail = from.Address
+		emailInfo.SenderName = from.Name
+	}
+
+	emailInfo.Recipients = append(emailInfo.Recipients, parseAddressList(msg.Header.Get("To"), RecipientTo)...)
+	emailInfo.Recipients = append(emailInfo.Recipients, parseAddressList(msg.Header.Get("Cc"), RecipientCC)...)
+	emailInfo.Recipients = append(emailInfo.Recipients, parseAddressList(msg.Header.Get("Bcc"), RecipientBCC)...)
+	emailInfo.To = recipientNames(emailInfo.Recipients, RecipientTo)
+	emailInfo.CC = recipientNames(emailInfo.Recipients, RecipientCC)
+
+	if date, err := msg.Header.Date(); err == nil {
+		emailInfo.SentTime = date
+	}
+	emailInfo.ReceivedTime = receivedTime(msg.Header)
+	if emailInfo.ReceivedTime.IsZero() {
+		emailInfo.ReceivedTime = emailInfo.SentTime
+	}
+
+	emailInfo.IsRead = readStatus(msg.Header)
+	applyThreadHeaders(&emailInfo, msg.Header)
+	applyListHeaders(&emailInfo, msg.Header)
+	applyAutoHeaders(&emailInfo, msg.Header)
+
+	// 退信（multipart/report）需要读取两遍：一遍取投递失败的地址，一遍取正文
+	var bodyReader io.Reader = msg.Body
+	if emailInfo.ReportType != "" {
+		raw, _ := io.ReadAll(io.LimitReader(msg.Body, maxPartSize))
+		if recipients := deliveryStatusRecipients(params["boundary"], raw); len(recipients) > 0 {
+			emailInfo.BouncedRecipients = recipients
+		}
+		bodyReader = bytes.NewReader(raw)
+	}
+	body, err := messageText(textproto.MIMEHeader(msg.Header), bodyReader)
+	if err == nil {
+		emailInfo.Body = truncateBody(body)
+	}
+
+	return emailInfo, msg.Header, nil
+}
+
+// applyThreadHeaders 读取Message-ID、In-Reply-To、References以及Outlook写入的Thread-Index
+func applyThreadHeaders(emailInfo *EmailInfo, header mail.Header) {
+	emailInfo.MessageID = firstMessageID(header.Get("Message-Id"))
+	emailInfo.InReplyTo = firstMessageID(header.Get("In-Reply-To"))
+	emailInfo.References = parseMessageIDs(header.Get("References"))
+	emailInfo.ConversationIndex = decodeThreadIndex(header.Get("Thread-Index"))
+	emailInfo.ConversationID = conversationIDFromIndex(emailInfo.ConversationIndex)
+}
+
+// applyListHeaders 读取邮件列表和自动发送相关的头：List-Id、List-Unsubscribe、Precedence、Auto-Submitted
+func applyListHeaders(emailInfo *EmailInfo, header mail.Header) {
+	emailInfo.ListID = decodeHeader(header.Get("List-Id"))
+	emailInfo.ListUnsubscribe = header.Get("List-Unsubscribe")
+	emailInfo.Precedence = strings.ToLower(strings.TrimSpace(header.Get("Precedence")))
+	emailInfo.AutoSubmitted = strings.ToLower(strings.TrimSpace(header.Get("Auto-Submitted")))
+}
+
+// applyAutoHeaders 读取判断自动回复和退信所需的头：X-Auto-Response-Suppress、X-Failed-Recipients，
+// 以及multipart/report的report-type
+func applyAutoHeaders(emailInfo *EmailInfo, header mail.Header) {
+	emailInfo.AutoResponseSuppress = strings.TrimSpace(header.Get("X-Auto-Response-Suppress"))
+	if mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil && mediaType == "multipart/report" {
+		emailInfo.ReportType = strings.ToLower(params["report-type"])
+	}
+	// Exim等在退信头中列出投递失败的地址
+	for _, address := range strings.Split(header.Get("X-Failed-Recipients"), ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			emailInfo.BouncedRecipients = append(emailInfo.BouncedRecipients, address)
+		}
+	}
+}
+
+// parseTransportHeaders 解析Outlook保存的原始邮件头（PR_TRANSPORT_MESSAGE_HEADERS），无法解析时返回空
+func parseTransportHeaders(raw string) mail.Header {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return mail.Header{}
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(raw + "\r\n\r\n"))
+	if err != nil {
+		return mail.Header{}
+	}
+	return msg.Header
+}
+
+func decodeHeader(value string) string {
+	decoded, err := headerDecoder.DecodeHeader(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(decoded)
+}
+
+func parseAddress(value string) (*mail.Address, error) {
+	parser := mail.AddressParser{WordDecoder: headerDecoder}
+	return parser.Parse(value)
+}
+
+// receivedTime 取最上面一条Received头中分号后的时间，即邮件到达本地邮箱的时间
+func receivedTime(header mail.Header) time.Time {
+	for _, received := range header["Received"] {
+		idx := strings.LastIndex(received, ";")
+		if idx < 0 {
+			continue
+		}
+		if t, err := mail.ParseDate(strings.TrimSpace(received[idx+1:])); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// readStatus 根据mbox常见的状态头判断邮件是否已读
+func readStatus(header mail.Header) bool {
+	// Thunderbird: X-Mozilla-Status 为十六进制标志位，0x0001 表示已读
+	if status := header.Get("X-Mozilla-Status"); status != "" {
+		if flags, err := strconv.ParseUint(strings.TrimSpace(status), 16, 32); err == nil {
+			return flags&0x0001 != 0
+		}
+	}
+	// mutt/pine: Status: RO 中的 R 表示已读
+	if status := header.Get("Status"); status != "" {
+		return strings.Contains(status, "R")
+	}
+	// Google Takeout: X-Gmail-Labels 含 Unread 表示未读
+	if labels := header.Get("X-Gmail-Labels"); labels != "" {
+		return !hasGmailLabel(labels, "Unread")
+	}
+	return false
+}
+
+func hasGmailLabel(labels, label string) bool {
+	for _, l := range strings.Split(labels, ",") {
+		if strings.EqualFold(strings.TrimSpace(l), label) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncateBody(body string) string {
+	body = strings.TrimSpace(body)
+	runes := []rune(body)
+	if len(runes) > bodyPreviewLen {
+		return string(runes[:bodyPreviewLen])
+	}
+	return body
+}
+
+// isOwnAddress 判断地址是否属于当前分析的账户
+func isOwnAddress(address, account string) bool {
+	return account != "" && account != "default" && strings.EqualFold(strings.TrimSpace(address), account)
+}
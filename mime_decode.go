@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+// 单个正文部分最多读取1MB，足够提取预览文本
+const maxPartSize = 1 << 20
+
+// 未声明字符集的8位邮件头多数来自中文客户端，按GB18030解码
+const fallbackCharset = "gb18030"
+
+var (
+	htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlScriptPattern  = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)>`)
+	htmlBreakPattern   = regexp.MustCompile(`(?i)<(br|/p|/div|/tr|/li|/h[1-6])\b[^>]*>`)
+	htmlTagPattern     = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlMetaCharset    = regexp.MustCompile(`(?i)<meta[^>]+charset=["']?([\w-]+)`)
+	spacePattern       = regexp.MustCompile(`[ \t\f\r\x{00a0}]+`)
+	blankLinePattern   = regexp.MustCompile(`\n\s*\n\s*`)
+)
+
+// charsetReader 将指定字符集的内容转换为UTF-8。GB2312/GBK统一按其超集GB18030解码，
+// 因为标注为GB2312的邮件经常包含GBK字符
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "gb2312", "gbk", "gb18030", "x-gbk", "cp936", "euc-cn":
+		return transform.NewReader(input, simplifiedchinese.GB18030.NewDecoder()), nil
+	case "hz-gb-2312", "hz":
+		// HZ是用 ~{ … ~} 转义的7位编码，不能按GB18030解码
+		return transform.NewReader(input, simplifiedchinese.HZGB2312.NewDecoder()), nil
+	case "big5", "big5-hkscs", "x-big5", "cp950":
+		return transform.NewReader(input, traditionalchinese.Big5.NewDecoder()), nil
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("不支持的字符集: %s", charset)
+	}
+	return transform.NewReader(input, enc.NewDecoder()), nil
+}
+
+func decodeCharset(data []byte, charset string) string {
+	reader, err := charsetReader(charset, strings.NewReader(string(data)))
+	if err != nil {
+		return string(data)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+// decodeRawHeader 处理未经RFC 2047编码、直接写入8位字节的邮件头
+func decodeRawHeader(value, charset string) string {
+	if utf8.ValidString(value) {
+		return value
+	}
+	if charset == "" {
+		charset = fallbackCharset
+	}
+	return decodeCharset([]byte(value), charset)
+}
+
+// messageText 递归查找邮件正文：优先使用text/plain，没有时退回去掉标签的text/html
+func messageText(header textproto.MIMEHeader, body io.Reader) (string, error) {
+	plain, htmlText, err := findTextParts(header, body, 0)
+	if strings.TrimSpace(plain) != "" {
+		return plain, nil
+	}
+	if htmlText != "" {
+		return htmlToText(htmlText), nil
+	}
+	return "", err
+}
+
+func findTextParts(header textproto.MIMEHeader, body io.Reader, depth int) (plain, htmlText string, err error) {
+	if depth > 10 {
+		return "", "", nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// RFC 2045: 缺省为 text/plain; charset=us-ascii
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return plain, htmlText, nil
+			}
+			if err != nil {
+				return plain, htmlText, err
+			}
+			partPlain, partHTML, _ := findTextParts(part.Header, part, depth+1)
+			if plain == "" {
+				plain = partPlain
+			}
+			if htmlText == "" {
+				htmlText = partHTML
+			}
+			if plain != "" {
+				return plain, htmlText, nil
+			}
+		}
+	}
+
+	if isAttachment(header) {
+		return "", "", nil
+	}
+
+	switch mediaType {
+	case "text/plain":
+		plain, err = decodePartBody(header, body, params["charset"], false)
+	case "text/html":
+		htmlText, err = decodePartBody(header, body, params["charset"], true)
+	}
+	return plain, htmlText, err
+}
+
+func isAttachment(header textproto.MIMEHeader) bool {
+	disposition, _, err := mime.ParseMediaType(header.Get("Content-Disposition"))
+	return err == nil && disposition == "attachment"
+}
+
+// decodePartBody 依次处理传输编码（quoted-printable/base64）和字符集
+func decodePartBody(header textproto.MIMEHeader, body io.Reader, charset string, isHTML bool) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxPartSize))
+	if err != nil && len(data) == 0 {
+		return "", err
+	}
+
+	if charset == "" && isHTML {
+		if match := htmlMetaCharset.FindSubmatch(data); match != nil {
+			charset = string(match[1])
+		}
+	}
+	if charset == "" && !utf8.Valid(data) {
+		charset = fallbackCharset
+	}
+	return decodeCharset(data, charset), nil
+}
+
+func htmlToText(htmlText string) string {
+	text := htmlCommentPattern.ReplaceAllString(htmlText, "")
+	text = htmlScriptPattern.ReplaceAllString(text, "")
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = spacePattern.ReplaceAllString(text, " ")
+	text = blankLinePattern.ReplaceAllString(text, "\n")
+	return strings.TrimSpace(text)
+}
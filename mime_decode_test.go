@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeCharset(t *testing.T) {
+	tests := []struct {
+		charset string
+		data    []byte
+		want    string
+	}{
+		{"utf-8", []byte("会议通知"), "会议通知"},
+		{"gb2312", []byte{0xbb, 0xe1, 0xd2, 0xe9}, "会议"},
+		{"GBK", []byte{0xbb, 0xe1, 0xd2, 0xe9}, "会议"},
+		{"hz-gb-2312", []byte("~{;aRi~}ok"), "会议ok"},
+		{"big5", []byte{0xb7, 0x7c, 0xc4, 0xb3}, "會議"},
+		{"iso-8859-1", []byte{0x63, 0x61, 0x66, 0xe9}, "café"},
+		{"x-unknown", []byte("raw"), "raw"},
+	}
+	for _, tt := range tests {
+		if got := decodeCharset(tt.data, tt.charset); got != tt.want {
+			t.Errorf("decodeCharset(%q) = %q, want %q", tt.charset, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeRawHeaderFallsBackToGB18030(t *testing.T) {
+	raw := string([]byte{0xbb, 0xe1, 0xd2, 0xe9})
+	if got := decodeRawHeader(raw, ""); got != "会议" {
+		t.Errorf("decodeRawHeader = %q, want 会议", got)
+	}
+	if got := decodeRawHeader("plain", "big5"); got != "plain" {
+		t.Errorf("decodeRawHeader kept valid UTF-8 as %q", got)
+	}
+}
+
+func TestParseMessageMIME(t *testing.T) {
+	raw := "From: =?gb2312?B?1cXI/Q==?= <zhangsan@example.com>\r\n" +
+		"To: Li Si <lisi@example.com>\r\n" +
+		"Subject: =?utf-8?Q?=E4=BC=9A=E8=AE=AE?=\r\n" +
+		"Date: Thu, 06 Mar 2025 10:00:00 +0800\r\n" +
+		"Content-Type: multipart/alternative; boundary=\"b1\"\r\n" +
+		"\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"\r\n" +
+		"<p>html body</p>\r\n" +
+		"--b1\r\n" +
+		"Content-Type: text/plain; charset=gb2312\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"u+HS6Q==\r\n" +
+		"--b1--\r\n"
+
+	info, _, err := parseMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parseMessage: %v", err)
+	}
+	if info.Subject != "会议" {
+		t.Errorf("Subject = %q", info.Subject)
+	}
+	if info.SenderName != "张三" || info.SenderEmail != "zhangsan@example.com" {
+		t.Errorf("sender = %q <%s>", info.SenderName, info.SenderEmail)
+	}
+	if info.Body != "会议" {
+		t.Errorf("Body = %q, want the text/plain part", info.Body)
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	in := "<html><head><style>p{}</style></head><body><!-- c --><p>Hello&nbsp;<b>world</b></p><br>Line&amp;2</body></html>"
+	if got := htmlToText(in); got != "Hello world\nLine&2" {
+		t.Errorf("htmlToText = %q", got)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// NTLMv2 认证（MS-NLMP），用于本地部署的Exchange。HTTP上只做身份验证，不需要签名和加密
+
+const (
+	ntlmNegotiateUnicode     = 0x00000001
+	ntlmRequestTarget        = 0x00000004
+	ntlmNegotiateNTLM        = 0x00000200
+	ntlmNegotiateAlwaysSign  = 0x00008000
+	ntlmNegotiateExtendedSec = 0x00080000
+	ntlmNegotiateTargetInfo  = 0x00800000
+	ntlmNegotiate128         = 0x20000000
+	ntlmNegotiate56          = 0x80000000
+
+	ntlmAvEOL       = 0
+	ntlmAvTimestamp = 7
+)
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+const ntlmNegotiateFlags = ntlmNegotiateUnicode | ntlmRequestTarget | ntlmNegotiateNTLM | ntlmNegotiateAlwaysSign |
+	ntlmNegotiateExtendedSec | ntlmNegotiateTargetInfo | ntlmNegotiate128 | ntlmNegotiate56
+
+// ntlmNegotiateMessage 生成第一步的NEGOTIATE_MESSAGE，不携带域和工作站名
+func ntlmNegotiateMessage() []byte {
+	msg := make([]byte, 32)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], ntlmNegotiateFlags)
+	return msg
+}
+
+// ntlmAuthenticateMessage 根据服务器的CHALLENGE_MESSAGE计算NTLMv2响应并生成AUTHENTICATE_MESSAGE。
+// user 可以是 "域\用户名" 或 "用户名@域"（UPN形式时域留空）
+func ntlmAuthenticateMessage(challenge []byte, user, password string) ([]byte, error) {
+	if len(challenge) < 32 || !bytes.Equal(challenge[:8], ntlmSignature) || binary.LittleEndian.Uint32(challenge[8:]) != 2 {
+		return nil, fmt.Errorf("无效的NTLM质询消息")
+	}
+	flags := binary.LittleEndian.Uint32(challenge[20:])
+	serverChallenge := challenge[24:32]
+	var targetInfo []byte
+	if len(challenge) >= 48 {
+		length := int(binary.LittleEndian.Uint16(challenge[40:]))
+		offset := int(binary.LittleEndian.Uint32(challenge[44:]))
+		if offset+length <= len(challenge) {
+			targetInfo = challenge[offset : offset+length]
+		}
+	}
+
+	domain := ""
+	if idx := strings.Index(user, `\`); idx >= 0 {
+		domain, user = user[:idx], user[idx+1:]
+	}
+
+	responseKey := ntowfv2(user, domain, password)
+
+	clientChallenge := make([]byte, 8)
+	if _, err := rand.Read(clientChallenge); err != nil {
+		return nil, err
+	}
+	timestamp, hasTimestamp := ntlmTargetTimestamp(targetInfo)
+	if !hasTimestamp {
+		timestamp = make([]byte, 8)
+		binary.LittleEndian.PutUint64(timestamp, timeToFiletime(time.Now()))
+	}
+
+	ntResponse := ntlmv2Response(responseKey, serverChallenge, clientChallenge, timestamp, targetInfo)
+	// 服务器提供时间戳时，按规范LMv2响应应为全零
+	lmResponse := make([]byte, 24)
+	if !hasTimestamp {
+		lmResponse = append(hmacMD5(responseKey, serverChallenge, clientChallenge), clientChallenge...)
+	}
+
+	fields := [][]byte{lmResponse, ntResponse, utf16LE(domain), utf16LE(user), nil, nil}
+	const headerSize = 64
+	msg := make([]byte, headerSize)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+	offset := headerSize
+	for i, field := range fields {
+		pos := 12 + i*8
+		binary.LittleEndian.PutUint16(msg[pos:], uint16(len(field)))
+		binary.LittleEndian.PutUint16(msg[pos+2:], uint16(len(field)))
+		binary.LittleEndian.PutUint32(msg[pos+4:], uint32(offset))
+		offset += len(field)
+	}
+	binary.LittleEndian.PutUint32(msg[60:], flags&ntlmNegotiateFlags)
+	for _, field := range fields {
+		msg = append(msg, field...)
+	}
+	return msg, nil
+}
+
+// ntowfv2 = HMAC_MD5(MD4(UNICODE(密码)), UNICODE(大写(用户名) + 域))
+func ntowfv2(user, domain, password string) []byte {
+	ntHash := md4Sum(utf16LE(password))
+	return hmacMD5(ntHash[:], utf16LE(strings.ToUpper(user)+domain))
+}
+
+// ntlmv2Response 计算NTLMv2响应：NTProofStr（16字节）后接客户端blob
+func ntlmv2Response(responseKey, serverChallenge, clientChallenge, timestamp, targetInfo []byte) []byte {
+	var blob bytes.Buffer
+	blob.Write([]byte{1, 1, 0, 0, 0, 0, 0, 0})
+	blob.Write(timestamp)
+	blob.Write(clientChallenge)
+	blob.Write([]byte{0, 0, 0, 0})
+	blob.Write(targetInfo)
+	blob.Write([]byte{0, 0, 0, 0})
+
+	ntProof := hmacMD5(responseKey, serverChallenge, blob.Bytes())
+	return append(ntProof, blob.Bytes()...)
+}
+
+// ntlmTargetTimestamp 从AV_PAIR列表中读取MsvAvTimestamp
+func ntlmTargetTimestamp(targetInfo []byte) ([]byte, bool) {
+	for len(targetInfo) >= 4 {
+		id := binary.LittleEndian.Uint16(targetInfo)
+		length := int(binary.LittleEndian.Uint16(targetInfo[2:]))
+		if id == ntlmAvEOL || 4+length > len(targetInfo) {
+			break
+		}
+		if id == ntlmAvTimestamp && length == 8 {
+			return targetInfo[4:12], true
+		}
+		targetInfo = targetInfo[4+length:]
+	}
+	return nil, false
+}
+
+func timeToFiletime(t time.Time) uint64 {
+	// FILETIME 为自1601-01-01起的100纳秒数
+	return uint64(t.UnixNano()/100) + 116444736000000000
+}
+
+func utf16LE(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	buf := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(buf[i*2:], u)
+	}
+	return buf
+}
+
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	mac := hmac.New(md5.New, key)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// md4Sum 实现RFC 1320 MD4摘要，NTLM的密码哈希依赖它，标准库中没有提供
+func md4Sum(data []byte) [16]byte {
+	length := uint64(len(data)) * 8
+	msg := append([]byte{}, data...)
+	msg = append(msg, 0x80)
+	for len(msg)%64 != 56 {
+		msg = append(msg, 0)
+	}
+	var lenBytes [8]byte
+	binary.LittleEndian.PutUint64(lenBytes[:], length)
+	msg = append(msg, lenBytes[:]...)
+
+	a, b, c, d := uint32(0x67452301), uint32(0xefcdab89), uint32(0x98badcfe), uint32(0x10325476)
+	var x [16]uint32
+	for chunk := 0; chunk < len(msg); chunk += 64 {
+		for i := range x {
+			x[i] = binary.LittleEndian.Uint32(msg[chunk+i*4:])
+		}
+		aa, bb, cc, dd := a, b, c, d
+
+		f := func(x, y, z uint32) uint32 { return x&y | ^x&z }
+		g := func(x, y, z uint32) uint32 { return x&y | x&z | y&z }
+		h := func(x, y, z uint32) uint32 { return x ^ y ^ z }
+
+		for _, i := range []int{0, 4, 8, 12} {
+			a = bits.RotateLeft32(a+f(b, c, d)+x[i], 3)
+			d = bits.RotateLeft32(d+f(a, b, c)+x[i+1], 7)
+			c = bits.RotateLeft32(c+f(d, a, b)+x[i+2], 11)
+			b = bits.RotateLeft32(b+f(c, d, a)+x[i+3], 19)
+		}
+		for _, i := range []int{0, 1, 2, 3} {
+			a = bits.RotateLeft32(a+g(b, c, d)+x[i]+0x5a827999, 3)
+			d = bits.RotateLeft32(d+g(a, b, c)+x[i+4]+0x5a827999, 5)
+			c = bits.RotateLeft32(c+g(d, a, b)+x[i+8]+0x5a827999, 9)
+			b = bits.RotateLeft32(b+g(c, d, a)+x[i+12]+0x5a827999, 13)
+		}
+		for _, i := range []int{0, 2, 1, 3} {
+			a = bits.RotateLeft32(a+h(b, c, d)+x[i]+0x6ed9eba1, 3)
+			d = bits.RotateLeft32(d+h(a, b, c)+x[i+8]+0x6ed9eba1, 9)
+			c = bits.RotateLeft32(c+h(d, a, b)+x[i+4]+0x6ed9eba1, 11)
+			b = bits.RotateLeft32(b+h(c, d, a)+x[i+12]+0x6ed9eba1, 15)
+		}
+
+		a += aa
+		b += bb
+		c += cc
+		d += dd
+	}
+
+	var sum [16]byte
+	binary.LittleEndian.PutUint32(sum[0:], a)
+	binary.LittleEndian.PutUint32(sum[4:], b)
+	binary.LittleEndian.PutUint32(sum[8:], c)
+	binary.LittleEndian.PutUint32(sum[12:], d)
+	return sum
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestMD4Vectors(t *testing.T) {
+	// RFC 1320 附录A.5
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "31d6cfe0d16ae931b73c59d7e0c089c0"},
+		{"a", "bde52cb31de33e46245e05fbdbd6fb24"},
+		{"abc", "a448017aaf21d8525fc10ae87aa6729d"},
+		{"message digest", "d9130a8164549fe818874806e1c7014b"},
+		{"abcdefghijklmnopqrstuvwxyz", "d79e1c308aa5bbcdeea8ed63df412da9"},
+		{strings.Repeat("1234567890", 8), "e33b4ddc9c38f2199c3e7b164fcc0536"},
+	}
+	for _, tt := range tests {
+		sum := md4Sum([]byte(tt.input))
+		if got := hex.EncodeToString(sum[:]); got != tt.want {
+			t.Errorf("md4(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+// MS-NLMP 4.2.4 的NTLMv2示例：用户 User，域 Domain，密码 Password
+var (
+	ntlmTestServerChallenge = mustHex("0123456789abcdef")
+	ntlmTestClientChallenge = mustHex("aaaaaaaaaaaaaaaa")
+	ntlmTestTargetInfo      = append(append(append([]byte{2, 0, 12, 0}, utf16LE("Domain")...),
+		append([]byte{1, 0, 12, 0}, utf16LE("Server")...)...), 0, 0, 0, 0)
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestNTLMv2SpecVectors(t *testing.T) {
+	ntHash := md4Sum(utf16LE("Password"))
+	if got := hex.EncodeToString(ntHash[:]); got != "a4f49c406510bdcab6824ee7c30fd852" {
+		t.Errorf("NT hash = %s", got)
+	}
+
+	responseKey := ntowfv2("User", "Domain", "Password")
+	if got := hex.EncodeToString(responseKey); got != "0c868a403bfd7a93a3001ef22ef02e3f" {
+		t.Errorf("NTOWFv2 = %s", got)
+	}
+
+	response := ntlmv2Response(responseKey, ntlmTestServerChallenge, ntlmTestClientChallenge, make([]byte, 8), ntlmTestTargetInfo)
+	if got := hex.EncodeToString(response[:16]); got != "68cd0ab851e51c96aabc927bebef6a1c" {
+		t.Errorf("NTProofStr = %s", got)
+	}
+	blob := response[16:]
+	if !bytes.Equal(blob[:8], []byte{1, 1, 0, 0, 0, 0, 0, 0}) || !bytes.Equal(blob[16:24], ntlmTestClientChallenge) {
+		t.Errorf("blob header = % x", blob[:28])
+	}
+	if !bytes.Equal(blob[28:len(blob)-4], ntlmTestTargetInfo) {
+		t.Errorf("blob does not carry the target info")
+	}
+
+	lm := append(hmacMD5(responseKey, ntlmTestServerChallenge, ntlmTestClientChallenge), ntlmTestClientChallenge...)
+	if got := hex.EncodeToString(lm); got != "86c35097ac9cec102554764a57cccc19aaaaaaaaaaaaaaaa" {
+		t.Errorf("LMv2 = %s", got)
+	}
+}
+
+// buildNTLMChallenge 构造服务器的CHALLENGE_MESSAGE，目标名留空，targetInfo放在48字节头之后
+func buildNTLMChallenge(flags uint32, targetInfo []byte) []byte {
+	msg := make([]byte, 48)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], 2)
+	binary.LittleEndian.PutUint32(msg[16:], 48)
+	binary.LittleEndian.PutUint32(msg[20:], flags)
+	copy(msg[24:], ntlmTestServerChallenge)
+	binary.LittleEndian.PutUint16(msg[40:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint16(msg[42:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint32(msg[44:], 48)
+	return append(msg, targetInfo...)
+}
+
+// ntlmField 按AUTHENTICATE_MESSAGE头中第i个字段的长度和偏移取出内容
+func ntlmField(t *testing.T, msg []byte, i int) []byte {
+	t.Helper()
+	pos := 12 + i*8
+	length := int(binary.LittleEndian.Uint16(msg[pos:]))
+	maxLength := int(binary.LittleEndian.Uint16(msg[pos+2:]))
+	offset := int(binary.LittleEndian.Uint32(msg[pos+4:]))
+	if length != maxLength || offset < 64 || offset+length > len(msg) {
+		t.Fatalf("field %d: len=%d max=%d offset=%d in %d-byte message", i, length, maxLength, offset, len(msg))
+	}
+	return msg[offset : offset+length]
+}
+
+func TestNTLMAuthenticateMessageLayout(t *testing.T) {
+	timestamp := mustHex("0090d336b734c301")
+	targetInfo := append(append([]byte{}, ntlmTestTargetInfo[:len(ntlmTestTargetInfo)-4]...), 7, 0, 8, 0)
+	targetInfo = append(append(targetInfo, timestamp...), 0, 0, 0, 0)
+
+	tests := []struct {
+		name         string
+		targetInfo   []byte
+		wantZeroLM   bool
+		wantBlobTime []byte
+	}{
+		{"server timestamp", targetInfo, true, timestamp},
+		{"no timestamp", ntlmTestTargetInfo, false, nil},
+	}
+	for _, tt := range tests {
+		challenge := buildNTLMChallenge(ntlmNegotiateFlags|0x00010000, tt.targetInfo)
+		msg, err := ntlmAuthenticateMessage(challenge, `Domain\User`, "Password")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !bytes.Equal(msg[:8], ntlmSignature) || binary.LittleEndian.Uint32(msg[8:]) != 3 {
+			t.Fatalf("%s: header = % x", tt.name, msg[:12])
+		}
+		if flags := binary.LittleEndian.Uint32(msg[60:]); flags != ntlmNegotiateFlags {
+			t.Errorf("%s: flags = %#x, want the negotiated subset %#x", tt.name, flags, ntlmNegotiateFlags)
+		}
+
+		lm, nt := ntlmField(t, msg, 0), ntlmField(t, msg, 1)
+		if got := ntlmField(t, msg, 2); !bytes.Equal(got, utf16LE("Domain")) {
+			t.Errorf("%s: domain = % x", tt.name, got)
+		}
+		if got := ntlmField(t, msg, 3); !bytes.Equal(got, utf16LE("User")) {
+			t.Errorf("%s: user = % x", tt.name, got)
+		}
+		if len(ntlmField(t, msg, 4)) != 0 || len(ntlmField(t, msg, 5)) != 0 {
+			t.Errorf("%s: workstation and session key should be empty", tt.name)
+		}
+
+		// 客户端质询是随机的，从blob中取出后按规范重新计算NTProofStr
+		blob := nt[16:]
+		clientChallenge := blob[16:24]
+		if tt.wantBlobTime != nil && !bytes.Equal(blob[8:16], tt.wantBlobTime) {
+			t.Errorf("%s: blob timestamp = % x, want the server's", tt.name, blob[8:16])
+		}
+		responseKey := ntowfv2("User", "Domain", "Password")
+		want := ntlmv2Response(responseKey, ntlmTestServerChallenge, clientChallenge, blob[8:16], tt.targetInfo)
+		if !bytes.Equal(nt, want) {
+			t.Errorf("%s: NT response does not match NTLMv2 recomputation", tt.name)
+		}
+
+		if len(lm) != 24 {
+			t.Fatalf("%s: LM response is %d bytes", tt.name, len(lm))
+		}
+		if tt.wantZeroLM {
+			if !bytes.Equal(lm, make([]byte, 24)) {
+				t.Errorf("%s: LM response should be zero when the server sends a timestamp", tt.name)
+			}
+		} else if wantLM := append(hmacMD5(responseKey, ntlmTestServerChallenge, clientChallenge), clientChallenge...); !bytes.Equal(lm, wantLM) {
+			t.Errorf("%s: LMv2 response = % x", tt.name, lm)
+		}
+	}
+}
+
+func TestNTLMAuthenticateRejectsBadChallenge(t *testing.T) {
+	for _, challenge := range [][]byte{nil, []byte("NTLMSSP\x00"), ntlmNegotiateMessage()} {
+		if _, err := ntlmAuthenticateMessage(challenge, "user", "pw"); err == nil {
+			t.Errorf("challenge % x accepted", challenge)
+		}
+	}
+}
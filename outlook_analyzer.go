@@ -2,22 +2,35 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
-	"log"
 	"os"
-	"regexp"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
-
-	"github.com/go-ole/go-ole"
-	"github.com/go-ole/go-ole/oleutil"
+	// 内置时区数据库，Windows上没有系统时区数据也能使用 -tz
+	_ "time/tzdata"
 )
 
 type OutlookEmailAnalyzer struct {
-	outlook   *ole.IDispatch
-	namespace *ole.IDispatch
+	source MailSource
+	// 划分日期边界（如“当天回复”）使用的时区
+	location *time.Location
+	// 计算工作时间内回复时长的日历，以及SLA目标（工作时间）
+	calendar  *workCalendar
+	slaTarget time.Duration
+	// 发出的请求超过多少个工作日没有回复时列为“等待对方回复”
+	waitDays float64
+	// 邮件分类规则，以及低于多少置信度时列入人工复核
+	classifier    mailClassifier
+	minConfidence float64
+	// Exchange旧式DN到SMTP地址的转换
+	resolver *addressResolver
+	// 把同一个人的不同地址和显示名合并为一个联系人，按联系人统计
+	contacts *contactResolver
+	// 收发时间热力图使用的时区，以及导出文件（.csv或.json，为空时不导出）
+	heatmapLocation *time.Location
+	heatmapPath     string
 }
 
 type EmailInfo struct {
@@ -30,120 +43,55 @@ type EmailInfo struct {
 	Body         string
 	To           string
 	CC           string
+	Recipients   []Recipient // 结构化的收件人列表（含密送），To/CC只是显示名
+	Folder       string      // 所在文件夹，仅收到的邮件
+	// 会话信息，用于判断发送的邮件回复的是哪一封
+	MessageID         string // 不含尖括号
+	InReplyTo         string
+	References        []string
+	ConversationID    string // Outlook会话ID（十六进制）
+	ConversationIndex []byte // Outlook会话索引（PR_CONVERSATION_INDEX / Thread-Index头）
+	// 群发邮件相关的邮件头，用于识别邮件列表和通知
+	ListID          string
+	ListUnsubscribe string
+	Precedence      string
+	AutoSubmitted   string
+	// 自动回复和退信相关的信息
+	MessageClass         string   // Outlook邮件类型（PR_MESSAGE_CLASS），如 IPM.Note、REPORT.IPM.Note.NDR
+	AutoResponseSuppress string   // X-Auto-Response-Suppress头
+	ReportType           string   // multipart/report的report-type，如 delivery-status
+	BouncedRecipients    []string // 退信中投递失败的地址
 }
 
+// 所有交互输入共用同一个reader，避免多次创建时缓冲区吞掉后续输入（如通过管道输入时）
+var stdinReader = bufio.NewReader(os.Stdin)
+
 type SenderCount struct {
 	Email string
 	Count int
 }
 
-func NewOutlookEmailAnalyzer() (*OutlookEmailAnalyzer, error) {
-	// 初始化COM，使用单线程模式来减少权限需求
-	err := ole.CoInitializeEx(0, ole.COINIT_APARTMENTTHREADED)
-	if err != nil {
-		fmt.Printf("警告: COM初始化失败: %v\n", err)
-		fmt.Println("尝试使用标准初始化...")
-		ole.CoInitialize(0)
-	}
-	
-	fmt.Println("正在尝试连接到Outlook...")
-	fmt.Println("注意: 如果Outlook未运行，请先启动Outlook应用程序")
-	
-	// 尝试连接到已运行的Outlook实例
-	outlook, err := oleutil.GetActiveObject("Outlook.Application")
-	if err != nil {
-		fmt.Printf("无法连接到运行中的Outlook实例: %v\n", err)
-		fmt.Println("尝试创建新的Outlook实例...")
-		
-		// 如果获取失败，尝试创建新实例
-		outlook, err = oleutil.CreateObject("Outlook.Application")
-		if err != nil {
-			return nil, fmt.Errorf(`无法连接到Outlook: %v
-
-可能的解决方案:
-1. 确保Microsoft Outlook已安装并已配置邮箱账户
-2. 尝试先手动启动Outlook应用程序
-3. 检查Outlook是否被防病毒软件阻止
-4. 尝试以管理员身份运行此程序
-5. 检查Windows安全策略设置
-
-如果仍然无法连接，请联系IT管理员获取帮助。`, err)
-		}
-	}
-	
-	outlookApp := outlook.MustQueryInterface(ole.IID_IDispatch)
-	namespace, err := oleutil.CallMethod(outlookApp, "GetNamespace", "MAPI")
-	if err != nil {
-		return nil, fmt.Errorf(`无法获取Outlook命名空间: %v
-
-这通常意味着:
-1. Outlook未正确初始化
-2. 邮箱配置文件未加载
-3. 需要用户交互来完成Outlook登录
-
-请尝试:
-1. 手动启动Outlook并确保完全加载
-2. 确认所有邮箱账户都已连接
-3. 关闭任何Outlook登录对话框
-4. 重新运行此程序`, err)
-	}
-	
-	fmt.Println("✓ 成功连接到Outlook")
-	
+func NewOutlookEmailAnalyzer(source MailSource) *OutlookEmailAnalyzer {
 	return &OutlookEmailAnalyzer{
-		outlook:   outlookApp,
-		namespace: namespace.ToIDispatch(),
-	}, nil
-}
-
-func (oa *OutlookEmailAnalyzer) Close() {
-	if oa.namespace != nil {
-		oa.namespace.Release()
+		source:          source,
+		location:        time.Local,
+		calendar:        newDefaultCalendar(time.Local),
+		slaTarget:       4 * time.Hour,
+		waitDays:        2,
+		classifier:      newDefaultClassifier(),
+		minConfidence:   0.6,
+		resolver:        newAddressResolver(),
+		contacts:        newContactResolver(),
+		heatmapLocation: time.Local,
 	}
-	if oa.outlook != nil {
-		oa.outlook.Release()
-	}
-	ole.CoUninitialize()
 }
 
-func (oa *OutlookEmailAnalyzer) checkOutlookSecurity() {
-	fmt.Println("\n=== Outlook安全检查 ===")
-	
-	// 检查Outlook版本
-	version, err := oleutil.GetProperty(oa.outlook, "Version")
-	if err == nil {
-		fmt.Printf("Outlook版本: %s\n", version.ToString())
-		version.Clear()
-	}
-	
-	// 检查安全设置
-	fmt.Println("正在检查Outlook安全设置...")
-	
-	// 尝试访问基本功能来检查权限
-	accounts, err := oleutil.GetProperty(oa.namespace, "Accounts")
-	if err != nil {
-		fmt.Printf("⚠️  警告: 无法访问邮箱账户信息: %v\n", err)
-		fmt.Println("   这可能是由于Outlook安全策略限制")
-		fmt.Println("   某些功能可能无法正常工作")
-	} else {
-		fmt.Println("✓ 可以访问邮箱账户信息")
-		accounts.Clear()
-	}
-	
-	// 检查默认文件夹访问权限
-	inbox, err := oleutil.CallMethod(oa.namespace, "GetDefaultFolder", 6)
-	if err != nil {
-		fmt.Printf("⚠️  警告: 无法访问默认收件箱: %v\n", err)
-	} else {
-		fmt.Println("✓ 可以访问收件箱")
-		inbox.Clear()
-	}
-	
-	fmt.Println("=== 安全检查完成 ===\n")
+func (oa *OutlookEmailAnalyzer) Close() {
+	oa.source.Close()
 }
 
 func (oa *OutlookEmailAnalyzer) getDateInput(prompt string) (time.Time, error) {
-	reader := bufio.NewReader(os.Stdin)
+	reader := stdinReader
 	
 	for {
 		fmt.Print(prompt)
@@ -163,476 +111,6 @@ func (oa *OutlookEmailAnalyzer) getDateInput(prompt string) (time.Time, error) {
 	}
 }
 
-func (oa *OutlookEmailAnalyzer) listAvailableAccounts() error {
-	fmt.Println("正在获取邮箱账户列表...")
-	
-	accounts, err := oleutil.GetProperty(oa.namespace, "Accounts")
-	if err != nil {
-		fmt.Printf("⚠️  无法获取账户列表: %v\n", err)
-		fmt.Println("   可能原因:")
-		fmt.Println("   - Outlook安全策略限制")
-		fmt.Println("   - 需要用户确认访问权限")
-		fmt.Println("   - 邮箱配置文件未完全加载")
-		fmt.Println("\n建议:")
-		fmt.Println("   - 确保Outlook完全启动并登录所有账户")
-		fmt.Println("   - 检查是否有Outlook安全提示需要确认")
-		fmt.Println("   - 尝试在Outlook中手动发送一封测试邮件")
-		return err
-	}
-	defer accounts.Clear()
-	
-	accountsDisp := accounts.ToIDispatch()
-	defer accountsDisp.Release()
-	
-	count, err := oleutil.GetProperty(accountsDisp, "Count")
-	if err != nil {
-		return fmt.Errorf("无法获取账户数量: %v", err)
-	}
-	
-	fmt.Printf("找到 %d 个邮箱账户:\n", int(count.Val))
-	
-	if int(count.Val) == 0 {
-		fmt.Println("⚠️  未找到任何邮箱账户")
-		fmt.Println("   请确保:")
-		fmt.Println("   - Outlook中已配置邮箱账户")
-		fmt.Println("   - 所有账户都已成功连接")
-		fmt.Println("   - 没有未完成的登录流程")
-		return nil
-	}
-	
-	for i := 1; i <= int(count.Val); i++ {
-		account, err := oleutil.GetProperty(accountsDisp, "Item", i)
-		if err != nil {
-			fmt.Printf("   %d. (无法访问账户信息)\n", i)
-			continue
-		}
-		
-		accountDisp := account.ToIDispatch()
-		
-		displayName, err := oleutil.GetProperty(accountDisp, "DisplayName")
-		if err == nil {
-			smtpAddress, err := oleutil.GetProperty(accountDisp, "SmtpAddress")
-			if err == nil {
-				fmt.Printf("   %d. %s (%s)\n", i, displayName.ToString(), smtpAddress.ToString())
-				smtpAddress.Clear()
-			} else {
-				fmt.Printf("   %d. %s (邮箱地址不可用)\n", i, displayName.ToString())
-			}
-			displayName.Clear()
-		} else {
-			fmt.Printf("   %d. (账户信息不可用)\n", i)
-		}
-		
-		accountDisp.Release()
-		account.Clear()
-	}
-	
-	return nil
-}
-
-func (oa *OutlookEmailAnalyzer) getEmailAccount(emailAddress string) (*ole.IDispatch, error) {
-	fmt.Printf("正在查找邮箱账户: %s\n", emailAddress)
-	
-	accounts, err := oleutil.GetProperty(oa.namespace, "Accounts")
-	if err != nil {
-		return nil, fmt.Errorf("无法访问账户列表: %v", err)
-	}
-	defer accounts.Clear()
-	
-	accountsDisp := accounts.ToIDispatch()
-	defer accountsDisp.Release()
-	
-	count, err := oleutil.GetProperty(accountsDisp, "Count")
-	if err != nil {
-		return nil, err
-	}
-	
-	for i := 1; i <= int(count.Val); i++ {
-		account, err := oleutil.GetProperty(accountsDisp, "Item", i)
-		if err != nil {
-			continue
-		}
-		
-		accountDisp := account.ToIDispatch()
-		
-		smtpAddress, err := oleutil.GetProperty(accountDisp, "SmtpAddress")
-		if err == nil && strings.EqualFold(smtpAddress.ToString(), emailAddress) {
-			displayName, _ := oleutil.GetProperty(accountDisp, "DisplayName")
-			fmt.Printf("✓ 找到匹配的账户: %s\n", displayName.ToString())
-			displayName.Clear()
-			smtpAddress.Clear()
-			account.Clear()
-			return accountDisp, nil
-		}
-		
-		if err == nil {
-			smtpAddress.Clear()
-		}
-		accountDisp.Release()
-		account.Clear()
-	}
-	
-	fmt.Println("⚠️  未找到匹配的账户，将使用默认账户")
-	return nil, nil
-}
-
-func (oa *OutlookEmailAnalyzer) getInboxFolders(emailAddress string) ([]*ole.IDispatch, error) {
-	var folders []*ole.IDispatch
-	
-	// 尝试获取指定账户的收件箱
-	account, _ := oa.getEmailAccount(emailAddress)
-	var inbox *ole.IDispatch
-	
-	if account != nil {
-		deliveryStore, err := oleutil.GetProperty(account, "DeliveryStore")
-		if err == nil {
-			storeDisp := deliveryStore.ToIDispatch()
-			inboxResult, err := oleutil.CallMethod(storeDisp, "GetDefaultFolder", 6) // olFolderInbox = 6
-			if err == nil {
-				inbox = inboxResult.ToIDispatch()
-				displayName, _ := oleutil.GetProperty(account, "DisplayName")
-				fmt.Printf("✓ 使用账户的收件箱: %s\n", displayName.ToString())
-				displayName.Clear()
-			} else {
-				fmt.Printf("⚠️  无法访问账户收件箱: %v\n", err)
-			}
-			storeDisp.Release()
-			deliveryStore.Clear()
-		}
-		account.Release()
-	}
-	
-	// 如果账户收件箱失败，使用默认收件箱
-	if inbox == nil {
-		fmt.Println("尝试访问默认收件箱...")
-		inboxResult, err := oleutil.CallMethod(oa.namespace, "GetDefaultFolder", 6)
-		if err != nil {
-			return nil, fmt.Errorf(`无法获取收件箱: %v
-
-可能的原因:
-1. Outlook未完全加载邮箱数据
-2. 邮箱账户未正确配置
-3. 网络连接问题导致邮箱离线
-4. Outlook安全策略阻止程序访问
-
-建议解决方案:
-1. 确保Outlook完全启动并显示所有邮件
-2. 检查邮箱连接状态（文件 > 账户设置）
-3. 尝试在Outlook中手动刷新邮箱
-4. 重新启动Outlook后再运行此程序`, err)
-		}
-		inbox = inboxResult.ToIDispatch()
-		fmt.Println("✓ 使用默认收件箱")
-	}
-	
-	folders = append(folders, inbox)
-	
-	// 尝试获取子文件夹，如果失败也不影响主要功能
-	fmt.Println("正在查找子文件夹...")
-	oa.getSubfolders(inbox, &folders)
-	
-	fmt.Printf("✓ 总共找到 %d 个文件夹\n", len(folders))
-	return folders, nil
-}
-
-func (oa *OutlookEmailAnalyzer) getSubfolders(parentFolder *ole.IDispatch, folderList *[]*ole.IDispatch) {
-	foldersProperty, err := oleutil.GetProperty(parentFolder, "Folders")
-	if err != nil {
-		return
-	}
-	defer foldersProperty.Clear()
-	
-	foldersDisp := foldersProperty.ToIDispatch()
-	defer foldersDisp.Release()
-	
-	count, err := oleutil.GetProperty(foldersDisp, "Count")
-	if err != nil {
-		return
-	}
-	
-	for i := 1; i <= int(count.Val); i++ {
-		folder, err := oleutil.GetProperty(foldersDisp, "Item", i)
-		if err != nil {
-			continue
-		}
-		
-		folderDisp := folder.ToIDispatch()
-		*folderList = append(*folderList, folderDisp)
-		oa.getSubfolders(folderDisp, folderList)
-		folder.Clear()
-	}
-}
-
-func (oa *OutlookEmailAnalyzer) getEmailsInDateRange(folders []*ole.IDispatch, startDate, endDate time.Time) ([]EmailInfo, error) {
-	var emails []EmailInfo
-	
-	startStr := startDate.Format("01/02/2006")
-	endStr := endDate.AddDate(0, 0, 1).Format("01/02/2006")
-	
-	fmt.Printf("正在分析 %d 个文件夹的邮件...\n", len(folders))
-	
-	for folderIndex, folder := range folders {
-		folderName, err := oleutil.GetProperty(folder, "Name")
-		if err != nil {
-			fmt.Printf("文件夹 %d: (无法获取名称)\n", folderIndex+1)
-			continue
-		}
-		
-		fmt.Printf("正在读取文件夹 %d/%d: %s\n", folderIndex+1, len(folders), folderName.ToString())
-		
-		items, err := oleutil.GetProperty(folder, "Items")
-		if err != nil {
-			fmt.Printf("  ⚠️  无法访问文件夹内容: %v\n", err)
-			folderName.Clear()
-			continue
-		}
-		
-		itemsDisp := items.ToIDispatch()
-		
-		// 尝试使用过滤器来提高性能
-		filterStr := fmt.Sprintf("[ReceivedTime] >= '%s' AND [ReceivedTime] < '%s'", startStr, endStr)
-		filteredItems, err := oleutil.CallMethod(itemsDisp, "Restrict", filterStr)
-		var targetItems *ole.IDispatch
-		
-		if err == nil {
-			targetItems = filteredItems.ToIDispatch()
-			fmt.Printf("  ✓ 使用日期过滤器\n")
-		} else {
-			fmt.Printf("  ⚠️  过滤器失败，将手动检查所有邮件: %v\n", err)
-			targetItems = itemsDisp
-		}
-		
-		count, err := oleutil.GetProperty(targetItems, "Count")
-		if err == nil {
-			folderCount := 0
-			totalCount := int(count.Val)
-			fmt.Printf("  处理 %d 封邮件...\n", totalCount)
-			
-			for i := 1; i <= totalCount; i++ {
-				if i%50 == 0 {
-					fmt.Printf("  进度: %d/%d (%.1f%%)\n", i, totalCount, float64(i)/float64(totalCount)*100)
-				}
-				
-				item, err := oleutil.GetProperty(targetItems, "Item", i)
-				if err != nil {
-					continue
-				}
-				
-				itemDisp := item.ToIDispatch()
-				emailInfo := oa.extractEmailInfo(itemDisp, false, startDate, endDate)
-				if emailInfo.Subject != "" {
-					emails = append(emails, emailInfo)
-					folderCount++
-				}
-				
-				itemDisp.Release()
-				item.Clear()
-			}
-			fmt.Printf("  ✓ 找到 %d 封符合条件的邮件\n", folderCount)
-		} else {
-			fmt.Printf("  ⚠️  无法获取邮件数量: %v\n", err)
-		}
-		
-		if filteredItems != nil {
-			targetItems.Release()
-			filteredItems.Clear()
-		}
-		itemsDisp.Release()
-		items.Clear()
-		folderName.Clear()
-	}
-	
-	fmt.Printf("✓ 总共找到 %d 封邮件\n", len(emails))
-	return emails, nil
-}
-
-func (oa *OutlookEmailAnalyzer) getSentEmailsInDateRange(emailAddress string, startDate, endDate time.Time) ([]EmailInfo, error) {
-	fmt.Println("正在获取发送邮件...")
-	
-	var sentFolder *ole.IDispatch
-	
-	// 尝试获取指定账户的发送文件夹
-	account, _ := oa.getEmailAccount(emailAddress)
-	if account != nil {
-		deliveryStore, err := oleutil.GetProperty(account, "DeliveryStore")
-		if err == nil {
-			storeDisp := deliveryStore.ToIDispatch()
-			sentResult, err := oleutil.CallMethod(storeDisp, "GetDefaultFolder", 5) // olFolderSentMail = 5
-			if err == nil {
-				sentFolder = sentResult.ToIDispatch()
-				displayName, _ := oleutil.GetProperty(account, "DisplayName")
-				fmt.Printf("✓ 使用账户 %s 的发送文件夹\n", displayName.ToString())
-				displayName.Clear()
-			} else {
-				fmt.Printf("⚠️  无法访问账户发送文件夹: %v\n", err)
-			}
-			storeDisp.Release()
-			deliveryStore.Clear()
-		}
-		account.Release()
-	}
-	
-	// 如果失败，使用默认发送文件夹
-	if sentFolder == nil {
-		sentResult, err := oleutil.CallMethod(oa.namespace, "GetDefaultFolder", 5)
-		if err != nil {
-			fmt.Printf("⚠️  无法访问发送文件夹: %v\n", err)
-			fmt.Println("   跳过发送邮件分析，继续其他功能...")
-			return []EmailInfo{}, nil
-		}
-		sentFolder = sentResult.ToIDispatch()
-		fmt.Println("✓ 使用默认发送文件夹")
-	}
-	defer sentFolder.Release()
-	
-	var sentEmails []EmailInfo
-	startStr := startDate.Format("01/02/2006")
-	endStr := endDate.AddDate(0, 0, 1).Format("01/02/2006")
-	
-	items, err := oleutil.GetProperty(sentFolder, "Items")
-	if err != nil {
-		return sentEmails, fmt.Errorf("读取发送邮件失败: %v", err)
-	}
-	defer items.Clear()
-	
-	itemsDisp := items.ToIDispatch()
-	defer itemsDisp.Release()
-	
-	count, err := oleutil.GetProperty(itemsDisp, "Count")
-	if err == nil {
-		totalCount := int(count.Val)
-		fmt.Printf("发送文件夹中总共有 %d 封邮件\n", totalCount)
-		
-		// 尝试使用过滤器
-		filterStr := fmt.Sprintf("[SentOn] >= '%s' AND [SentOn] < '%s'", startStr, endStr)
-		filteredItems, err := oleutil.CallMethod(itemsDisp, "Restrict", filterStr)
-		
-		var targetItems *ole.IDispatch
-		if err == nil {
-			targetItems = filteredItems.ToIDispatch()
-			defer targetItems.Release()
-			defer filteredItems.Clear()
-			
-			filteredCount, _ := oleutil.GetProperty(targetItems, "Count")
-			fmt.Printf("✓ 过滤后有 %d 封邮件\n", int(filteredCount.Val))
-		} else {
-			fmt.Printf("⚠️  过滤失败，手动遍历: %v\n", err)
-			targetItems = itemsDisp
-		}
-		
-		itemCount, _ := oleutil.GetProperty(targetItems, "Count")
-		processCount := int(itemCount.Val)
-		
-		for i := 1; i <= processCount; i++ {
-			if i%20 == 0 {
-				fmt.Printf("处理发送邮件进度: %d/%d\n", i, processCount)
-			}
-			
-			item, err := oleutil.GetProperty(targetItems, "Item", i)
-			if err != nil {
-				continue
-			}
-			
-			itemDisp := item.ToIDispatch()
-			emailInfo := oa.extractEmailInfo(itemDisp, true, startDate, endDate)
-			if emailInfo.Subject != "" {
-				sentEmails = append(sentEmails, emailInfo)
-			}
-			
-			itemDisp.Release()
-			item.Clear()
-		}
-	}
-	
-	fmt.Printf("✓ 找到 %d 封发送邮件\n", len(sentEmails))
-	return sentEmails, nil
-}
-
-func (oa *OutlookEmailAnalyzer) extractEmailInfo(item *ole.IDispatch, isSent bool, startDate, endDate time.Time) EmailInfo {
-	var emailInfo EmailInfo
-	
-	// 获取主题
-	subject, err := oleutil.GetProperty(item, "Subject")
-	if err == nil {
-		emailInfo.Subject = subject.ToString()
-	}
-	subject.Clear()
-	
-	// 获取时间并验证日期范围
-	if isSent {
-		sentOn, err := oleutil.GetProperty(item, "SentOn")
-		if err == nil {
-			emailInfo.SentTime = sentOn.ToDateTime()
-			// 验证日期范围
-			if emailInfo.SentTime.Before(startDate) || emailInfo.SentTime.After(endDate.AddDate(0, 0, 1)) {
-				sentOn.Clear()
-				return EmailInfo{} // 返回空结构体表示不符合条件
-			}
-		}
-		sentOn.Clear()
-	} else {
-		receivedTime, err := oleutil.GetProperty(item, "ReceivedTime")
-		if err == nil {
-			emailInfo.ReceivedTime = receivedTime.ToDateTime()
-			// 验证日期范围
-			if emailInfo.ReceivedTime.Before(startDate) || emailInfo.ReceivedTime.After(endDate.AddDate(0, 0, 1)) {
-				receivedTime.Clear()
-				return EmailInfo{} // 返回空结构体表示不符合条件
-			}
-		}
-		receivedTime.Clear()
-		
-		// 获取已读状态
-		unread, err := oleutil.GetProperty(item, "UnRead")
-		if err == nil {
-			emailInfo.IsRead = !unread.ToBool()
-		}
-		unread.Clear()
-		
-		// 获取发件人信息
-		senderEmail, err := oleutil.GetProperty(item, "SenderEmailAddress")
-		if err == nil {
-			emailInfo.SenderEmail = senderEmail.ToString()
-		}
-		senderEmail.Clear()
-		
-		senderName, err := oleutil.GetProperty(item, "SenderName")
-		if err == nil {
-			emailInfo.SenderName = senderName.ToString()
-		}
-		senderName.Clear()
-	}
-	
-	// 获取收件人信息（用于发送邮件）
-	if isSent {
-		to, err := oleutil.GetProperty(item, "To")
-		if err == nil {
-			emailInfo.To = to.ToString()
-		}
-		to.Clear()
-		
-		cc, err := oleutil.GetProperty(item, "CC")
-		if err == nil {
-			emailInfo.CC = cc.ToString()
-		}
-		cc.Clear()
-	}
-	
-	// 尝试获取邮件正文（可能比较慢，所以可以选择跳过）
-	// 为了提高性能，只获取前500个字符用于分类
-	body, err := oleutil.GetProperty(item, "Body")
-	if err == nil {
-		bodyText := body.ToString()
-		if len(bodyText) > 500 {
-			bodyText = bodyText[:500]
-		}
-		emailInfo.Body = bodyText
-	}
-	body.Clear()
-	
-	return emailInfo
-}
-
 func (oa *OutlookEmailAnalyzer) analyzeReadStatus(emails []EmailInfo) (int, int, float64, float64) {
 	readCount := 0
 	unreadCount := 0
@@ -655,86 +133,72 @@ func (oa *OutlookEmailAnalyzer) analyzeReadStatus(emails []EmailInfo) (int, int,
 	return readCount, unreadCount, readPercentage, unreadPercentage
 }
 
-func (oa *OutlookEmailAnalyzer) findRepliedEmails(receivedEmails, sentEmails []EmailInfo) (int, int) {
+func (oa *OutlookEmailAnalyzer) findRepliedEmails(receivedEmails, sentEmails []EmailInfo) (int, int, []emailReply) {
 	if len(sentEmails) == 0 {
 		fmt.Println("⚠️  没有发送邮件数据，跳过回复分析")
-		return 0, 0
+		return 0, 0, nil
 	}
 	
-	repliedCount := 0
-	sameDayReplies := 0
-	
-	// 创建发送邮件的主题和时间映射
-	sentSubjects := make(map[string][]time.Time)
-	rePrefix := regexp.MustCompile(`^(re:|回复:|回覆:)\s*`)
-	
-	for _, sentEmail := range sentEmails {
-		subject := strings.ToLower(sentEmail.Subject)
-		cleanSubject := rePrefix.ReplaceAllString(subject, "")
-		sentDate := sentEmail.SentTime.Truncate(24 * time.Hour)
-		
-		if _, exists := sentSubjects[cleanSubject]; !exists {
-			sentSubjects[cleanSubject] = []time.Time{}
+	// 通过邮件头、会话索引或主题把每封发送的回复对应到原邮件，保留最早的一封回复
+	firstReply := make(map[int]EmailInfo)
+	for i, original := range matchReplies(receivedEmails, sentEmails) {
+		if original < 0 {
+			continue
+		}
+		if reply, exists := firstReply[original]; !exists || sentEmails[i].SentTime.Before(reply.SentTime) {
+			firstReply[original] = sentEmails[i]
 		}
-		sentSubjects[cleanSubject] = append(sentSubjects[cleanSubject], sentDate)
 	}
 	
-	for _, email := range receivedEmails {
+	repliedCount := 0
+	sameDayReplies := 0
+	var replies []emailReply
+
+	for i, email := range receivedEmails {
 		if !email.IsRead {
 			continue
 		}
 		
-		subject := strings.ToLower(email.Subject)
-		cleanSubject := rePrefix.ReplaceAllString(subject, "")
-		receivedDate := email.ReceivedTime.Truncate(24 * time.Hour)
-		
-		if sentDates, exists := sentSubjects[cleanSubject]; exists {
-			repliedCount++
-			for _, sentDate := range sentDates {
-				if sentDate.Equal(receivedDate) {
-					sameDayReplies++
-					break
-				}
-			}
+		reply, exists := firstReply[i]
+		if !exists {
+			continue
+		}
+		repliedCount++
+		pair := emailReply{Original: email, Reply: reply}
+		if !pair.dated() {
+			continue
 		}
+		if pair.sameBusinessDay(oa.calendar) {
+			sameDayReplies++
+		}
+		replies = append(replies, pair)
 	}
 	
-	return repliedCount, sameDayReplies
+	return repliedCount, sameDayReplies, replies
 }
 
 func (oa *OutlookEmailAnalyzer) getTopSendersAndRecipients(receivedEmails, sentEmails []EmailInfo) ([]SenderCount, []SenderCount) {
 	senderCounts := make(map[string]int)
 	recipientCounts := make(map[string]int)
 	
-	// 统计发件人
+	// 统计发件人，同一联系人的不同地址合并计数
 	for _, email := range receivedEmails {
-		sender := email.SenderEmail
-		if sender == "" {
-			sender = email.SenderName
-		}
-		if sender != "" {
+		if sender := oa.contacts.senderIdentity(email); sender != "" {
 			senderCounts[sender]++
 		}
 	}
 	
 	// 统计收件人
 	for _, email := range sentEmails {
-		recipients := strings.Split(email.To, ";")
-		ccRecipients := strings.Split(email.CC, ";")
-		allRecipients := append(recipients, ccRecipients...)
-		
-		for _, recipient := range allRecipients {
-			recipient = strings.TrimSpace(recipient)
-			if recipient != "" {
-				recipientCounts[recipient]++
-			}
+		for _, recipient := range oa.contacts.recipientIdentities(email.recipientsOf()) {
+			recipientCounts[recipient]++
 		}
 	}
 	
 	// 转换为切片并排序
 	var topSenders []SenderCount
 	for sender, count := range senderCounts {
-		topSenders = append(topSenders, SenderCount{Email: sender, Count: count})
+		topSenders = append(topSenders, SenderCount{Email: oa.contacts.label(sender), Count: count})
 	}
 	sort.Slice(topSenders, func(i, j int) bool {
 		return topSenders[i].Count > topSenders[j].Count
@@ -745,7 +209,7 @@ func (oa *OutlookEmailAnalyzer) getTopSendersAndRecipients(receivedEmails, sentE
 	
 	var topRecipients []SenderCount
 	for recipient, count := range recipientCounts {
-		topRecipients = append(topRecipients, SenderCount{Email: recipient, Count: count})
+		topRecipients = append(topRecipients, SenderCount{Email: oa.contacts.label(recipient), Count: count})
 	}
 	sort.Slice(topRecipients, func(i, j int) bool {
 		return topRecipients[i].Count > topRecipients[j].Count
@@ -757,52 +221,59 @@ func (oa *OutlookEmailAnalyzer) getTopSendersAndRecipients(receivedEmails, sentE
 	return topSenders, topRecipients
 }
 
-func (oa *OutlookEmailAnalyzer) classifyEmails(emails []EmailInfo) (int, int, int) {
-	infoKeywords := []string{"通知", "信息", "更新", "公告", "新闻", "newsletter", "notification", "update", "info", "通告"}
-	approvalKeywords := []string{"批准", "审批", "确认", "同意", "授权", "approve", "approval", "authorize", "confirm", "核准", "签核"}
-	responseKeywords := []string{"回复", "回应", "反馈", "意见", "建议", "reply", "response", "feedback", "urgent", "紧急", "请回复", "请回覆"}
-	
-	infoCount := 0
-	approvalCount := 0
-	responseCount := 0
-	
+// classifyEmails 为收到的邮件打上分类标签，统计每个分类的邮件数、分类两两同时出现的次数，
+// 并找出主要分类置信度低于oa.minConfidence的邮件
+func (oa *OutlookEmailAnalyzer) classifyEmails(emails []EmailInfo) classificationStats {
+	stats := classificationStats{Total: len(emails)}
+	categories := oa.classifier.categories()
+	order := make(map[*emailCategory]int)
+	for i, category := range categories {
+		order[category] = i
+	}
+
+	counts := make(map[*emailCategory]int)
+	pairs := make(map[[2]*emailCategory]int)
 	for _, email := range emails {
-		subject := strings.ToLower(email.Subject)
-		body := strings.ToLower(email.Body)
-		
-		foundApproval := false
-		foundResponse := false
-		
-		// 检查是否包含批准关键词
-		for _, keyword := range approvalKeywords {
-			if strings.Contains(subject, keyword) || strings.Contains(body, keyword) {
-				approvalCount++
-				foundApproval = true
-				break
-			}
-		}
-		
-		if !foundApproval {
-			// 检查是否需要回复
-			for _, keyword := range responseKeywords {
-				if strings.Contains(subject, keyword) || strings.Contains(body, keyword) {
-					responseCount++
-					foundResponse = true
-					break
+		labels := oa.classifier.labels(email)
+		for i, label := range labels {
+			counts[label.Category]++
+			for _, other := range labels[i+1:] {
+				key := [2]*emailCategory{label.Category, other.Category}
+				if order[key[0]] > order[key[1]] {
+					key[0], key[1] = key[1], key[0]
 				}
+				pairs[key]++
 			}
 		}
-		
-		if !foundApproval && !foundResponse {
-			infoCount++
+		if labels[0].Confidence < oa.minConfidence {
+			stats.LowConfidence = append(stats.LowConfidence, lowConfidenceEmail{Email: email, Labels: labels})
 		}
 	}
-	
-	return infoCount, approvalCount, responseCount
+
+	for _, category := range categories {
+		stats.Counts = append(stats.Counts, categoryCount{Category: category, Count: counts[category]})
+	}
+	for key, count := range pairs {
+		stats.CoOccurrence = append(stats.CoOccurrence, labelPair{First: key[0], Second: key[1], Count: count})
+	}
+	sort.Slice(stats.CoOccurrence, func(i, j int) bool {
+		a, b := stats.CoOccurrence[i], stats.CoOccurrence[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if order[a.First] != order[b.First] {
+			return order[a.First] < order[b.First]
+		}
+		return order[a.Second] < order[b.Second]
+	})
+	sort.SliceStable(stats.LowConfidence, func(i, j int) bool {
+		return stats.LowConfidence[i].Labels[0].Confidence < stats.LowConfidence[j].Labels[0].Confidence
+	})
+	return stats
 }
 
 func (oa *OutlookEmailAnalyzer) printResults(totalReceived, readCount, unreadCount int, readPercentage, unreadPercentage float64,
-	repliedCount, sameDayReplies int, topSenders, topRecipients []SenderCount, infoCount, approvalCount, responseCount int) {
+	repliedCount, sameDayReplies int, latency replyLatencyStats, sla slaStats, topSenders, topRecipients []SenderCount, classification classificationStats, unanswered []unansweredEmail, awaiting []awaitingRecipient, repliesUntilNow bool, bulk bulkStats, auto autoEmailStats, attention attentionStats, traffic trafficHeatmap) {
 	
 	fmt.Println("\n" + strings.Repeat("=", 60))
 	fmt.Println("📊 邮件分析结果")
@@ -810,6 +281,9 @@ func (oa *OutlookEmailAnalyzer) printResults(totalReceived, readCount, unreadCou
 	
 	fmt.Printf("\n📧 1. 收件箱邮件统计:\n")
 	fmt.Printf("   总收到邮件数: %d 封\n", totalReceived)
+	if auto.Received > 0 {
+		fmt.Printf("   其中自动回复、退信和回执: %d 封，不计入以下读取和回复统计\n", auto.Received)
+	}
 	
 	fmt.Printf("\n👁️ 2. 邮件读取状态:\n")
 	fmt.Printf("   已读邮件: %d 封 (%.1f%%)\n", readCount, readPercentage)
@@ -817,11 +291,16 @@ func (oa *OutlookEmailAnalyzer) printResults(totalReceived, readCount, unreadCou
 	
 	fmt.Printf("\n↩️ 3. 邮件回复统计:\n")
 	fmt.Printf("   已回复邮件数: %d 封\n", repliedCount)
-	fmt.Printf("   当天回复数: %d 封\n", sameDayReplies)
-	if repliedCount > 0 {
-		sameDayPercentage := float64(sameDayReplies) / float64(repliedCount) * 100
+	fmt.Printf("   当天回复数（工作日下班前）: %d 封\n", sameDayReplies)
+	if latency.Count > 0 {
+		sameDayPercentage := float64(sameDayReplies) / float64(latency.Count) * 100
 		fmt.Printf("   当天回复率: %.1f%%\n", sameDayPercentage)
 	}
+	if undated := repliedCount - latency.Count; undated > 0 {
+		fmt.Printf("   有 %d 封回复没有日期，不计入当天回复、回复时效和SLA\n", undated)
+	}
+	oa.printReplyLatency(latency)
+	oa.printSLA(sla)
 	
 	fmt.Printf("\n📬 4. 前5名发件人:\n")
 	if len(topSenders) > 0 {
@@ -841,11 +320,15 @@ func (oa *OutlookEmailAnalyzer) printResults(totalReceived, readCount, unreadCou
 		fmt.Printf("   无发送邮件数据\n")
 	}
 	
-	fmt.Printf("\n📋 6. 邮件分类统计:\n")
-	fmt.Printf("   a. 信息类邮件: %d 封 (%.1f%%)\n", infoCount, float64(infoCount)/float64(totalReceived)*100)
-	fmt.Printf("   b. 需要批准的邮件: %d 封 (%.1f%%)\n", approvalCount, float64(approvalCount)/float64(totalReceived)*100)
-	fmt.Printf("   c. 需要回复的邮件: %d 封 (%.1f%%)\n", responseCount, float64(responseCount)/float64(totalReceived)*100)
+	oa.printClassification(classification)
 	
+	oa.printUnanswered(unanswered, repliesUntilNow)
+	oa.printAwaitingResponse(awaiting, repliesUntilNow)
+	oa.printBulkMail(bulk)
+	oa.printAutoEmails(auto)
+	oa.printAttention(attention)
+	oa.printTraffic(traffic)
+
 	fmt.Println("\n" + strings.Repeat("=", 60))
 	
 	// 添加一些有用的建议
@@ -853,31 +336,57 @@ func (oa *OutlookEmailAnalyzer) printResults(totalReceived, readCount, unreadCou
 	if unreadPercentage > 20 {
 		fmt.Println("   - 未读邮件较多，建议及时处理重要邮件")
 	}
-	if repliedCount > 0 && sameDayReplies < repliedCount/2 {
+	if latency.Count > 0 && sameDayReplies < latency.Count/2 {
 		fmt.Println("   - 考虑提高邮件回复及时性")
 	}
-	if responseCount > 0 {
-		fmt.Printf("   - 有 %d 封邮件可能需要您的回复\n", responseCount)
+	if sla.Overall.Total > 0 && sla.Overall.attainment() < 80 {
+		fmt.Printf("   - SLA达成率为 %.1f%%，有 %d 封邮件未在%s工作时间内回复\n",
+			sla.Overall.attainment(), sla.Overall.Total-sla.Overall.Met, formatSLATarget(sla.Target))
+	}
+	for _, category := range classification.Counts {
+		if category.Category.Action && category.Count > 0 {
+			fmt.Printf("   - 有 %d 封“%s”邮件可能需要您处理\n", category.Count, category.Category.Name)
+		}
+	}
+	if len(unanswered) > 0 {
+		fmt.Printf("   - 其中 %d 封尚未回复，详见第7项待回复邮件\n", len(unanswered))
+	}
+	if len(awaiting) > 0 {
+		fmt.Printf("   - 有 %d 位收件人尚未回复您的提问或请求，可考虑跟进\n", len(awaiting))
 	}
-	if approvalCount > 0 {
-		fmt.Printf("   - 有 %d 封邮件可能需要您的批准\n", approvalCount)
+	if len(bulk.Candidates) > 0 {
+		fmt.Printf("   - 有 %d 个邮件列表从未打开，可考虑退订以减少信息类邮件\n", len(bulk.Candidates))
+	}
+	if len(auto.Bounced) > 0 {
+		fmt.Printf("   - 有 %d 个地址投递失败，建议核对地址或从通讯录中删除\n", len(auto.Bounced))
+	}
+	if len(attention.Lists) > 0 {
+		fmt.Printf("   - 分发列表 %s 有 %d 封未读邮件，可考虑设置规则归档或退出该列表\n", attention.Lists[0].Name, attention.Lists[0].Unread)
+	}
+	if traffic.SentShare.Total > 0 && traffic.SentShare.afterHoursRate() > 30 {
+		fmt.Printf("   - %.1f%% 的邮件在非工作时间发出，可考虑使用延迟发送\n", traffic.SentShare.afterHoursRate())
 	}
 }
 
 func (oa *OutlookEmailAnalyzer) runAnalysis() error {
 	fmt.Println("=== 📧 Outlook 邮件分析工具 ===")
 	fmt.Println("此工具将分析您的Outlook邮件数据")
-	fmt.Println("请确保Outlook已完全启动并登录所有账户\n")
+	fmt.Println("请确保Outlook已完全启动并登录所有账户")
+	fmt.Println()
 	
-	// 执行安全检查
-	oa.checkOutlookSecurity()
+	// 执行安全检查（仅部分数据源支持）
+	if checker, ok := oa.source.(securityChecker); ok {
+		checker.checkOutlookSecurity()
+	}
 	
 	// 显示可用账户
-	if err := oa.listAvailableAccounts(); err != nil {
-		fmt.Printf("⚠️  获取账户列表时遇到问题: %v\n", err)
-		fmt.Println("程序将尝试继续运行...")
+	if lister, ok := oa.source.(accountLister); ok {
+		if err := lister.listAvailableAccounts(); err != nil {
+			fmt.Printf("⚠️  获取账户列表时遇到问题: %v\n", err)
+			fmt.Println("程序将尝试继续运行...")
+		}
+		fmt.Println()
 	}
-	fmt.Println()
 	
 	// 获取用户输入
 	startDate, err := oa.getDateInput("请输入开始日期 (DD-MM-YYYY): ")
@@ -900,14 +409,14 @@ func (oa *OutlookEmailAnalyzer) runAnalysis() error {
 	if daysDiff > 365 {
 		fmt.Printf("⚠️  警告: 日期范围超过一年 (%.0f 天)，分析可能需要较长时间\n", daysDiff)
 		fmt.Print("是否继续? (y/n): ")
-		reader := bufio.NewReader(os.Stdin)
+		reader := stdinReader
 		response, _ := reader.ReadString('\n')
 		if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(response)), "y") {
 			return fmt.Errorf("用户取消操作")
 		}
 	}
 	
-	reader := bufio.NewReader(os.Stdin)
+	reader := stdinReader
 	fmt.Print("请输入邮箱地址 (或按回车使用默认账户): ")
 	emailAddress, err := reader.ReadString('\n')
 	if err != nil {
@@ -923,23 +432,8 @@ func (oa *OutlookEmailAnalyzer) runAnalysis() error {
 	fmt.Printf("\n🔍 正在分析 %s 到 %s 的邮件...\n", 
 		startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 	
-	// 获取收件箱文件夹
-	inboxFolders, err := oa.getInboxFolders(emailAddress)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		for _, folder := range inboxFolders {
-			folder.Release()
-		}
-	}()
-	
-	if len(inboxFolders) == 0 {
-		return fmt.Errorf("无法获取收件箱文件夹")
-	}
-	
 	// 获取收到的邮件
-	receivedEmails, err := oa.getEmailsInDateRange(inboxFolders, startDate, endDate)
+	receivedEmails, err := oa.source.ReceivedEmails(emailAddress, startDate, endDate)
 	if err != nil {
 		return err
 	}
@@ -954,46 +448,225 @@ func (oa *OutlookEmailAnalyzer) runAnalysis() error {
 	}
 	
 	// 获取发送的邮件
-	sentEmails, err := oa.getSentEmailsInDateRange(emailAddress, startDate, endDate)
+	sentEmails, err := oa.source.SentEmails(emailAddress, startDate, endDate)
 	if err != nil {
 		fmt.Printf("⚠️  获取发送邮件时出错: %v\n", err)
 		fmt.Println("   继续进行其他分析...")
 		sentEmails = []EmailInfo{} // 继续执行，但没有发送邮件数据
 	}
 	
+	// 期间内的邮件可能在期间结束后才回复，读取之后的邮件用于查找回复
+	laterReceived, laterSent, repliesUntilNow := oa.fetchLaterEmails(emailAddress, endDate, time.Now())
+
+	// 离线数据源中Exchange内部地址为X.500格式，按映射文件转换为SMTP地址
+	oa.resolver.resolveEmails(receivedEmails)
+	oa.resolver.resolveEmails(sentEmails)
+	oa.resolver.resolveEmails(laterReceived)
+	oa.resolver.resolveEmails(laterSent)
+	printUnresolvedAddresses(oa.resolver.unresolvedAddresses())
+
+	// 自动回复、退信和回执单独统计，以下分析只使用其余邮件
+	allReceived := receivedEmails
+	receivedEmails, autoReceived := separateAutoEmails(receivedEmails)
+	sentEmails, autoSent := separateAutoEmails(sentEmails)
+	laterReceived, _ = separateAutoEmails(laterReceived)
+	laterSent, _ = separateAutoEmails(laterSent)
+
+	// 按联系人统计：同一人的不同地址和显示名合并
+	oa.contacts.observeEmails(receivedEmails)
+	oa.contacts.observeEmails(sentEmails)
+	oa.contacts.finish()
+
 	// 执行各项分析
 	fmt.Println("\n📊 正在进行数据分析...")
-	
+
+	auto := oa.analyzeAutoEmails(autoReceived, autoSent)
+
 	readCount, unreadCount, readPercentage, unreadPercentage := oa.analyzeReadStatus(receivedEmails)
-	repliedCount, sameDayReplies := oa.findRepliedEmails(receivedEmails, sentEmails)
+	repliedCount, sameDayReplies, replies := oa.findRepliedEmails(receivedEmails, sentEmails)
+	latency := oa.analyzeReplyLatency(replies)
+	sla := oa.analyzeSLA(replies)
 	topSenders, topRecipients := oa.getTopSendersAndRecipients(receivedEmails, sentEmails)
-	infoCount, approvalCount, responseCount := oa.classifyEmails(receivedEmails)
+	classification := oa.classifyEmails(receivedEmails)
+	unanswered := oa.findUnansweredEmails(receivedEmails, append(append([]EmailInfo{}, sentEmails...), laterSent...), time.Now())
+	awaiting := oa.findAwaitingResponse(append(append([]EmailInfo{}, receivedEmails...), laterReceived...), sentEmails, time.Now())
+	bulk := oa.analyzeBulkMail(receivedEmails)
+	attention := oa.analyzeAttention(emailAddress, receivedEmails, sentEmails)
+	traffic := oa.analyzeTraffic(receivedEmails, sentEmails, oa.heatmapLocation)
 	
 	// 打印结果
-	oa.printResults(len(receivedEmails), readCount, unreadCount, readPercentage, unreadPercentage,
-		repliedCount, sameDayReplies, topSenders, topRecipients, infoCount, approvalCount, responseCount)
+	oa.printResults(len(allReceived), readCount, unreadCount, readPercentage, unreadPercentage,
+		repliedCount, sameDayReplies, latency, sla, topSenders, topRecipients, classification, unanswered, awaiting, repliesUntilNow, bulk, auto, attention, traffic)
+
+	if oa.heatmapPath != "" {
+		if err := exportTraffic(traffic, oa.heatmapPath); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		} else {
+			fmt.Printf("✓ 收发时间分布已导出到 %s\n", oa.heatmapPath)
+		}
+	}
 	
 	return nil
 }
 
+// fetchLaterEmails 读取分析期间结束后到now的邮件，只用于查找期间内邮件的回复，不计入统计。
+// 期间截止到今天或数据源没有日期时不需要读取；读取失败时返回false，报告中注明回复只在分析期间内查找
+func (oa *OutlookEmailAnalyzer) fetchLaterEmails(account string, endDate, now time.Time) ([]EmailInfo, []EmailInfo, bool) {
+	start := endDate.AddDate(0, 0, 1)
+	if !start.Before(now) {
+		return nil, nil, true
+	}
+	// 没有日期的数据源已经返回了所有邮件，再读取会重复
+	if checker, ok := oa.source.(undatedChecker); ok && checker.isUndated() {
+		return nil, nil, true
+	}
+
+	fmt.Printf("\n🔍 正在读取 %s 之后的邮件以查找回复...\n", endDate.Format("2006-01-02"))
+	received, err := oa.source.ReceivedEmails(account, start, now)
+	if err != nil {
+		fmt.Printf("⚠️  读取之后收到的邮件失败: %v\n", err)
+		return nil, nil, false
+	}
+	sent, err := oa.source.SentEmails(account, start, now)
+	if err != nil {
+		fmt.Printf("⚠️  读取之后发送的邮件失败: %v\n", err)
+		return nil, nil, false
+	}
+	return received, sent, true
+}
+
 func main() {
+	var opts sourceOptions
+	flag.StringVar(&opts.Kind, "source", "outlook", "邮件数据来源: outlook, mbox, maildir, eml, msg, pst, csv, imap, graph, ews")
+	flag.StringVar(&opts.Path, "path", "", "文件类数据源的路径")
+	flag.StringVar(&opts.SentPath, "sent-path", "", "发送邮件所在的文件或目录（可选）")
+	flag.StringVar(&opts.MboxFormat, "mbox-format", "mboxrd", "mbox变体: mboxrd 或 mboxo")
+	flag.StringVar(&opts.Server, "server", "", "服务器地址，如 imaps://imap.example.com:993；graph数据源可用于指定Graph终结点")
+	flag.StringVar(&opts.User, "user", "", "服务器登录用户名（EWS可使用 域\\用户名）")
+	flag.StringVar(&opts.Password, "password", "", "服务器登录密码（也可通过MAIL_PASSWORD环境变量提供）")
+	flag.StringVar(&opts.Token, "token", "", "Microsoft Graph访问令牌（也可通过GRAPH_TOKEN环境变量提供）")
+	flag.StringVar(&opts.Tenant, "tenant", "organizations", "设备代码登录使用的Azure AD租户")
+	flag.StringVar(&opts.ClientID, "client-id", "", "设备代码登录使用的应用程序(客户端)ID")
+	timezone := flag.String("tz", "", "划分日期边界使用的时区，如 Asia/Shanghai（默认为系统时区）")
+	calendarPath := flag.String("calendar", "", "工作日历文件（.ics节假日日历或.yaml工作时间配置），默认周一至周五 9:00-18:00")
+	slaTarget := flag.Duration("sla", 4*time.Hour, "回复SLA目标（工作时间），如 4h、30m")
+	rulesPath := flag.String("rules", "", "邮件分类规则文件（.json或.yaml），默认使用内置的批准/回复关键词规则")
+	modelPath := flag.String("model", "", "朴素贝叶斯分类模型文件；指定后用模型代替规则分类，也是 -train 的输出位置")
+	trainPath := flag.String("train", "", "用带标注的样本文件（JSONL，每行含subject、body、category，可用action标注是否需要处理）训练模型后退出")
+	evaluatePath := flag.String("evaluate", "", "用留出的样本文件（JSONL）评估模型的精确率和召回率后退出")
+	minConfidence := flag.Float64("min-confidence", 0.6, "主要分类置信度低于该值（0-1）的邮件列入人工复核")
+	addressMapPath := flag.String("address-map", "", "Exchange内部地址（X.500 DN）到SMTP地址的映射文件（CSV），用于pst、msg、csv等离线数据源")
+	aliasesPath := flag.String("aliases", "", "联系人别名文件（CSV），每行列出同一个人的地址和显示名，或以@开头的别名域名")
+	heatmapTimezone := flag.String("heatmap-tz", "", "收发时间热力图使用的时区，如 America/New_York（默认与 -tz 相同）")
+	heatmapPath := flag.String("heatmap", "", "将收发时间热力图导出到文件，按扩展名为 .csv 或 .json")
+	waitDays := flag.Float64("wait-days", 2, "发出的提问或请求超过多少个工作日未收到回复时列入“等待对方回复”")
+	flag.Parse()
+
+	rules := newDefaultClassifier()
+	if *rulesPath != "" {
+		classifier, err := loadClassifier(*rulesPath)
+		if err != nil {
+			fmt.Printf("⚠️  %v，使用内置分类规则\n", err)
+		} else {
+			rules = classifier
+		}
+	}
+
+	if *trainPath != "" || *evaluatePath != "" {
+		if *modelPath == "" {
+			fmt.Println("❌ 训练和评估模型需要用 -model 指定模型文件")
+			return
+		}
+		var err error
+		if *trainPath != "" {
+			err = runBayesTraining(*trainPath, *modelPath, rules)
+		}
+		if err == nil && *evaluatePath != "" {
+			err = runBayesEvaluation(*evaluatePath, *modelPath)
+		}
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+		}
+		return
+	}
+
 	fmt.Println("正在启动Outlook邮件分析工具...")
 	fmt.Println("版本: 2.0 (增强权限处理)")
 	
-	analyzer, err := NewOutlookEmailAnalyzer()
+	source, err := openMailSource(opts)
 	if err != nil {
 		fmt.Printf("\n❌ 初始化失败: %v\n\n", err)
-		fmt.Println("🔧 故障排除建议:")
-		fmt.Println("1. 确保Microsoft Outlook已安装并配置")
-		fmt.Println("2. 尝试手动启动Outlook并确保完全加载")
-		fmt.Println("3. 如果使用企业版Outlook，联系IT部门检查安全策略")
-		fmt.Println("4. 尝试以管理员身份运行此程序")
-		fmt.Println("5. 检查防病毒软件是否阻止了程序访问")
+		if opts.Kind == "outlook" {
+			fmt.Println("🔧 故障排除建议:")
+			fmt.Println("1. 确保Microsoft Outlook已安装并配置")
+			fmt.Println("2. 尝试手动启动Outlook并确保完全加载")
+			fmt.Println("3. 如果使用企业版Outlook，联系IT部门检查安全策略")
+			fmt.Println("4. 尝试以管理员身份运行此程序")
+			fmt.Println("5. 检查防病毒软件是否阻止了程序访问")
+		}
 		
 		fmt.Print("\n按回车键退出...")
-		bufio.NewReader(os.Stdin).ReadString('\n')
+		stdinReader.ReadString('\n')
 		return
 	}
+	analyzer := NewOutlookEmailAnalyzer(source)
+	if *timezone != "" {
+		loc, err := time.LoadLocation(*timezone)
+		if err != nil {
+			fmt.Printf("⚠️  无效的时区 %s，使用系统时区: %v\n", *timezone, err)
+		} else {
+			analyzer.location = loc
+		}
+	}
+	analyzer.heatmapLocation = analyzer.location
+	if *heatmapTimezone != "" {
+		loc, err := time.LoadLocation(*heatmapTimezone)
+		if err != nil {
+			fmt.Printf("⚠️  无效的时区 %s，热力图使用 %s: %v\n", *heatmapTimezone, analyzer.location, err)
+		} else {
+			analyzer.heatmapLocation = loc
+		}
+	}
+	analyzer.heatmapPath = *heatmapPath
+	analyzer.calendar = newDefaultCalendar(analyzer.location)
+	if *calendarPath != "" {
+		calendar, err := loadWorkCalendar(*calendarPath, analyzer.location)
+		if err != nil {
+			fmt.Printf("⚠️  %v，使用默认工作时间\n", err)
+		} else {
+			analyzer.calendar = calendar
+		}
+	}
+	analyzer.slaTarget = *slaTarget
+	analyzer.waitDays = *waitDays
+	analyzer.minConfidence = *minConfidence
+	if *aliasesPath != "" {
+		contacts, err := loadContactAliases(*aliasesPath)
+		if err != nil {
+			fmt.Printf("⚠️  %v，不使用别名文件\n", err)
+		} else {
+			analyzer.contacts = contacts
+		}
+	}
+	if *addressMapPath != "" {
+		resolver, err := loadAddressMap(*addressMapPath)
+		if err != nil {
+			fmt.Printf("⚠️  %v，不转换Exchange内部地址\n", err)
+		} else {
+			analyzer.resolver = resolver
+			fmt.Printf("✓ 已加载 %d 个Exchange地址映射\n", len(resolver.mapping))
+		}
+	}
+	analyzer.classifier = rules
+	if *modelPath != "" {
+		model, err := loadBayesModel(*modelPath)
+		if err != nil {
+			fmt.Printf("⚠️  %v，使用规则分类\n", err)
+		} else {
+			analyzer.classifier = newBayesClassifier(model, rules)
+			fmt.Printf("✓ 使用朴素贝叶斯模型分类: %s\n", *modelPath)
+		}
+	}
 	defer analyzer.Close()
 	
 	if err := analyzer.runAnalysis(); err != nil {
@@ -1003,5 +676,5 @@ func main() {
 	}
 	
 	fmt.Print("\n按回车键退出...")
-	bufio.NewReader(os.Stdin).ReadString('\n')
+	stdinReader.ReadString('\n')
 } 
\ No newline at end of file
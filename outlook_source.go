@@ -0,0 +1,837 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+)
+
+// OutlookSource 通过Outlook COM自动化读取邮件，是MailSource的一种实现
+type OutlookSource struct {
+	outlook   *ole.IDispatch
+	namespace *ole.IDispatch
+	// Exchange旧式DN（小写）到SMTP地址的缓存，无法解析的为空字符串
+	smtpAddresses map[string]string
+}
+
+func NewOutlookSource() (*OutlookSource, error) {
+	// 初始化COM，使用单线程模式来减少权限需求
+	err := ole.CoInitializeEx(0, ole.COINIT_APARTMENTTHREADED)
+	if err != nil {
+		fmt.Printf("警告: COM初始化失败: %v\n", err)
+		fmt.Println("尝试使用标准初始化...")
+		ole.CoInitialize(0)
+	}
+
+	fmt.Println("正在尝试连接到Outlook...")
+	fmt.Println("注意: 如果Outlook未运行，请先启动Outlook应用程序")
+
+	// 尝试连接到已运行的Outlook实例
+	outlook, err := oleutil.GetActiveObject("Outlook.Application")
+	if err != nil {
+		fmt.Printf("无法连接到运行中的Outlook实例: %v\n", err)
+		fmt.Println("尝试创建新的Outlook实例...")
+
+		// 如果获取失败，尝试创建新实例
+		outlook, err = oleutil.CreateObject("Outlook.Application")
+		if err != nil {
+			return nil, fmt.Errorf(`无法连接到Outlook: %v
+
+可能的解决方案:
+1. 确保Microsoft Outlook已安装并已配置邮箱账户
+2. 尝试先手动启动Outlook应用程序
+3. 检查Outlook是否被防病毒软件阻止
+4. 尝试以管理员身份运行此程序
+5. 检查Windows安全策略设置
+
+如果仍然无法连接，请联系IT管理员获取帮助。`, err)
+		}
+	}
+
+	outlookApp := outlook.MustQueryInterface(ole.IID_IDispatch)
+	namespace, err := oleutil.CallMethod(outlookApp, "GetNamespace", "MAPI")
+	if err != nil {
+		return nil, fmt.Errorf(`无法获取Outlook命名空间: %v
+
+这通常意味着:
+1. Outlook未正确初始化
+2. 邮箱配置文件未加载
+3. 需要用户交互来完成Outlook登录
+
+请尝试:
+1. 手动启动Outlook并确保完全加载
+2. 确认所有邮箱账户都已连接
+3. 关闭任何Outlook登录对话框
+4. 重新运行此程序`, err)
+	}
+
+	fmt.Println("✓ 成功连接到Outlook")
+
+	return &OutlookSource{
+		outlook:       outlookApp,
+		namespace:     namespace.ToIDispatch(),
+		smtpAddresses: make(map[string]string),
+	}, nil
+}
+
+func (ol *OutlookSource) Close() {
+	if ol.namespace != nil {
+		ol.namespace.Release()
+	}
+	if ol.outlook != nil {
+		ol.outlook.Release()
+	}
+	ole.CoUninitialize()
+}
+
+func (ol *OutlookSource) checkOutlookSecurity() {
+	fmt.Println("\n=== Outlook安全检查 ===")
+
+	// 检查Outlook版本
+	version, err := oleutil.GetProperty(ol.outlook, "Version")
+	if err == nil {
+		fmt.Printf("Outlook版本: %s\n", version.ToString())
+		version.Clear()
+	}
+
+	// 检查安全设置
+	fmt.Println("正在检查Outlook安全设置...")
+
+	// 尝试访问基本功能来检查权限
+	accounts, err := oleutil.GetProperty(ol.namespace, "Accounts")
+	if err != nil {
+		fmt.Printf("⚠️  警告: 无法访问邮箱账户信息: %v\n", err)
+		fmt.Println("   这可能是由于Outlook安全策略限制")
+		fmt.Println("   某些功能可能无法正常工作")
+	} else {
+		fmt.Println("✓ 可以访问邮箱账户信息")
+		accounts.Clear()
+	}
+
+	// 检查默认文件夹访问权限
+	inbox, err := oleutil.CallMethod(ol.namespace, "GetDefaultFolder", 6)
+	if err != nil {
+		fmt.Printf("⚠️  警告: 无法访问默认收件箱: %v\n", err)
+	} else {
+		fmt.Println("✓ 可以访问收件箱")
+		inbox.Clear()
+	}
+
+	fmt.Println("=== 安全检查完成 ===")
+	fmt.Println()
+}
+
+func (ol *OutlookSource) listAvailableAccounts() error {
+	fmt.Println("正在获取邮箱账户列表...")
+
+	accounts, err := oleutil.GetProperty(ol.namespace, "Accounts")
+	if err != nil {
+		fmt.Printf("⚠️  无法获取账户列表: %v\n", err)
+		fmt.Println("   可能原因:")
+		fmt.Println("   - Outlook安全策略限制")
+		fmt.Println("   - 需要用户确认访问权限")
+		fmt.Println("   - 邮箱配置文件未完全加载")
+		fmt.Println("\n建议:")
+		fmt.Println("   - 确保Outlook完全启动并登录所有账户")
+		fmt.Println("   - 检查是否有Outlook安全提示需要确认")
+		fmt.Println("   - 尝试在Outlook中手动发送一封测试邮件")
+		return err
+	}
+	defer accounts.Clear()
+
+	accountsDisp := accounts.ToIDispatch()
+	defer accountsDisp.Release()
+
+	count, err := oleutil.GetProperty(accountsDisp, "Count")
+	if err != nil {
+		return fmt.Errorf("无法获取账户数量: %v", err)
+	}
+
+	fmt.Printf("找到 %d 个邮箱账户:\n", int(count.Val))
+
+	if int(count.Val) == 0 {
+		fmt.Println("⚠️  未找到任何邮箱账户")
+		fmt.Println("   请确保:")
+		fmt.Println("   - Outlook中已配置邮箱账户")
+		fmt.Println("   - 所有账户都已成功连接")
+		fmt.Println("   - 没有未完成的登录流程")
+		return nil
+	}
+
+	for i := 1; i <= int(count.Val); i++ {
+		account, err := oleutil.GetProperty(accountsDisp, "Item", i)
+		if err != nil {
+			fmt.Printf("   %d. (无法访问账户信息)\n", i)
+			continue
+		}
+
+		accountDisp := account.ToIDispatch()
+
+		displayName, err := oleutil.GetProperty(accountDisp, "DisplayName")
+		if err == nil {
+			smtpAddress, err := oleutil.GetProperty(accountDisp, "SmtpAddress")
+			if err == nil {
+				fmt.Printf("   %d. %s (%s)\n", i, displayName.ToString(), smtpAddress.ToString())
+				smtpAddress.Clear()
+			} else {
+				fmt.Printf("   %d. %s (邮箱地址不可用)\n", i, displayName.ToString())
+			}
+			displayName.Clear()
+		} else {
+			fmt.Printf("   %d. (账户信息不可用)\n", i)
+		}
+
+		accountDisp.Release()
+		account.Clear()
+	}
+
+	return nil
+}
+
+func (ol *OutlookSource) getEmailAccount(emailAddress string) (*ole.IDispatch, error) {
+	fmt.Printf("正在查找邮箱账户: %s\n", emailAddress)
+
+	accounts, err := oleutil.GetProperty(ol.namespace, "Accounts")
+	if err != nil {
+		return nil, fmt.Errorf("无法访问账户列表: %v", err)
+	}
+	defer accounts.Clear()
+
+	accountsDisp := accounts.ToIDispatch()
+	defer accountsDisp.Release()
+
+	count, err := oleutil.GetProperty(accountsDisp, "Count")
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i <= int(count.Val); i++ {
+		account, err := oleutil.GetProperty(accountsDisp, "Item", i)
+		if err != nil {
+			continue
+		}
+
+		accountDisp := account.ToIDispatch()
+
+		smtpAddress, err := oleutil.GetProperty(accountDisp, "SmtpAddress")
+		if err == nil && strings.EqualFold(smtpAddress.ToString(), emailAddress) {
+			displayName, _ := oleutil.GetProperty(accountDisp, "DisplayName")
+			fmt.Printf("✓ 找到匹配的账户: %s\n", displayName.ToString())
+			displayName.Clear()
+			smtpAddress.Clear()
+			account.Clear()
+			return accountDisp, nil
+		}
+
+		if err == nil {
+			smtpAddress.Clear()
+		}
+		accountDisp.Release()
+		account.Clear()
+	}
+
+	fmt.Println("⚠️  未找到匹配的账户，将使用默认账户")
+	return nil, nil
+}
+
+func (ol *OutlookSource) getInboxFolders(emailAddress string) ([]*ole.IDispatch, error) {
+	var folders []*ole.IDispatch
+
+	// 尝试获取指定账户的收件箱
+	account, _ := ol.getEmailAccount(emailAddress)
+	var inbox *ole.IDispatch
+
+	if account != nil {
+		deliveryStore, err := oleutil.GetProperty(account, "DeliveryStore")
+		if err == nil {
+			storeDisp := deliveryStore.ToIDispatch()
+			inboxResult, err := oleutil.CallMethod(storeDisp, "GetDefaultFolder", 6) // olFolderInbox = 6
+			if err == nil {
+				inbox = inboxResult.ToIDispatch()
+				displayName, _ := oleutil.GetProperty(account, "DisplayName")
+				fmt.Printf("✓ 使用账户的收件箱: %s\n", displayName.ToString())
+				displayName.Clear()
+			} else {
+				fmt.Printf("⚠️  无法访问账户收件箱: %v\n", err)
+			}
+			storeDisp.Release()
+			deliveryStore.Clear()
+		}
+		account.Release()
+	}
+
+	// 如果账户收件箱失败，使用默认收件箱
+	if inbox == nil {
+		fmt.Println("尝试访问默认收件箱...")
+		inboxResult, err := oleutil.CallMethod(ol.namespace, "GetDefaultFolder", 6)
+		if err != nil {
+			return nil, fmt.Errorf(`无法获取收件箱: %v
+
+可能的原因:
+1. Outlook未完全加载邮箱数据
+2. 邮箱账户未正确配置
+3. 网络连接问题导致邮箱离线
+4. Outlook安全策略阻止程序访问
+
+建议解决方案:
+1. 确保Outlook完全启动并显示所有邮件
+2. 检查邮箱连接状态（文件 > 账户设置）
+3. 尝试在Outlook中手动刷新邮箱
+4. 重新启动Outlook后再运行此程序`, err)
+		}
+		inbox = inboxResult.ToIDispatch()
+		fmt.Println("✓ 使用默认收件箱")
+	}
+
+	folders = append(folders, inbox)
+
+	// 尝试获取子文件夹，如果失败也不影响主要功能
+	fmt.Println("正在查找子文件夹...")
+	ol.getSubfolders(inbox, &folders)
+
+	fmt.Printf("✓ 总共找到 %d 个文件夹\n", len(folders))
+	return folders, nil
+}
+
+func (ol *OutlookSource) getSubfolders(parentFolder *ole.IDispatch, folderList *[]*ole.IDispatch) {
+	foldersProperty, err := oleutil.GetProperty(parentFolder, "Folders")
+	if err != nil {
+		return
+	}
+	defer foldersProperty.Clear()
+
+	foldersDisp := foldersProperty.ToIDispatch()
+	defer foldersDisp.Release()
+
+	count, err := oleutil.GetProperty(foldersDisp, "Count")
+	if err != nil {
+		return
+	}
+
+	for i := 1; i <= int(count.Val); i++ {
+		folder, err := oleutil.GetProperty(foldersDisp, "Item", i)
+		if err != nil {
+			continue
+		}
+
+		folderDisp := folder.ToIDispatch()
+		*folderList = append(*folderList, folderDisp)
+		ol.getSubfolders(folderDisp, folderList)
+		folder.Clear()
+	}
+}
+
+func (ol *OutlookSource) getEmailsInDateRange(folders []*ole.IDispatch, startDate, endDate time.Time) ([]EmailInfo, error) {
+	var emails []EmailInfo
+
+	startStr := startDate.Format("01/02/2006")
+	endStr := endDate.AddDate(0, 0, 1).Format("01/02/2006")
+
+	fmt.Printf("正在分析 %d 个文件夹的邮件...\n", len(folders))
+
+	for folderIndex, folder := range folders {
+		folderName, err := oleutil.GetProperty(folder, "Name")
+		if err != nil {
+			fmt.Printf("文件夹 %d: (无法获取名称)\n", folderIndex+1)
+			continue
+		}
+
+		fmt.Printf("正在读取文件夹 %d/%d: %s\n", folderIndex+1, len(folders), folderName.ToString())
+
+		items, err := oleutil.GetProperty(folder, "Items")
+		if err != nil {
+			fmt.Printf("  ⚠️  无法访问文件夹内容: %v\n", err)
+			folderName.Clear()
+			continue
+		}
+
+		itemsDisp := items.ToIDispatch()
+
+		// 尝试使用过滤器来提高性能
+		filterStr := fmt.Sprintf("[ReceivedTime] >= '%s' AND [ReceivedTime] < '%s'", startStr, endStr)
+		filteredItems, err := oleutil.CallMethod(itemsDisp, "Restrict", filterStr)
+		var targetItems *ole.IDispatch
+
+		if err == nil {
+			targetItems = filteredItems.ToIDispatch()
+			fmt.Printf("  ✓ 使用日期过滤器\n")
+		} else {
+			fmt.Printf("  ⚠️  过滤器失败，将手动检查所有邮件: %v\n", err)
+			targetItems = itemsDisp
+		}
+
+		count, err := oleutil.GetProperty(targetItems, "Count")
+		if err == nil {
+			folderCount := 0
+			totalCount := int(count.Val)
+			fmt.Printf("  处理 %d 封邮件...\n", totalCount)
+
+			for i := 1; i <= totalCount; i++ {
+				if i%50 == 0 {
+					fmt.Printf("  进度: %d/%d (%.1f%%)\n", i, totalCount, float64(i)/float64(totalCount)*100)
+				}
+
+				item, err := oleutil.GetProperty(targetItems, "Item", i)
+				if err != nil {
+					continue
+				}
+
+				itemDisp := item.ToIDispatch()
+				emailInfo := ol.extractEmailInfo(itemDisp, false, startDate, endDate)
+				if emailInfo.Subject != "" {
+					emailInfo.Folder = folderName.ToString()
+					emails = append(emails, emailInfo)
+					folderCount++
+				}
+
+				itemDisp.Release()
+				item.Clear()
+			}
+			fmt.Printf("  ✓ 找到 %d 封符合条件的邮件\n", folderCount)
+		} else {
+			fmt.Printf("  ⚠️  无法获取邮件数量: %v\n", err)
+		}
+
+		if filteredItems != nil {
+			targetItems.Release()
+			filteredItems.Clear()
+		}
+		itemsDisp.Release()
+		items.Clear()
+		folderName.Clear()
+	}
+
+	fmt.Printf("✓ 总共找到 %d 封邮件\n", len(emails))
+	return emails, nil
+}
+
+func (ol *OutlookSource) getSentEmailsInDateRange(emailAddress string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	fmt.Println("正在获取发送邮件...")
+
+	var sentFolder *ole.IDispatch
+
+	// 尝试获取指定账户的发送文件夹
+	account, _ := ol.getEmailAccount(emailAddress)
+	if account != nil {
+		deliveryStore, err := oleutil.GetProperty(account, "DeliveryStore")
+		if err == nil {
+			storeDisp := deliveryStore.ToIDispatch()
+			sentResult, err := oleutil.CallMethod(storeDisp, "GetDefaultFolder", 5) // olFolderSentMail = 5
+			if err == nil {
+				sentFolder = sentResult.ToIDispatch()
+				displayName, _ := oleutil.GetProperty(account, "DisplayName")
+				fmt.Printf("✓ 使用账户 %s 的发送文件夹\n", displayName.ToString())
+				displayName.Clear()
+			} else {
+				fmt.Printf("⚠️  无法访问账户发送文件夹: %v\n", err)
+			}
+			storeDisp.Release()
+			deliveryStore.Clear()
+		}
+		account.Release()
+	}
+
+	// 如果失败，使用默认发送文件夹
+	if sentFolder == nil {
+		sentResult, err := oleutil.CallMethod(ol.namespace, "GetDefaultFolder", 5)
+		if err != nil {
+			fmt.Printf("⚠️  无法访问发送文件夹: %v\n", err)
+			fmt.Println("   跳过发送邮件分析，继续其他功能...")
+			return []EmailInfo{}, nil
+		}
+		sentFolder = sentResult.ToIDispatch()
+		fmt.Println("✓ 使用默认发送文件夹")
+	}
+	defer sentFolder.Release()
+
+	var sentEmails []EmailInfo
+	startStr := startDate.Format("01/02/2006")
+	endStr := endDate.AddDate(0, 0, 1).Format("01/02/2006")
+
+	items, err := oleutil.GetProperty(sentFolder, "Items")
+	if err != nil {
+		return sentEmails, fmt.Errorf("读取发送邮件失败: %v", err)
+	}
+	defer items.Clear()
+
+	itemsDisp := items.ToIDispatch()
+	defer itemsDisp.Release()
+
+	count, err := oleutil.GetProperty(itemsDisp, "Count")
+	if err == nil {
+		totalCount := int(count.Val)
+		fmt.Printf("发送文件夹中总共有 %d 封邮件\n", totalCount)
+
+		// 尝试使用过滤器
+		filterStr := fmt.Sprintf("[SentOn] >= '%s' AND [SentOn] < '%s'", startStr, endStr)
+		filteredItems, err := oleutil.CallMethod(itemsDisp, "Restrict", filterStr)
+
+		var targetItems *ole.IDispatch
+		if err == nil {
+			targetItems = filteredItems.ToIDispatch()
+			defer targetItems.Release()
+			defer filteredItems.Clear()
+
+			filteredCount, _ := oleutil.GetProperty(targetItems, "Count")
+			fmt.Printf("✓ 过滤后有 %d 封邮件\n", int(filteredCount.Val))
+		} else {
+			fmt.Printf("⚠️  过滤失败，手动遍历: %v\n", err)
+			targetItems = itemsDisp
+		}
+
+		itemCount, _ := oleutil.GetProperty(targetItems, "Count")
+		processCount := int(itemCount.Val)
+
+		for i := 1; i <= processCount; i++ {
+			if i%20 == 0 {
+				fmt.Printf("处理发送邮件进度: %d/%d\n", i, processCount)
+			}
+
+			item, err := oleutil.GetProperty(targetItems, "Item", i)
+			if err != nil {
+				continue
+			}
+
+			itemDisp := item.ToIDispatch()
+			emailInfo := ol.extractEmailInfo(itemDisp, true, startDate, endDate)
+			if emailInfo.Subject != "" {
+				sentEmails = append(sentEmails, emailInfo)
+			}
+
+			itemDisp.Release()
+			item.Clear()
+		}
+	}
+
+	fmt.Printf("✓ 找到 %d 封发送邮件\n", len(sentEmails))
+	return sentEmails, nil
+}
+
+func (ol *OutlookSource) extractEmailInfo(item *ole.IDispatch, isSent bool, startDate, endDate time.Time) EmailInfo {
+	var emailInfo EmailInfo
+
+	// 获取主题
+	subject, err := oleutil.GetProperty(item, "Subject")
+	if err == nil {
+		emailInfo.Subject = subject.ToString()
+	}
+	subject.Clear()
+
+	// 获取时间并验证日期范围
+	if isSent {
+		sentOn, err := oleutil.GetProperty(item, "SentOn")
+		if err == nil {
+			emailInfo.SentTime = variantTime(sentOn)
+			// 验证日期范围
+			if emailInfo.SentTime.Before(startDate) || emailInfo.SentTime.After(endDate.AddDate(0, 0, 1)) {
+				sentOn.Clear()
+				return EmailInfo{} // 返回空结构体表示不符合条件
+			}
+		}
+		sentOn.Clear()
+	} else {
+		receivedTime, err := oleutil.GetProperty(item, "ReceivedTime")
+		if err == nil {
+			emailInfo.ReceivedTime = variantTime(receivedTime)
+			// 验证日期范围
+			if emailInfo.ReceivedTime.Before(startDate) || emailInfo.ReceivedTime.After(endDate.AddDate(0, 0, 1)) {
+				receivedTime.Clear()
+				return EmailInfo{} // 返回空结构体表示不符合条件
+			}
+		}
+		receivedTime.Clear()
+
+		// 获取已读状态
+		unread, err := oleutil.GetProperty(item, "UnRead")
+		if err == nil {
+			emailInfo.IsRead = !variantBool(unread)
+		}
+		unread.Clear()
+	}
+
+	// 获取发件人信息。发送的邮件同样读取，反向匹配对方的回复时需要比较发件人
+	senderEmail, err := oleutil.GetProperty(item, "SenderEmailAddress")
+	if err == nil {
+		emailInfo.SenderEmail = senderEmail.ToString()
+	}
+	senderEmail.Clear()
+
+	// Exchange内部发件人的地址是X.500格式（/O=.../CN=...），需要转换为SMTP地址
+	if isExchangeDN(emailInfo.SenderEmail) {
+		emailInfo.SenderEmail = ol.exchangeSMTPAddress(item, "Sender", emailInfo.SenderEmail)
+	}
+
+	senderName, err := oleutil.GetProperty(item, "SenderName")
+	if err == nil {
+		emailInfo.SenderName = senderName.ToString()
+	}
+	senderName.Clear()
+
+	// 获取收件人信息：To/CC属性只有分号分隔的显示名，地址和类型从Recipients集合读取
+	to, err := oleutil.GetProperty(item, "To")
+	if err == nil {
+		emailInfo.To = to.ToString()
+	}
+	to.Clear()
+
+	cc, err := oleutil.GetProperty(item, "CC")
+	if err == nil {
+		emailInfo.CC = cc.ToString()
+	}
+	cc.Clear()
+
+	emailInfo.Recipients = ol.extractRecipients(item)
+
+	// 尝试获取邮件正文（可能比较慢，所以可以选择跳过）
+	// 为了提高性能，只保留开头部分用于分类（按字符截断，不会截断多字节字符）
+	body, err := oleutil.GetProperty(item, "Body")
+	if err == nil {
+		emailInfo.Body = truncateBody(body.ToString())
+	}
+	body.Clear()
+
+	// 邮件类型用于识别自动回复（IPM.Note.Rules.OofTemplate）和退信、回执（REPORT.*）
+	messageClass, err := oleutil.GetProperty(item, "MessageClass")
+	if err == nil {
+		emailInfo.MessageClass = messageClass.ToString()
+	}
+	messageClass.Clear()
+
+	ol.extractThreadInfo(item, &emailInfo)
+
+	return emailInfo
+}
+
+// extractRecipients 读取邮件的Recipients集合，Exchange内部收件人的DN转换为SMTP地址
+func (ol *OutlookSource) extractRecipients(item *ole.IDispatch) []Recipient {
+	recipientsVar, err := oleutil.GetProperty(item, "Recipients")
+	if err != nil {
+		return nil
+	}
+	collection := recipientsVar.ToIDispatch()
+	if collection == nil {
+		return nil
+	}
+	defer collection.Release()
+
+	countVar, err := oleutil.GetProperty(collection, "Count")
+	if err != nil {
+		return nil
+	}
+	count := int(countVar.Val)
+	countVar.Clear()
+
+	var recipients []Recipient
+	// Outlook集合的索引从1开始
+	for i := 1; i <= count; i++ {
+		itemVar, err := oleutil.CallMethod(collection, "Item", i)
+		if err != nil {
+			continue
+		}
+		recipient := itemVar.ToIDispatch()
+		if recipient == nil {
+			continue
+		}
+
+		var r Recipient
+		if name, err := oleutil.GetProperty(recipient, "Name"); err == nil {
+			r.Name = name.ToString()
+			name.Clear()
+		}
+		if address, err := oleutil.GetProperty(recipient, "Address"); err == nil {
+			r.Address = address.ToString()
+			address.Clear()
+		}
+		if kind, err := oleutil.GetProperty(recipient, "Type"); err == nil {
+			r.Type = RecipientType(kind.Val)
+			kind.Clear()
+		}
+		if resolved, err := oleutil.GetProperty(recipient, "Resolved"); err == nil {
+			r.Resolved = variantBool(resolved)
+			resolved.Clear()
+		}
+		if displayType, err := oleutil.GetProperty(recipient, "DisplayType"); err == nil {
+			r.DistList = isDistListDisplayType(int32(displayType.Val))
+			displayType.Clear()
+		}
+		if isExchangeDN(r.Address) {
+			r.Address = ol.exchangeSMTPAddress(recipient, "AddressEntry", r.Address)
+		}
+		recipient.Release()
+		recipients = append(recipients, r)
+	}
+	return recipients
+}
+
+// currentUser 从Session.CurrentUser.AddressEntry读取默认账户的显示名和地址，Exchange账户的DN转换为SMTP地址
+func (ol *OutlookSource) currentUser() (string, string) {
+	userVar, err := oleutil.GetProperty(ol.namespace, "CurrentUser")
+	if err != nil {
+		return "", ""
+	}
+	user := userVar.ToIDispatch()
+	if user == nil {
+		return "", ""
+	}
+	defer user.Release()
+
+	entryVar, err := oleutil.GetProperty(user, "AddressEntry")
+	if err != nil {
+		return "", ""
+	}
+	entry := entryVar.ToIDispatch()
+	if entry == nil {
+		return "", ""
+	}
+	defer entry.Release()
+
+	var name, address string
+	if value, err := oleutil.GetProperty(entry, "Name"); err == nil {
+		name = value.ToString()
+		value.Clear()
+	}
+	if value, err := oleutil.GetProperty(entry, "Address"); err == nil {
+		address = value.ToString()
+		value.Clear()
+	}
+	if isExchangeDN(address) {
+		address = ol.exchangeSMTPAddress(user, "AddressEntry", address)
+	}
+	return name, address
+}
+
+// exchangeSMTPAddress 通过owner的地址条目属性（邮件的Sender或收件人的AddressEntry）
+// .GetExchangeUser().PrimarySmtpAddress取SMTP地址，同一DN只查询一次。
+// 无法解析时（如脱机、不在全局地址列表中）返回原DN
+func (ol *OutlookSource) exchangeSMTPAddress(owner *ole.IDispatch, property, dn string) string {
+	key := strings.ToLower(dn)
+	smtp, cached := ol.smtpAddresses[key]
+	if !cached {
+		smtp = ol.lookupExchangeUser(owner, property)
+		ol.smtpAddresses[key] = smtp
+	}
+	if smtp == "" {
+		return dn
+	}
+	return smtp
+}
+
+func (ol *OutlookSource) lookupExchangeUser(owner *ole.IDispatch, property string) string {
+	entryVar, err := oleutil.GetProperty(owner, property)
+	if err != nil {
+		return ""
+	}
+	entry := entryVar.ToIDispatch()
+	if entry == nil {
+		return ""
+	}
+	defer entry.Release()
+
+	userVar, err := oleutil.CallMethod(entry, "GetExchangeUser")
+	if err != nil {
+		return ""
+	}
+	user := userVar.ToIDispatch()
+	if user == nil {
+		return ""
+	}
+	defer user.Release()
+
+	address, err := oleutil.GetProperty(user, "PrimarySmtpAddress")
+	if err != nil {
+		return ""
+	}
+	defer address.Clear()
+	return address.ToString()
+}
+
+// MAPI属性的DASL名称，通过PropertyAccessor读取邮件头中的会话信息
+const (
+	daslInternetMessageID  = "http://schemas.microsoft.com/mapi/proptag/0x1035001F"
+	daslInReplyToID        = "http://schemas.microsoft.com/mapi/proptag/0x1042001F"
+	daslInternetReferences = "http://schemas.microsoft.com/mapi/proptag/0x1039001F"
+	daslTransportHeaders   = "http://schemas.microsoft.com/mapi/proptag/0x007D001F"
+)
+
+// extractThreadInfo 读取会话ID、会话索引以及Message-ID、In-Reply-To、References，
+// 并从原始邮件头中读取邮件列表相关的头。
+// 这些属性在部分邮件（如会议请求、本地草稿）上不存在，读取失败时忽略
+func (ol *OutlookSource) extractThreadInfo(item *ole.IDispatch, emailInfo *EmailInfo) {
+	conversationID, err := oleutil.GetProperty(item, "ConversationID")
+	if err == nil {
+		emailInfo.ConversationID = strings.ToUpper(conversationID.ToString())
+	}
+	conversationID.Clear()
+
+	// ConversationIndex 是会话索引的十六进制字符串
+	conversationIndex, err := oleutil.GetProperty(item, "ConversationIndex")
+	if err == nil {
+		if index, err := hex.DecodeString(conversationIndex.ToString()); err == nil {
+			emailInfo.ConversationIndex = index
+		}
+	}
+	conversationIndex.Clear()
+
+	accessorVar, err := oleutil.GetProperty(item, "PropertyAccessor")
+	if err != nil {
+		return
+	}
+	accessor := accessorVar.ToIDispatch()
+	defer accessor.Release()
+
+	getString := func(schema string) string {
+		value, err := oleutil.CallMethod(accessor, "GetProperty", schema)
+		if err != nil {
+			return ""
+		}
+		defer value.Clear()
+		return value.ToString()
+	}
+	emailInfo.MessageID = firstMessageID(getString(daslInternetMessageID))
+	emailInfo.InReplyTo = firstMessageID(getString(daslInReplyToID))
+	emailInfo.References = parseMessageIDs(getString(daslInternetReferences))
+	header := parseTransportHeaders(getString(daslTransportHeaders))
+	applyListHeaders(emailInfo, header)
+	applyAutoHeaders(emailInfo, header)
+}
+
+func (ol *OutlookSource) ReceivedEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	// 获取收件箱文件夹
+	inboxFolders, err := ol.getInboxFolders(account)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, folder := range inboxFolders {
+			folder.Release()
+		}
+	}()
+
+	if len(inboxFolders) == 0 {
+		return nil, fmt.Errorf("无法获取收件箱文件夹")
+	}
+
+	return ol.getEmailsInDateRange(inboxFolders, startDate, endDate)
+}
+
+func (ol *OutlookSource) SentEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	return ol.getSentEmailsInDateRange(account, startDate, endDate)
+}
+
+// variantTime 将VT_DATE类型的VARIANT转换为time.Time
+func variantTime(v *ole.VARIANT) time.Time {
+	if t, ok := v.Value().(time.Time); ok {
+		return t
+	}
+	return time.Time{}
+}
+
+// variantBool 将VT_BOOL类型的VARIANT转换为bool
+func variantBool(v *ole.VARIANT) bool {
+	b, _ := v.Value().(bool)
+	return b
+}
@@ -0,0 +1,624 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// pstFile 是Outlook个人文件夹（.pst/.ost，Unicode格式）的只读实现，覆盖MS-PST中的
+// NDB层（节点和数据块B树）和LTP层（堆、BTH、属性上下文PC、表上下文TC）。
+// B树按需查找，不把整个索引读入内存，以便处理较大的存档文件
+type pstFile struct {
+	file    *os.File
+	crypt   byte
+	nbtRoot uint64 // 节点B树根页的文件偏移
+	bbtRoot uint64 // 数据块B树根页的文件偏移
+}
+
+// pstNode 是NBT中的节点，或SLBLOCK中的子节点
+type pstNode struct {
+	nid     uint32
+	bidData uint64
+	bidSub  uint64
+}
+
+const (
+	pstPageSize     = 512
+	pstMaxBlockSize = 8192
+	pstBlockTrailer = 16
+
+	pstPageTypeBBT = 0x80
+	pstPageTypeNBT = 0x81
+
+	pstCryptNone    = 0
+	pstCryptPermute = 1
+
+	// 堆上的客户端签名
+	pstHeapSigTC  = 0x7C
+	pstHeapSigBTH = 0xB5
+	pstHeapSigPC  = 0xBC
+
+	// 数据树为XXBLOCK→XBLOCK→数据块，子节点树为SIBLOCK→SLBLOCK。
+	// 损坏的文件中内部块可能互相引用，按层数限制递归
+	pstMaxDataTreeDepth = 2
+	pstMaxSubnodeDepth  = 1
+)
+
+// pstPermuteTable 是 "compressible encryption" (NDB_CRYPT_PERMUTE) 的解码表（MS-PST mpbbI）
+var pstPermuteTable = [256]byte{
+	0x47, 0xf1, 0xb4, 0xe6, 0x0b, 0x6a, 0x72, 0x48, 0x85, 0x4e, 0x9e, 0xeb, 0xe2, 0xf8, 0x94, 0x53,
+	0xe0, 0xbb, 0xa0, 0x02, 0xe8, 0x5a, 0x09, 0xab, 0xdb, 0xe3, 0xba, 0xc6, 0x7c, 0xc3, 0x10, 0xdd,
+	0x39, 0x05, 0x96, 0x30, 0xf5, 0x37, 0x60, 0x82, 0x8c, 0xc9, 0x13, 0x4a, 0x6b, 0x1d, 0xf3, 0xfb,
+	0x8f, 0x26, 0x97, 0xca, 0x91, 0x17, 0x01, 0xc4, 0x32, 0x2d, 0x6e, 0x31, 0x95, 0xff, 0xd9, 0x23,
+	0xd1, 0x00, 0x5e, 0x79, 0xdc, 0x44, 0x3b, 0x1a, 0x28, 0xc5, 0x61, 0x57, 0x20, 0x90, 0x3d, 0x83,
+	0xb9, 0x43, 0xbe, 0x67, 0xd2, 0x46, 0x42, 0x76, 0xc0, 0x6d, 0x5b, 0x7e, 0xb2, 0x0f, 0x16, 0x29,
+	0x3c, 0xa9, 0x03, 0x54, 0x0d, 0xda, 0x5d, 0xdf, 0xf6, 0xb7, 0xc7, 0x62, 0xcd, 0x8d, 0x06, 0xd3,
+	0x69, 0x5c, 0x86, 0xd6, 0x14, 0xf7, 0xa5, 0x66, 0x75, 0xac, 0xb1, 0xe9, 0x45, 0x21, 0x70, 0x0c,
+	0x87, 0x9f, 0x74, 0xa4, 0x22, 0x4c, 0x6f, 0xbf, 0x1f, 0x56, 0xaa, 0x2e, 0xb3, 0x78, 0x33, 0x50,
+	0xb0, 0xa3, 0x92, 0xbc, 0xcf, 0x19, 0x1c, 0xa7, 0x63, 0xcb, 0x1e, 0x4d, 0x3e, 0x4b, 0x1b, 0x9b,
+	0x4f, 0xe7, 0xf0, 0xee, 0xad, 0x3a, 0xb5, 0x59, 0x04, 0xea, 0x40, 0x55, 0x25, 0x51, 0xe5, 0x7a,
+	0x89, 0x38, 0x68, 0x52, 0x7b, 0xfc, 0x27, 0xae, 0xd7, 0xbd, 0xfa, 0x07, 0xf4, 0xcc, 0x8e, 0x5f,
+	0xef, 0x35, 0x9c, 0x84, 0x2b, 0x15, 0xd5, 0x77, 0x34, 0x49, 0xb6, 0x12, 0x0a, 0x7f, 0x71, 0x88,
+	0xfd, 0x9d, 0x18, 0x41, 0x7d, 0x93, 0xd8, 0x58, 0x2c, 0xce, 0xfe, 0x24, 0xaf, 0xde, 0xb8, 0x36,
+	0xc8, 0xa1, 0x80, 0xa6, 0x99, 0x98, 0xa8, 0x2f, 0x0e, 0x81, 0x65, 0x73, 0xe4, 0xc2, 0xa2, 0x8a,
+	0xd4, 0xe1, 0x11, 0xd0, 0x08, 0x8b, 0x2a, 0xf2, 0xed, 0x9a, 0x64, 0x3f, 0xc1, 0x6c, 0xf9, 0xec,
+}
+
+func openPST(path string) (*pstFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 564)
+	if _, err := io.ReadFull(file, header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("读取文件头失败: %v", err)
+	}
+	if string(header[0:4]) != "!BDN" || string(header[8:10]) != "SM" {
+		file.Close()
+		return nil, fmt.Errorf("不是PST/OST文件")
+	}
+
+	version := binary.LittleEndian.Uint16(header[10:])
+	switch {
+	case version == 14 || version == 15:
+		file.Close()
+		return nil, fmt.Errorf("不支持ANSI格式的PST文件（Outlook 2002及更早版本），请在Outlook中另存为Unicode格式")
+	case version == 36:
+		file.Close()
+		return nil, fmt.Errorf("不支持4K页面格式的OST文件")
+	case version < 23:
+		file.Close()
+		return nil, fmt.Errorf("未知的PST版本: %d", version)
+	}
+
+	pst := &pstFile{
+		file:  file,
+		crypt: header[513],
+		// ROOT结构位于偏移180，其中BREFNBT和BREFBBT的ib字段分别在224和240
+		nbtRoot: binary.LittleEndian.Uint64(header[224:]),
+		bbtRoot: binary.LittleEndian.Uint64(header[240:]),
+	}
+	if pst.crypt != pstCryptNone && pst.crypt != pstCryptPermute {
+		file.Close()
+		return nil, fmt.Errorf("不支持的PST加密方式: %d（仅支持无加密和可压缩加密）", pst.crypt)
+	}
+	return pst, nil
+}
+
+func (p *pstFile) Close() error {
+	return p.file.Close()
+}
+
+func (p *pstFile) readAt(off uint64, size int) ([]byte, error) {
+	buf := make([]byte, size)
+	if _, err := p.file.ReadAt(buf, int64(off)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// lookupBTree 在NBT或BBT中查找键，返回叶子记录。中间层选择键不大于目标的最后一项
+func (p *pstFile) lookupBTree(pageOffset uint64, pageType byte, key uint64) ([]byte, error) {
+	for depth := 0; depth < 16; depth++ {
+		page, err := p.readAt(pageOffset, pstPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("读取B树页失败: %v", err)
+		}
+		if page[496] != pageType {
+			return nil, fmt.Errorf("B树页类型错误: 0x%02X", page[496])
+		}
+
+		count := int(page[488])
+		entrySize := int(page[490])
+		level := page[491]
+		// 叶子和中间页的记录都至少有24字节（BBTENTRY、BTENTRY；NBTENTRY为32字节）
+		if entrySize < 24 || count*entrySize > 488 {
+			return nil, fmt.Errorf("B树页损坏")
+		}
+
+		if level == 0 {
+			for i := 0; i < count; i++ {
+				entry := page[i*entrySize : (i+1)*entrySize]
+				if pstKeyEqual(binary.LittleEndian.Uint64(entry), key, pageType) {
+					return entry, nil
+				}
+			}
+			return nil, fmt.Errorf("未找到键 0x%X", key)
+		}
+
+		next := -1
+		for i := 0; i < count; i++ {
+			entryKey := binary.LittleEndian.Uint64(page[i*entrySize:])
+			if pstKeyCompare(entryKey, key, pageType) > 0 {
+				break
+			}
+			next = i
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("未找到键 0x%X", key)
+		}
+		// BTENTRY: btkey(8) + BREF{bid(8), ib(8)}
+		pageOffset = binary.LittleEndian.Uint64(page[next*entrySize+16:])
+	}
+	return nil, fmt.Errorf("B树层级过深")
+}
+
+// NID只使用低32位；BID的最低位保留，比较时忽略
+func pstKeyCompare(a, b uint64, pageType byte) int {
+	if pageType == pstPageTypeNBT {
+		a, b = a&0xFFFFFFFF, b&0xFFFFFFFF
+	} else {
+		a, b = a&^1, b&^1
+	}
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func pstKeyEqual(a, b uint64, pageType byte) bool {
+	return pstKeyCompare(a, b, pageType) == 0
+}
+
+func (p *pstFile) node(nid uint32) (pstNode, error) {
+	entry, err := p.lookupBTree(p.nbtRoot, pstPageTypeNBT, uint64(nid))
+	if err != nil {
+		return pstNode{}, fmt.Errorf("节点 0x%X: %v", nid, err)
+	}
+	// NBTENTRY: nid(8) bidData(8) bidSub(8) nidParent(4)
+	return pstNode{
+		nid:     nid,
+		bidData: binary.LittleEndian.Uint64(entry[8:]),
+		bidSub:  binary.LittleEndian.Uint64(entry[16:]),
+	}, nil
+}
+
+// readBlock 读取单个数据块，外部数据块按文件的加密方式解码
+func (p *pstFile) readBlock(bid uint64) ([]byte, error) {
+	entry, err := p.lookupBTree(p.bbtRoot, pstPageTypeBBT, bid)
+	if err != nil {
+		return nil, fmt.Errorf("数据块 0x%X: %v", bid, err)
+	}
+	// BBTENTRY: BREF{bid(8), ib(8)} cb(2) cRef(2)
+	offset := binary.LittleEndian.Uint64(entry[8:])
+	size := int(binary.LittleEndian.Uint16(entry[16:]))
+	if size > pstMaxBlockSize-pstBlockTrailer {
+		return nil, fmt.Errorf("数据块 0x%X 大小异常: %d", bid, size)
+	}
+
+	data, err := p.readAt(offset, size)
+	if err != nil {
+		return nil, err
+	}
+	if !pstInternalBID(bid) && p.crypt == pstCryptPermute {
+		for i, b := range data {
+			data[i] = pstPermuteTable[b]
+		}
+	}
+	return data, nil
+}
+
+// 内部数据块（XBLOCK、SLBLOCK等）的BID设置了第二位
+func pstInternalBID(bid uint64) bool {
+	return bid&0x2 != 0
+}
+
+// readNodeData 读取节点数据对应的全部数据块。内部块为XBLOCK/XXBLOCK，
+// 按顺序展开为叶子数据块；堆和表按数据块边界组织，因此不拼接
+func (p *pstFile) readNodeData(bid uint64) ([][]byte, error) {
+	return p.readDataTree(bid, pstMaxDataTreeDepth)
+}
+
+func (p *pstFile) readDataTree(bid uint64, depth int) ([][]byte, error) {
+	if bid == 0 {
+		return nil, nil
+	}
+	data, err := p.readBlock(bid)
+	if err != nil {
+		return nil, err
+	}
+	if !pstInternalBID(bid) {
+		return [][]byte{data}, nil
+	}
+	if depth == 0 {
+		return nil, fmt.Errorf("数据块 0x%X 层级过深", bid)
+	}
+
+	// XBLOCK: btype(1)=0x01 cLevel(1) cEnt(2) lcbTotal(4) rgbid[cEnt]
+	if len(data) < 8 || data[0] != 0x01 {
+		return nil, fmt.Errorf("数据块 0x%X 不是XBLOCK", bid)
+	}
+	count := int(binary.LittleEndian.Uint16(data[2:]))
+	var blocks [][]byte
+	for i := 0; i < count && 8+i*8+8 <= len(data); i++ {
+		child := binary.LittleEndian.Uint64(data[8+i*8:])
+		childBlocks, err := p.readDataTree(child, depth-1)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, childBlocks...)
+	}
+	return blocks, nil
+}
+
+func (p *pstFile) readNodeBytes(bid uint64) ([]byte, error) {
+	blocks, err := p.readNodeData(bid)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, block...)
+	}
+	return data, nil
+}
+
+// subnodes 读取SLBLOCK/SIBLOCK子节点树
+func (p *pstFile) subnodes(bid uint64) (map[uint32]pstNode, error) {
+	result := make(map[uint32]pstNode)
+	if bid == 0 {
+		return result, nil
+	}
+	err := p.collectSubnodes(bid, result, pstMaxSubnodeDepth)
+	return result, err
+}
+
+func (p *pstFile) collectSubnodes(bid uint64, result map[uint32]pstNode, depth int) error {
+	data, err := p.readBlock(bid)
+	if err != nil {
+		return err
+	}
+	// SLBLOCK/SIBLOCK: btype(1)=0x02 cLevel(1) cEnt(2) dwPadding(4) rgentries
+	if len(data) < 8 || data[0] != 0x02 {
+		return fmt.Errorf("数据块 0x%X 不是子节点块", bid)
+	}
+	level := int(data[1])
+	if level > depth {
+		return fmt.Errorf("子节点块 0x%X 层级过深", bid)
+	}
+	count := int(binary.LittleEndian.Uint16(data[2:]))
+
+	if level == 0 {
+		// SLENTRY: nid(8) bidData(8) bidSub(8)
+		for i := 0; i < count && 8+(i+1)*24 <= len(data); i++ {
+			entry := data[8+i*24:]
+			nid := uint32(binary.LittleEndian.Uint64(entry))
+			result[nid] = pstNode{
+				nid:     nid,
+				bidData: binary.LittleEndian.Uint64(entry[8:]),
+				bidSub:  binary.LittleEndian.Uint64(entry[16:]),
+			}
+		}
+		return nil
+	}
+
+	// SIENTRY: nid(8) bid(8)
+	for i := 0; i < count && 8+(i+1)*16 <= len(data); i++ {
+		if err := p.collectSubnodes(binary.LittleEndian.Uint64(data[8+i*16+8:]), result, level-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pstHeap 是节点上的堆（Heap-on-Node），PC和TC都建立在堆之上
+type pstHeap struct {
+	file      *pstFile
+	blocks    [][]byte
+	clientSig byte
+	userRoot  uint32
+	subnodes  map[uint32]pstNode
+}
+
+func (p *pstFile) openHeap(node pstNode) (*pstHeap, error) {
+	blocks, err := p.readNodeData(node.bidData)
+	if err != nil {
+		return nil, err
+	}
+	// HNHDR: ibHnpm(2) bSig(1)=0xEC bClientSig(1) hidUserRoot(4)
+	if len(blocks) == 0 || len(blocks[0]) < 12 || blocks[0][2] != 0xEC {
+		return nil, fmt.Errorf("节点 0x%X 不是堆", node.nid)
+	}
+	subnodes, err := p.subnodes(node.bidSub)
+	if err != nil {
+		return nil, err
+	}
+	return &pstHeap{
+		file:      p,
+		blocks:    blocks,
+		clientSig: blocks[0][3],
+		userRoot:  binary.LittleEndian.Uint32(blocks[0][4:]),
+		subnodes:  subnodes,
+	}, nil
+}
+
+// item 按HID取出堆上的分配项。HID: hidType(5位) hidIndex(11位，从1开始) hidBlockIndex(16位)
+func (h *pstHeap) item(hid uint32) ([]byte, error) {
+	if hid == 0 {
+		return nil, nil
+	}
+	blockIndex := int(hid >> 16)
+	index := int((hid >> 5) & 0x7FF)
+	if blockIndex >= len(h.blocks) || index == 0 {
+		return nil, fmt.Errorf("无效的HID 0x%X", hid)
+	}
+
+	block := h.blocks[blockIndex]
+	if len(block) < 2 {
+		return nil, fmt.Errorf("堆数据块过短")
+	}
+	pageMap := int(binary.LittleEndian.Uint16(block))
+	if pageMap+4 > len(block) {
+		return nil, fmt.Errorf("堆页映射损坏")
+	}
+	// HNPAGEMAP: cAlloc(2) cFree(2) rgibAlloc[cAlloc+1]
+	allocCount := int(binary.LittleEndian.Uint16(block[pageMap:]))
+	if index > allocCount || pageMap+4+(index+1)*2 > len(block) {
+		return nil, fmt.Errorf("无效的HID 0x%X", hid)
+	}
+	start := int(binary.LittleEndian.Uint16(block[pageMap+4+(index-1)*2:]))
+	end := int(binary.LittleEndian.Uint16(block[pageMap+4+index*2:]))
+	if start > end || end > len(block) {
+		return nil, fmt.Errorf("堆分配项越界")
+	}
+	return block[start:end], nil
+}
+
+// value 解析HNID：低5位为0时是堆上的HID，否则是子节点NID（较大的属性值存放在子节点中）
+func (h *pstHeap) value(hnid uint32) ([]byte, error) {
+	if hnid&0x1F == 0 {
+		return h.item(hnid)
+	}
+	sub, ok := h.subnodes[hnid]
+	if !ok {
+		return nil, fmt.Errorf("未找到子节点 0x%X", hnid)
+	}
+	return h.file.readNodeBytes(sub.bidData)
+}
+
+// bthRecords 读取堆上的BTH（BTree-on-Heap），返回所有叶子记录（键+数据）
+func (h *pstHeap) bthRecords(hidHeader uint32) (keySize, dataSize int, records [][]byte, err error) {
+	header, err := h.item(hidHeader)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	// BTHHEADER: bType(1)=0xB5 cbKey(1) cbEnt(1) bIdxLevels(1) hidRoot(4)
+	if len(header) < 8 || header[0] != pstHeapSigBTH {
+		return 0, 0, nil, fmt.Errorf("BTH头损坏")
+	}
+	keySize = int(header[1])
+	dataSize = int(header[2])
+	levels := int(header[3])
+	root := binary.LittleEndian.Uint32(header[4:])
+	if keySize+dataSize == 0 {
+		return 0, 0, nil, fmt.Errorf("BTH记录大小为0")
+	}
+
+	// 每个索引项只能出现一次，损坏的索引可能指回上层
+	visited := make(map[uint32]bool)
+	var walk func(hid uint32, level int) error
+	walk = func(hid uint32, level int) error {
+		if visited[hid] {
+			return fmt.Errorf("BTH索引存在循环: HID 0x%X", hid)
+		}
+		visited[hid] = true
+		data, err := h.item(hid)
+		if err != nil {
+			return err
+		}
+		if level == 0 {
+			size := keySize + dataSize
+			for off := 0; off+size <= len(data); off += size {
+				records = append(records, data[off:off+size])
+			}
+			return nil
+		}
+		// 索引记录: key(cbKey) + hidNextLevel(4)
+		size := keySize + 4
+		for off := 0; off+size <= len(data); off += size {
+			if err := walk(binary.LittleEndian.Uint32(data[off+keySize:]), level-1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if root != 0 {
+		err = walk(root, levels)
+	}
+	return keySize, dataSize, records, err
+}
+
+// pstPropSize 返回定长属性类型的字节数，变长类型返回0
+func pstPropSize(propType uint16) int {
+	switch propType {
+	case 0x0002: // PtypInteger16
+		return 2
+	case 0x0003, 0x0004, 0x000A: // PtypInteger32, PtypFloating32, PtypErrorCode
+		return 4
+	case 0x000B: // PtypBoolean
+		return 1
+	case 0x0005, 0x0006, 0x0007, 0x0014, ptSysTime: // PtypFloating64, PtypCurrency, PtypFloatingTime, PtypInteger64, PtypTime
+		return 8
+	}
+	return 0
+}
+
+// propertyContext 读取属性上下文（PC），结果与.msg的属性集合格式相同
+func (h *pstHeap) propertyContext() (mapiProps, error) {
+	props := mapiProps{values: make(map[uint32][]byte)}
+	if h.clientSig != pstHeapSigPC {
+		return props, fmt.Errorf("堆不是属性上下文")
+	}
+
+	_, _, records, err := h.bthRecords(h.userRoot)
+	if err != nil {
+		return props, err
+	}
+	// PC记录: wPropId(2) wPropType(2) dwValueHnid(4)
+	for _, record := range records {
+		if len(record) < 8 {
+			continue
+		}
+		propID := binary.LittleEndian.Uint16(record)
+		propType := binary.LittleEndian.Uint16(record[2:])
+		tag := uint32(propID)<<16 | uint32(propType)
+
+		size := pstPropSize(propType)
+		if size > 0 && size <= 4 {
+			props.values[tag] = record[4:8]
+			continue
+		}
+		value, err := h.value(binary.LittleEndian.Uint32(record[4:]))
+		if err != nil {
+			continue
+		}
+		props.values[tag] = value
+	}
+
+	if cp, ok := props.int32(prInternetCodepage); ok {
+		props.codepage = int(cp)
+	} else if cp, ok := props.int32(prMessageCodepage); ok {
+		props.codepage = int(cp)
+	}
+	return props, nil
+}
+
+type pstColumn struct {
+	tag    uint32
+	offset int
+	size   int
+	bit    int
+}
+
+// tableContext 读取表上下文（TC），每行以属性集合形式返回
+func (h *pstHeap) tableContext() ([]mapiProps, error) {
+	if h.clientSig != pstHeapSigTC {
+		return nil, fmt.Errorf("堆不是表上下文")
+	}
+	info, err := h.item(h.userRoot)
+	if err != nil {
+		return nil, err
+	}
+	// TCINFO: bType(1) cCols(1) rgib[4](2) hidRowIndex(4) hnidRows(4) hidIndex(4) rgTCOLDESC
+	if len(info) < 22 || info[0] != pstHeapSigTC {
+		return nil, fmt.Errorf("表头损坏")
+	}
+	columnCount := int(info[1])
+	cebOffset := int(binary.LittleEndian.Uint16(info[6:]))
+	rowSize := int(binary.LittleEndian.Uint16(info[8:]))
+	rowIndex := binary.LittleEndian.Uint32(info[10:])
+	rowsHnid := binary.LittleEndian.Uint32(info[14:])
+	// 每个数据块至少要容纳一行，否则按块计算行位置时会除零
+	if rowSize == 0 || rowSize > pstMaxBlockSize-pstBlockTrailer {
+		return nil, fmt.Errorf("表行大小异常: %d", rowSize)
+	}
+
+	var columns []pstColumn
+	for i := 0; i < columnCount && 22+(i+1)*8 <= len(info); i++ {
+		desc := info[22+i*8:]
+		// TCOLDESC: tag(4) ibData(2) cbData(1) iBit(1)
+		column := pstColumn{
+			tag:    binary.LittleEndian.Uint32(desc),
+			offset: int(binary.LittleEndian.Uint16(desc[4:])),
+			size:   int(desc[6]),
+			bit:    int(desc[7]),
+		}
+		// 变长属性的单元格是4字节的HNID
+		if pstPropSize(uint16(column.tag)) == 0 && column.size < 4 {
+			return nil, fmt.Errorf("表列 0x%08X 大小异常: %d", column.tag, column.size)
+		}
+		columns = append(columns, column)
+	}
+
+	_, _, indexRecords, err := h.bthRecords(rowIndex)
+	if err != nil {
+		return nil, err
+	}
+	rowCount := len(indexRecords)
+	if rowCount == 0 {
+		return nil, nil
+	}
+
+	// 行数据在堆上（HID）或子节点中；位于子节点时行不跨数据块
+	var rowBlocks [][]byte
+	if rowsHnid&0x1F == 0 {
+		data, err := h.item(rowsHnid)
+		if err != nil {
+			return nil, err
+		}
+		rowBlocks = [][]byte{data}
+	} else {
+		sub, ok := h.subnodes[rowsHnid]
+		if !ok {
+			return nil, fmt.Errorf("未找到行数据子节点 0x%X", rowsHnid)
+		}
+		rowBlocks, err = h.file.readNodeData(sub.bidData)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rowsPerBlock := (pstMaxBlockSize - pstBlockTrailer) / rowSize
+
+	var rows []mapiProps
+	for i := 0; i < rowCount; i++ {
+		blockIndex, offset := 0, i*rowSize
+		if len(rowBlocks) > 1 {
+			blockIndex, offset = i/rowsPerBlock, (i%rowsPerBlock)*rowSize
+		}
+		if blockIndex >= len(rowBlocks) || offset+rowSize > len(rowBlocks[blockIndex]) {
+			break
+		}
+		row := rowBlocks[blockIndex][offset : offset+rowSize]
+
+		props := mapiProps{values: make(map[uint32][]byte)}
+		for _, col := range columns {
+			// 单元格存在位图（CEB）中对应位为0表示该单元格没有值
+			if cebOffset+col.bit/8 >= len(row) || row[cebOffset+col.bit/8]&(0x80>>(col.bit%8)) == 0 {
+				continue
+			}
+			if col.offset+col.size > len(row) {
+				continue
+			}
+			cell := row[col.offset : col.offset+col.size]
+			propType := uint16(col.tag & 0xFFFF)
+			if size := pstPropSize(propType); size > 0 {
+				props.values[col.tag] = cell
+				continue
+			}
+			value, err := h.value(binary.LittleEndian.Uint32(cell))
+			if err != nil {
+				continue
+			}
+			props.values[col.tag] = value
+		}
+		rows = append(rows, props)
+	}
+	return rows, nil
+}
@@ -0,0 +1,740 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateFixtures = flag.Bool("update", false, "重新生成testdata中的测试文件")
+
+const pstFixture = "testdata/sample.pst"
+
+// testPSTBuilder 生成测试用的Unicode PST文件。B树叶子页只放少量记录，
+// 以便小文件也能生成多层B树
+type testPSTBuilder struct {
+	crypt   byte
+	leafCap int
+	nextBID uint64
+	blocks  []testPSTBlock
+	nodes   []pstNode
+}
+
+type testPSTBlock struct {
+	bid  uint64
+	data []byte
+}
+
+func newTestPSTBuilder(crypt byte) *testPSTBuilder {
+	return &testPSTBuilder{crypt: crypt, leafCap: 4, nextBID: 4}
+}
+
+// addBlock 添加一个数据块。内部块（XBLOCK、SLBLOCK）的BID设置第二位，且不加密
+func (b *testPSTBuilder) addBlock(data []byte, internal bool) uint64 {
+	bid := b.nextBID
+	b.nextBID += 4
+	if internal {
+		bid |= 0x2
+	}
+	b.blocks = append(b.blocks, testPSTBlock{bid: bid, data: data})
+	return bid
+}
+
+// addNode 把堆写入数据块，子节点写入SLBLOCK，然后登记到NBT
+func (b *testPSTBuilder) addNode(nid uint32, heap *testPSTHeap) {
+	b.nodes = append(b.nodes, b.heapNode(nid, heap))
+}
+
+func (b *testPSTBuilder) heapNode(nid uint32, heap *testPSTHeap) pstNode {
+	node := pstNode{nid: nid, bidData: b.addBlock(heap.bytes(), false)}
+	if len(heap.subnodes) > 0 {
+		sl := make([]byte, 8)
+		sl[0] = 0x02
+		binary.LittleEndian.PutUint16(sl[2:], uint16(len(heap.subnodes)))
+		for _, sub := range heap.subnodes {
+			entry := make([]byte, 24)
+			binary.LittleEndian.PutUint64(entry, uint64(sub.nid))
+			binary.LittleEndian.PutUint64(entry[8:], sub.bidData)
+			binary.LittleEndian.PutUint64(entry[16:], sub.bidSub)
+			sl = append(sl, entry...)
+		}
+		node.bidSub = b.addBlock(sl, true)
+	}
+	return node
+}
+
+func (b *testPSTBuilder) bytes() []byte {
+	var inverse [256]byte
+	for i, v := range pstPermuteTable {
+		inverse[v] = byte(i)
+	}
+
+	out := make([]byte, 1024)
+	var bbt [][]byte
+	for _, block := range b.blocks {
+		data := append([]byte(nil), block.data...)
+		if b.crypt == pstCryptPermute && !pstInternalBID(block.bid) {
+			for i, v := range data {
+				data[i] = inverse[v]
+			}
+		}
+		entry := make([]byte, 24)
+		binary.LittleEndian.PutUint64(entry, block.bid)
+		binary.LittleEndian.PutUint64(entry[8:], uint64(len(out)))
+		binary.LittleEndian.PutUint16(entry[16:], uint16(len(data)))
+		binary.LittleEndian.PutUint16(entry[18:], 1)
+		bbt = append(bbt, entry)
+
+		// 数据块加上16字节块尾，按64字节对齐
+		size := (len(data) + pstBlockTrailer + 63) / 64 * 64
+		padded := make([]byte, size)
+		copy(padded, data)
+		out = append(out, padded...)
+	}
+
+	nodes := append([]pstNode(nil), b.nodes...)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].nid < nodes[j].nid })
+	var nbt [][]byte
+	for _, node := range nodes {
+		entry := make([]byte, 32)
+		binary.LittleEndian.PutUint64(entry, uint64(node.nid))
+		binary.LittleEndian.PutUint64(entry[8:], node.bidData)
+		binary.LittleEndian.PutUint64(entry[16:], node.bidSub)
+		nbt = append(nbt, entry)
+	}
+
+	out, nbtRoot := b.writeBTree(out, pstPageTypeNBT, nbt)
+	out, bbtRoot := b.writeBTree(out, pstPageTypeBBT, bbt)
+
+	copy(out, "!BDN")
+	copy(out[8:], "SM")
+	binary.LittleEndian.PutUint16(out[10:], 23)
+	binary.LittleEndian.PutUint64(out[224:], nbtRoot)
+	binary.LittleEndian.PutUint64(out[240:], bbtRoot)
+	out[513] = b.crypt
+	return out
+}
+
+// writeBTree 写入叶子页，叶子页多于一页时再写一层中间页，返回根页偏移
+func (b *testPSTBuilder) writeBTree(out []byte, pageType byte, entries [][]byte) ([]byte, uint64) {
+	writePage := func(level byte, records [][]byte) uint64 {
+		page := make([]byte, pstPageSize)
+		for i, record := range records {
+			copy(page[i*len(record):], record)
+		}
+		page[488] = byte(len(records))
+		page[490] = byte(len(records[0]))
+		page[491] = level
+		page[496], page[497] = pageType, pageType
+		offset := uint64(len(out))
+		out = append(out, page...)
+		return offset
+	}
+
+	var index [][]byte
+	for start := 0; start < len(entries); start += b.leafCap {
+		end := start + b.leafCap
+		if end > len(entries) {
+			end = len(entries)
+		}
+		offset := writePage(0, entries[start:end])
+		entry := make([]byte, 24)
+		copy(entry, entries[start][:8])
+		binary.LittleEndian.PutUint64(entry[16:], offset)
+		index = append(index, entry)
+	}
+	if len(index) == 1 {
+		return out, binary.LittleEndian.Uint64(index[0][16:])
+	}
+	root := writePage(1, index)
+	return out, root
+}
+
+// testPSTHeap 生成单个数据块的堆（Heap-on-Node），超过堆分配上限的值放入子节点
+type testPSTHeap struct {
+	builder   *testPSTBuilder
+	clientSig byte
+	userRoot  uint32
+	items     [][]byte
+	subnodes  []pstNode
+}
+
+func (b *testPSTBuilder) newHeap(clientSig byte) *testPSTHeap {
+	return &testPSTHeap{builder: b, clientSig: clientSig}
+}
+
+func (h *testPSTHeap) alloc(data []byte) uint32 {
+	h.items = append(h.items, data)
+	return uint32(len(h.items)) << 5
+}
+
+// value 返回属性值的HNID
+func (h *testPSTHeap) value(data []byte) uint32 {
+	if len(data) <= 3580 {
+		return h.alloc(data)
+	}
+	nid := uint32(len(h.subnodes)+1)<<5 | 0x01
+	h.subnodes = append(h.subnodes, pstNode{nid: nid, bidData: h.builder.addBlock(data, false)})
+	return nid
+}
+
+func (h *testPSTHeap) subnode(nid uint32, heap *testPSTHeap) {
+	h.subnodes = append(h.subnodes, h.builder.heapNode(nid, heap))
+}
+
+func (h *testPSTHeap) bth(keySize, dataSize int, records [][]byte) uint32 {
+	var root uint32
+	if len(records) > 0 {
+		root = h.alloc(bytes.Join(records, nil))
+	}
+	header := []byte{pstHeapSigBTH, byte(keySize), byte(dataSize), 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(header[4:], root)
+	return h.alloc(header)
+}
+
+func (h *testPSTHeap) bytes() []byte {
+	data := make([]byte, 12)
+	data[2] = 0xEC
+	data[3] = h.clientSig
+	binary.LittleEndian.PutUint32(data[4:], h.userRoot)
+
+	offsets := []uint16{uint16(len(data))}
+	for _, item := range h.items {
+		data = append(data, item...)
+		offsets = append(offsets, uint16(len(data)))
+	}
+	if len(data)%2 != 0 {
+		data = append(data, 0)
+	}
+	binary.LittleEndian.PutUint16(data, uint16(len(data)))
+	pageMap := make([]byte, 4+len(offsets)*2)
+	binary.LittleEndian.PutUint16(pageMap, uint16(len(h.items)))
+	for i, offset := range offsets {
+		binary.LittleEndian.PutUint16(pageMap[4+i*2:], offset)
+	}
+	return append(data, pageMap...)
+}
+
+type testPSTProp struct {
+	tag   uint32
+	value []byte
+}
+
+// propertyContext 生成属性上下文（PC）堆
+func (b *testPSTBuilder) propertyContext(props []testPSTProp) *testPSTHeap {
+	heap := b.newHeap(pstHeapSigPC)
+	var records [][]byte
+	for _, prop := range props {
+		record := make([]byte, 8)
+		binary.LittleEndian.PutUint32(record, prop.tag>>16|prop.tag<<16)
+		if size := pstPropSize(uint16(prop.tag)); size > 0 && size <= 4 {
+			copy(record[4:], prop.value)
+		} else {
+			binary.LittleEndian.PutUint32(record[4:], heap.value(prop.value))
+		}
+		records = append(records, record)
+	}
+	heap.userRoot = heap.bth(2, 6, records)
+	return heap
+}
+
+// tableContext 生成表上下文（TC）堆。第一列固定为PR_LTP_ROW_ID（子文件夹或邮件的NID），
+// rows的每行以该值开头，为nil的单元格没有值
+func (b *testPSTBuilder) tableContext(tags []uint32, rows [][][]byte) *testPSTHeap {
+	heap := b.newHeap(pstHeapSigTC)
+	tags = append([]uint32{uint32(prLtpRowID)<<16 | ptLong}, tags...)
+
+	// 按4字节、8字节、1字节的顺序排布单元格，变长属性的单元格是4字节HNID
+	sizes := make([]int, len(tags))
+	offsets := make([]int, len(tags))
+	rowSize := 0
+	for _, want := range []int{4, 8, 1} {
+		for i, tag := range tags {
+			size := pstPropSize(uint16(tag))
+			if size == 0 || size == 2 {
+				size = 4
+			}
+			if size == want {
+				sizes[i], offsets[i] = size, rowSize
+				rowSize += size
+			}
+		}
+	}
+	cebOffset := rowSize
+	rowSize += (len(tags) + 7) / 8
+
+	var rowData []byte
+	var rowIndex [][]byte
+	for r, cells := range rows {
+		row := make([]byte, rowSize)
+		for i, cell := range cells {
+			if cell == nil {
+				continue
+			}
+			if pstPropSize(uint16(tags[i])) == 0 {
+				cell = le32(heap.value(cell))
+			}
+			copy(row[offsets[i]:offsets[i]+sizes[i]], cell)
+			row[cebOffset+i/8] |= 0x80 >> (i % 8)
+		}
+		rowData = append(rowData, row...)
+		rowIndex = append(rowIndex, append(append([]byte(nil), cells[0]...), le32(uint32(r))...))
+	}
+
+	var rowsHnid uint32
+	if len(rowData) > 0 {
+		rowsHnid = heap.alloc(rowData)
+	}
+	info := make([]byte, 22)
+	info[0] = pstHeapSigTC
+	info[1] = byte(len(tags))
+	binary.LittleEndian.PutUint16(info[2:], uint16(cebOffset))
+	binary.LittleEndian.PutUint16(info[4:], uint16(cebOffset))
+	binary.LittleEndian.PutUint16(info[6:], uint16(cebOffset))
+	binary.LittleEndian.PutUint16(info[8:], uint16(rowSize))
+	binary.LittleEndian.PutUint32(info[10:], heap.bth(4, 4, rowIndex))
+	binary.LittleEndian.PutUint32(info[14:], rowsHnid)
+	for i, tag := range tags {
+		desc := make([]byte, 8)
+		binary.LittleEndian.PutUint32(desc, tag)
+		binary.LittleEndian.PutUint16(desc[4:], uint16(offsets[i]))
+		desc[6] = byte(sizes[i])
+		desc[7] = byte(i)
+		info = append(info, desc...)
+	}
+	heap.userRoot = heap.alloc(info)
+	return heap
+}
+
+func le32(v uint32) []byte {
+	raw := make([]byte, 4)
+	binary.LittleEndian.PutUint32(raw, v)
+	return raw
+}
+
+func filetime(t time.Time) []byte {
+	raw := make([]byte, 8)
+	binary.LittleEndian.PutUint64(raw, uint64(t.UnixNano()/100)+116444736000000000)
+	return raw
+}
+
+func entryID(nid uint32) []byte {
+	return append(make([]byte, 20), le32(nid)...)
+}
+
+func unicodeTag(id uint16) uint32 { return uint32(id)<<16 | ptUnicode }
+func longTag(id uint16) uint32    { return uint32(id)<<16 | ptLong }
+func timeTag(id uint16) uint32    { return uint32(id)<<16 | ptSysTime }
+
+// 测试存档的文件夹：IPM根目录下有收件箱（含子文件夹Projects）、已发送邮件和日历
+const (
+	testNidSubtree  = 0x8022
+	testNidInbox    = 0x8042
+	testNidSent     = 0x8062
+	testNidCalendar = 0x8082
+	testNidProjects = 0x80A2
+)
+
+var testLongBody = strings.Repeat("项目进度说明。", 300)
+
+func buildTestPST(crypt byte) []byte {
+	b := newTestPSTBuilder(crypt)
+
+	b.addNode(pstNidMessageStore, b.propertyContext([]testPSTProp{
+		{uint32(prIPMSubtreeEntryID)<<16 | ptBinary, entryID(testNidSubtree)},
+		{uint32(prIPMSentMailEntryID)<<16 | ptBinary, entryID(testNidSent)},
+	}))
+
+	folder := func(nid uint32, name string, children [][][]byte, messages [][][]byte) {
+		b.addNode(nid, b.propertyContext([]testPSTProp{
+			{unicodeTag(prDisplayName), unicodeProp(name)},
+			{unicodeTag(prContainerClass), unicodeProp("IPF.Note")},
+		}))
+		b.addNode(nid&^0x1F|pstNidTypeHierarchy, b.tableContext(
+			[]uint32{unicodeTag(prDisplayName), unicodeTag(prContainerClass)}, children))
+		b.addNode(nid&^0x1F|pstNidTypeContents, b.tableContext(
+			[]uint32{timeTag(prMessageDeliveryTime), timeTag(prClientSubmitTime)}, messages))
+	}
+	child := func(nid uint32, name, class string) [][]byte {
+		row := [][]byte{le32(nid), nil, nil}
+		if name != "" {
+			row[1] = unicodeProp(name)
+		}
+		if class != "" {
+			row[2] = unicodeProp(class)
+		}
+		return row
+	}
+
+	message := func(nid uint32, subject, sender, address string, delivered time.Time, read bool, body string, recipients [][][]byte) [][]byte {
+		flags := uint32(0)
+		if read {
+			flags = msgFlagRead
+		}
+		heap := b.propertyContext([]testPSTProp{
+			{unicodeTag(prSubject), unicodeProp(subject)},
+			{unicodeTag(prSenderName), unicodeProp(sender)},
+			{unicodeTag(prSenderSMTPAddress), unicodeProp(address)},
+			{timeTag(prMessageDeliveryTime), filetime(delivered)},
+			{timeTag(prClientSubmitTime), filetime(delivered.Add(-time.Minute))},
+			{longTag(prMessageFlags), le32(flags)},
+			{unicodeTag(prBody), unicodeProp(body)},
+			{unicodeTag(prMessageClass), unicodeProp("IPM.Note")},
+		})
+		if len(recipients) > 0 {
+			heap.subnode(pstNidRecipientTbl, b.tableContext(
+				[]uint32{longTag(prRecipientType), unicodeTag(prDisplayName), unicodeTag(prSMTPAddress)}, recipients))
+		}
+		b.addNode(nid, heap)
+		return [][]byte{le32(nid), filetime(delivered), filetime(delivered.Add(-time.Minute))}
+	}
+	recipient := func(rowID uint32, kind RecipientType, name, address string) [][]byte {
+		return [][]byte{le32(rowID), le32(uint32(kind)), unicodeProp(name), unicodeProp(address)}
+	}
+
+	march := func(day, hour int) time.Time { return time.Date(2025, 3, day, hour, 0, 0, 0, time.UTC) }
+
+	inbox := [][][]byte{
+		message(0x200004, "季度预算审批", "张三", "zhangsan@example.com", march(3, 9), true, "请审批附件中的预算",
+			[][][]byte{recipient(0, RecipientTo, "Me", "me@example.com"), recipient(1, RecipientCC, "李四", "lisi@example.com")}),
+		message(0x200024, "Old newsletter", "News", "news@example.com", time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC), false, "old", nil),
+	}
+	projects := [][][]byte{
+		message(0x200044, "项目周报", "王五", "wangwu@example.com", march(4, 15), false, testLongBody,
+			[][][]byte{recipient(0, RecipientTo, "Me", "me@example.com")}),
+	}
+	sent := [][][]byte{
+		message(0x200064, "RE: 季度预算审批", "Me", "me@example.com", march(5, 10), true, "已审批",
+			[][][]byte{recipient(0, RecipientTo, "张三", "zhangsan@example.com")}),
+	}
+
+	folder(testNidSubtree, "Top of Personal Folders", [][][]byte{
+		child(testNidInbox, "Inbox", "IPF.Note"),
+		child(testNidSent, "Sent Items", "IPF.Note"),
+		child(testNidCalendar, "Calendar", "IPF.Appointment"),
+	}, nil)
+	// Projects在层次表中没有名称和容器类，需要从文件夹自身的属性读取
+	folder(testNidInbox, "Inbox", [][][]byte{child(testNidProjects, "", "")}, inbox)
+	folder(testNidProjects, "Projects", nil, projects)
+	folder(testNidSent, "Sent Items", nil, sent)
+	return b.bytes()
+}
+
+func TestPSTFixtureUpToDate(t *testing.T) {
+	data := buildTestPST(pstCryptPermute)
+	if *updateFixtures {
+		if err := os.WriteFile(pstFixture, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	existing, err := os.ReadFile(pstFixture)
+	if err != nil {
+		t.Fatalf("%v (使用 go test -run TestPSTFixtureUpToDate -update 生成)", err)
+	}
+	if !bytes.Equal(existing, data) {
+		t.Errorf("%s 已过期，使用 go test -run TestPSTFixtureUpToDate -update 重新生成", pstFixture)
+	}
+}
+
+func openTestPST(t *testing.T, crypt byte) *PstSource {
+	t.Helper()
+	path := pstFixture
+	if crypt != pstCryptPermute {
+		path = filepath.Join(t.TempDir(), "plain.pst")
+		if err := os.WriteFile(path, buildTestPST(crypt), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pst, err := openPST(path)
+	if err != nil {
+		t.Fatalf("openPST: %v", err)
+	}
+	t.Cleanup(func() { pst.Close() })
+	return &PstSource{pst: pst}
+}
+
+func TestPSTBTreeLookup(t *testing.T) {
+	for _, crypt := range []byte{pstCryptPermute, pstCryptNone} {
+		ps := openTestPST(t, crypt)
+
+		// 叶子页只有4条记录，这些节点分布在多个叶子页中，需要经过中间页查找
+		for _, nid := range []uint32{pstNidMessageStore, testNidSubtree, testNidInbox, testNidProjects | pstNidTypeContents, 0x200064} {
+			node, err := ps.pst.node(nid)
+			if err != nil {
+				t.Errorf("crypt %d: node(0x%X): %v", crypt, nid, err)
+				continue
+			}
+			if _, err := ps.pst.readBlock(node.bidData); err != nil {
+				t.Errorf("crypt %d: readBlock(0x%X): %v", crypt, node.bidData, err)
+			}
+		}
+		for _, nid := range []uint32{0x1, 0x8023, 0xFFFFFF} {
+			if _, err := ps.pst.node(nid); err == nil {
+				t.Errorf("crypt %d: node(0x%X) found a node that does not exist", crypt, nid)
+			}
+		}
+		if _, err := ps.pst.readBlock(0xFFFF0); err == nil {
+			t.Errorf("crypt %d: readBlock found a block that does not exist", crypt)
+		}
+	}
+}
+
+func TestPSTPropertyAndTableContext(t *testing.T) {
+	ps := openTestPST(t, pstCryptPermute)
+
+	store, err := ps.nodeProps(pstNidMessageStore)
+	if err != nil {
+		t.Fatalf("message store: %v", err)
+	}
+	if got := store.binary(prIPMSubtreeEntryID); len(got) != 24 || binary.LittleEndian.Uint32(got[20:]) != testNidSubtree {
+		t.Errorf("PR_IPM_SUBTREE_ENTRYID = %x", got)
+	}
+
+	folders, err := ps.childFolders(testNidSubtree)
+	if err != nil {
+		t.Fatalf("hierarchy table: %v", err)
+	}
+	want := []pstFolder{
+		{nid: testNidInbox, name: "Inbox", isMail: true},
+		{nid: testNidSent, name: "Sent Items", isMail: true},
+		{nid: testNidCalendar, name: "Calendar", isMail: false},
+	}
+	if len(folders) != len(want) {
+		t.Fatalf("childFolders = %+v", folders)
+	}
+	for i := range want {
+		if folders[i] != want[i] {
+			t.Errorf("folder %d = %+v, want %+v", i, folders[i], want[i])
+		}
+	}
+
+	rows, err := ps.table(testNidInbox, pstNidTypeContents)
+	if err != nil {
+		t.Fatalf("contents table: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("contents table has %d rows, want 2", len(rows))
+	}
+	if nid, _ := rows[1].int32(prLtpRowID); nid != 0x200024 {
+		t.Errorf("second row id = 0x%X", nid)
+	}
+	if got := rows[0].time(prMessageDeliveryTime); !got.Equal(time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("delivery time column = %v", got)
+	}
+}
+
+func TestPSTSourceEmails(t *testing.T) {
+	ps := openTestPST(t, pstCryptPermute)
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	received, err := ps.ReceivedEmails("", start, end)
+	if err != nil {
+		t.Fatalf("ReceivedEmails: %v", err)
+	}
+	if len(received) != 2 {
+		t.Fatalf("got %d received emails, want 2 (one is outside the date range)", len(received))
+	}
+
+	budget := received[0]
+	if budget.Subject != "季度预算审批" || budget.SenderName != "张三" || budget.SenderEmail != "zhangsan@example.com" {
+		t.Errorf("subject/sender = %q, %q <%s>", budget.Subject, budget.SenderName, budget.SenderEmail)
+	}
+	if !budget.IsRead || budget.Folder != "Inbox" || budget.Body != "请审批附件中的预算" {
+		t.Errorf("read/folder/body = %v, %q, %q", budget.IsRead, budget.Folder, budget.Body)
+	}
+	if !budget.ReceivedTime.Equal(time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("ReceivedTime = %v", budget.ReceivedTime)
+	}
+	wantRecipients := []Recipient{
+		{Name: "Me", Address: "me@example.com", Type: RecipientTo, Resolved: true},
+		{Name: "李四", Address: "lisi@example.com", Type: RecipientCC, Resolved: true},
+	}
+	if len(budget.Recipients) != len(wantRecipients) {
+		t.Fatalf("Recipients = %+v", budget.Recipients)
+	}
+	for i := range wantRecipients {
+		if budget.Recipients[i] != wantRecipients[i] {
+			t.Errorf("recipient %d = %+v, want %+v", i, budget.Recipients[i], wantRecipients[i])
+		}
+	}
+	if budget.To != "Me" || budget.CC != "李四" {
+		t.Errorf("To/CC = %q / %q", budget.To, budget.CC)
+	}
+
+	// 正文超过堆分配上限，存放在子节点中
+	report := received[1]
+	if report.Folder != "Inbox/Projects" || report.IsRead {
+		t.Errorf("folder/read = %q, %v", report.Folder, report.IsRead)
+	}
+	if report.Body != truncateBody(testLongBody) {
+		t.Errorf("body from subnode = %q", report.Body)
+	}
+
+	sent, err := ps.SentEmails("", start, end)
+	if err != nil {
+		t.Fatalf("SentEmails: %v", err)
+	}
+	if len(sent) != 1 || sent[0].Subject != "RE: 季度预算审批" || sent[0].SenderEmail != "me@example.com" {
+		t.Errorf("SentEmails = %+v", sent)
+	}
+}
+
+func TestPSTTableRejectsBadRowSize(t *testing.T) {
+	b := newTestPSTBuilder(pstCryptNone)
+	valid := b.tableContext([]uint32{unicodeTag(prDisplayName)}, [][][]byte{{le32(1), unicodeProp("a")}})
+	data := valid.bytes()
+	heap := &pstHeap{blocks: [][]byte{data}, clientSig: pstHeapSigTC, userRoot: valid.userRoot}
+	if rows, err := heap.tableContext(); err != nil || len(rows) != 1 {
+		t.Fatalf("valid table: %d rows, %v", len(rows), err)
+	}
+
+	info, err := heap.item(valid.userRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rowSize := range []uint16{0, pstMaxBlockSize - pstBlockTrailer + 1, 0xFFFF} {
+		binary.LittleEndian.PutUint16(info[8:], rowSize)
+		if _, err := heap.tableContext(); err == nil {
+			t.Errorf("rowSize %d was accepted", rowSize)
+		}
+	}
+}
+
+func openTestPSTBytes(t *testing.T, data []byte) *PstSource {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.pst")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pst, err := openPST(path)
+	if err != nil {
+		t.Fatalf("openPST: %v", err)
+	}
+	t.Cleanup(func() { pst.Close() })
+	return &PstSource{pst: pst}
+}
+
+func TestPSTCyclicStructures(t *testing.T) {
+	b := newTestPSTBuilder(pstCryptNone)
+	// 指向自身的XBLOCK和SIBLOCK
+	xblock := b.nextBID | 0x2
+	data := []byte{0x01, 1, 1, 0, 0, 0, 0, 0}
+	data = append(data, make([]byte, 8)...)
+	binary.LittleEndian.PutUint64(data[8:], xblock)
+	if bid := b.addBlock(data, true); bid != xblock {
+		t.Fatalf("XBLOCK bid = 0x%X, want 0x%X", bid, xblock)
+	}
+	siblock := b.nextBID | 0x2
+	data = []byte{0x02, 1, 1, 0, 0, 0, 0, 0}
+	data = append(data, make([]byte, 16)...)
+	binary.LittleEndian.PutUint64(data[8:], 0x21)
+	binary.LittleEndian.PutUint64(data[16:], siblock)
+	b.addBlock(data, true)
+
+	// 互相引用的文件夹：A的子文件夹是A自身和B，B的子文件夹是A
+	const folderA, folderB = 0x8122, 0x8142
+	for _, folder := range []struct {
+		nid      uint32
+		children []uint32
+	}{{folderA, []uint32{folderA, folderB}}, {folderB, []uint32{folderA}}} {
+		var rows [][][]byte
+		for _, child := range folder.children {
+			rows = append(rows, [][]byte{le32(child), unicodeProp("F"), unicodeProp("IPF.Note")})
+		}
+		b.addNode(folder.nid&^0x1F|pstNidTypeHierarchy, b.tableContext(
+			[]uint32{unicodeTag(prDisplayName), unicodeTag(prContainerClass)}, rows))
+	}
+	ps := openTestPSTBytes(t, b.bytes())
+
+	if _, err := ps.pst.readNodeData(xblock); err == nil || !strings.Contains(err.Error(), "层级过深") {
+		t.Errorf("cyclic XBLOCK: err = %v", err)
+	}
+	if _, err := ps.pst.subnodes(siblock); err == nil || !strings.Contains(err.Error(), "层级过深") {
+		t.Errorf("cyclic SIBLOCK: err = %v", err)
+	}
+	var folders []pstFolder
+	ps.getSubfolders(pstFolder{nid: folderA}, &folders, nil)
+	if len(folders) != 2 || folders[0].nid != folderB || folders[1].nid != folderA {
+		t.Errorf("cyclic folders = %+v", folders)
+	}
+
+	// BTH索引项指向自身
+	heap := b.newHeap(pstHeapSigPC)
+	root := heap.alloc(append([]byte{0, 0}, le32(1<<5)...))
+	header := []byte{pstHeapSigBTH, 2, 6, 1, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(header[4:], root)
+	heap.userRoot = heap.alloc(header)
+	cyclic := &pstHeap{blocks: [][]byte{heap.bytes()}, clientSig: pstHeapSigPC, userRoot: heap.userRoot}
+	if _, err := cyclic.propertyContext(); err == nil || !strings.Contains(err.Error(), "循环") {
+		t.Errorf("cyclic BTH: err = %v", err)
+	}
+}
+
+func TestPSTTruncatedStructures(t *testing.T) {
+	short := &pstHeap{blocks: [][]byte{{0x01}}}
+	if _, err := short.item(1 << 5); err == nil {
+		t.Error("item in a 1-byte heap block succeeded")
+	}
+
+	// 变长属性列的单元格不足4字节
+	b := newTestPSTBuilder(pstCryptNone)
+	valid := b.tableContext([]uint32{unicodeTag(prDisplayName)}, [][][]byte{{le32(1), unicodeProp("a")}})
+	heap := &pstHeap{blocks: [][]byte{valid.bytes()}, clientSig: pstHeapSigTC, userRoot: valid.userRoot}
+	info, err := heap.item(valid.userRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info[22+8+6] = 2
+	if _, err := heap.tableContext(); err == nil || !strings.Contains(err.Error(), "大小异常") {
+		t.Errorf("2-byte string column: err = %v", err)
+	}
+}
+
+// TestPSTCorruptFixture 截断或改写测试存档中的字节后读取全部邮件，只要求不崩溃、不死循环
+func TestPSTCorruptFixture(t *testing.T) {
+	original := buildTestPST(pstCryptNone)
+	readAll := func(data []byte) {
+		path := filepath.Join(t.TempDir(), "corrupt.pst")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		pst, err := openPST(path)
+		if err != nil {
+			return
+		}
+		defer pst.Close()
+		ps := &PstSource{pst: pst}
+		subtree, err := ps.storeFolder(prIPMSubtreeEntryID)
+		if err != nil {
+			subtree = pstFolder{nid: pstNidRootFolder}
+		}
+		folders := []pstFolder{subtree}
+		ps.getSubfolders(subtree, &folders, nil)
+		for _, folder := range folders {
+			rows, _ := ps.table(folder.nid, pstNidTypeContents)
+			for _, row := range rows {
+				if nid, ok := row.int32(prLtpRowID); ok {
+					ps.readMessage(uint32(nid))
+				}
+			}
+		}
+	}
+
+	for size := 600; size < len(original); size += 512 {
+		readAll(original[:size])
+	}
+	for offset := 1024; offset < len(original); offset += 7 {
+		for _, value := range []byte{0x00, 0xFF} {
+			data := append([]byte(nil), original...)
+			data[offset] = value
+			readAll(data)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// RecipientType 是收件人类型，取值与MAPI的PR_RECIPIENT_TYPE和Outlook的Recipient.Type相同
+type RecipientType int
+
+const (
+	RecipientTo  RecipientType = 1
+	RecipientCC  RecipientType = 2
+	RecipientBCC RecipientType = 3
+)
+
+// Recipient 是邮件的一个收件人
+type Recipient struct {
+	Name     string
+	Address  string // 只有显示名时为空
+	Type     RecipientType
+	Resolved bool // 地址已确定（来自邮件头，或Outlook中已解析的收件人）
+	DistList bool // 数据源标记为分发列表（Outlook和MAPI的显示类型），邮件头中没有此信息
+}
+
+// PR_DISPLAY_TYPE 和 Outlook Recipient.DisplayType 中表示分发列表的取值
+const (
+	displayTypeDistList        = 1 // DT_DISTLIST / olDistList，Exchange通讯组
+	displayTypePrivateDistList = 5 // DT_PRIVATE_DISTLIST / olPrivateDistList，个人联系人组
+)
+
+func isDistListDisplayType(displayType int32) bool {
+	return displayType == displayTypeDistList || displayType == displayTypePrivateDistList
+}
+
+// recipientsOf 返回指定类型的收件人，不指定类型时返回全部。
+// 数据源没有提供收件人列表时，使用To/CC属性中分号分隔的显示名
+func (e EmailInfo) recipientsOf(types ...RecipientType) []Recipient {
+	recipients := e.Recipients
+	if len(recipients) == 0 {
+		recipients = append(displayRecipients(e.To, RecipientTo), displayRecipients(e.CC, RecipientCC)...)
+	}
+	if len(types) == 0 {
+		return recipients
+	}
+	var result []Recipient
+	for _, recipient := range recipients {
+		for _, kind := range types {
+			if recipient.Type == kind {
+				result = append(result, recipient)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// parseAddressList 解析RFC 5322地址列表。不符合标准时（如Outlook使用分号分隔、显示名中的逗号没有加引号），
+// 在引号、尖括号和括号之外的逗号或分号处切分后逐个解析，无法解析为地址的项作为未解析的显示名
+func parseAddressList(value string, kind RecipientType) []Recipient {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	var recipients []Recipient
+	parser := mail.AddressParser{WordDecoder: headerDecoder}
+	if addresses, err := parser.ParseList(value); err == nil {
+		for _, address := range addresses {
+			recipients = append(recipients, Recipient{Name: address.Name, Address: address.Address, Type: kind, Resolved: true})
+		}
+		return recipients
+	}
+
+	parts := splitAddressList(value)
+	for i := 0; i < len(parts); i++ {
+		part := parts[i]
+		// 显示名中没有加引号的逗号，如 Doe, Jane <jane@example.com>
+		if i+1 < len(parts) && !strings.ContainsAny(part, "<@") && strings.Contains(parts[i+1], "<") {
+			parts[i+1] = part + "," + parts[i+1]
+			continue
+		}
+		// 组语法 "名称: 地址, ...;" 只保留其中的地址
+		if colon := strings.Index(part, ":"); colon >= 0 && !strings.ContainsAny(part[:colon], `<@"`) {
+			part = part[colon+1:]
+		}
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if address, err := parseAddress(part); err == nil {
+			recipients = append(recipients, Recipient{Name: address.Name, Address: address.Address, Type: kind, Resolved: true})
+			continue
+		}
+		recipient := Recipient{Name: strings.Trim(decodeHeader(part), `"'`), Type: kind}
+		if lt, gt := strings.LastIndex(part, "<"), strings.LastIndex(part, ">"); lt >= 0 && gt > lt {
+			recipient.Name = strings.Trim(decodeHeader(part[:lt]), `"' `)
+			recipient.Address = strings.TrimSpace(part[lt+1 : gt])
+		} else if strings.Contains(part, "@") && !strings.ContainsAny(part, " \t") {
+			recipient.Name, recipient.Address = "", part
+		}
+		recipient.Resolved = recipient.Address != ""
+		recipients = append(recipients, recipient)
+	}
+	return recipients
+}
+
+// displayRecipients 将To/CC属性转换为收件人，其中可能是显示名或地址
+func displayRecipients(list string, kind RecipientType) []Recipient {
+	var recipients []Recipient
+	for _, part := range strings.Split(list, ";") {
+		name, address := parseRecipient(part)
+		if name != "" || address != "" {
+			recipients = append(recipients, Recipient{Name: name, Address: address, Type: kind, Resolved: address != ""})
+		}
+	}
+	return recipients
+}
+
+// splitAddressList 在引号、尖括号和括号（注释）之外的逗号或分号处切分
+func splitAddressList(value string) []string {
+	var parts []string
+	var quoted, escaped bool
+	angle, paren, start := 0, 0, 0
+	for i, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '<':
+			angle++
+		case r == '>' && angle > 0:
+			angle--
+		case r == '(':
+			paren++
+		case r == ')' && paren > 0:
+			paren--
+		case (r == ',' || r == ';') && angle == 0 && paren == 0:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// addressRecipients 将已解析的地址转换为收件人
+func addressRecipients(addresses []mail.Address, kind RecipientType) []Recipient {
+	recipients := make([]Recipient, 0, len(addresses))
+	for _, address := range addresses {
+		recipients = append(recipients, Recipient{Name: address.Name, Address: address.Address, Type: kind, Resolved: true})
+	}
+	return recipients
+}
+
+// recipientNames 按Outlook To/CC属性的格式拼接指定类型收件人的显示名，没有显示名时使用地址，以分号分隔
+func recipientNames(recipients []Recipient, kind RecipientType) string {
+	var names []string
+	for _, recipient := range recipients {
+		if recipient.Type != kind {
+			continue
+		}
+		if recipient.Name != "" {
+			names = append(names, recipient.Name)
+		} else if recipient.Address != "" {
+			names = append(names, recipient.Address)
+		}
+	}
+	return strings.Join(names, "; ")
+}
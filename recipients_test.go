@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAddressList(t *testing.T) {
+	to := func(name, address string) Recipient {
+		return Recipient{Name: name, Address: address, Type: RecipientTo, Resolved: address != ""}
+	}
+	tests := []struct {
+		name  string
+		value string
+		want  []Recipient
+	}{
+		{"standard list", `"Doe, Jane" <jane@example.com>, bob@example.com`,
+			[]Recipient{to("Doe, Jane", "jane@example.com"), to("", "bob@example.com")}},
+		{"encoded display name", "=?UTF-8?B?5byg5LiJ?= <zhangsan@example.com>",
+			[]Recipient{to("张三", "zhangsan@example.com")}},
+		{"Outlook semicolons", "张三 <zhangsan@example.com>; 李四 <lisi@example.com>;",
+			[]Recipient{to("张三", "zhangsan@example.com"), to("李四", "lisi@example.com")}},
+		{"unquoted comma in display name", "Doe, Jane <jane@example.com>, Roe, Richard <richard@example.com>",
+			[]Recipient{to("Doe, Jane", "jane@example.com"), to("Roe, Richard", "richard@example.com")}},
+		{"group syntax", "undisclosed-recipients:;", nil},
+		{"group with members; bad member kept", "项目组: alice@example.com, 王五;",
+			[]Recipient{to("", "alice@example.com"), to("王五", "")}},
+		{"display name only", "全体员工",
+			[]Recipient{to("全体员工", "")}},
+		{"Exchange DN in angle brackets", "张三 </O=CORP/OU=EXCHANGE/CN=RECIPIENTS/CN=ZHANGSAN>; 李四",
+			[]Recipient{to("张三", "/O=CORP/OU=EXCHANGE/CN=RECIPIENTS/CN=ZHANGSAN"), to("李四", "")}},
+		{"bare address without domain dot", "张三 <zhangsan@localhost>, admin@intranet",
+			[]Recipient{to("张三", "zhangsan@localhost"), to("", "admin@intranet")}},
+		{"empty", "  ", nil},
+	}
+	for _, tt := range tests {
+		if got := parseAddressList(tt.value, RecipientTo); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseAddressList(%q) = %+v, want %+v", tt.name, tt.value, got, tt.want)
+		}
+	}
+
+	if got := parseAddressList("a@example.com", RecipientBCC); len(got) != 1 || got[0].Type != RecipientBCC {
+		t.Errorf("type = %+v", got)
+	}
+}
+
+func TestSplitAddressList(t *testing.T) {
+	tests := map[string][]string{
+		`a@x.com, "b; c" <b@x.com>`:         {"a@x.com", ` "b; c" <b@x.com>`},
+		`x (note, more) <x@y.com>; z@y.com`: {"x (note, more) <x@y.com>", " z@y.com"},
+		`"a\", b" <a@x.com>`:                {`"a\", b" <a@x.com>`},
+		"":                                  {""},
+	}
+	for value, want := range tests {
+		if got := splitAddressList(value); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitAddressList(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
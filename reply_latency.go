@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// emailReply 是一封收到的邮件和对它的第一封回复
+type emailReply struct {
+	Original EmailInfo
+	Reply    EmailInfo
+}
+
+// latency 是从收到邮件到发出回复的实际时长。时钟偏差导致为负时按0计算
+func (r emailReply) latency() time.Duration {
+	d := r.Reply.SentTime.Sub(r.Original.ReceivedTime)
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// dated 判断收到和回复的时间是否都已知。没有日期的邮件（如没有日期列的CSV）不计入当天回复、回复时效和SLA
+func (r emailReply) dated() bool {
+	return !r.Original.ReceivedTime.IsZero() && !r.Reply.SentTime.IsZero()
+}
+
+// sameBusinessDay 判断是否在收到邮件的工作日下班前回复，下班后或节假日收到的邮件在下一个工作日下班前回复也算。
+// 回复统计中的“当天回复数”和回复时长分布中的“当天”都按此判断
+func (r emailReply) sameBusinessDay(calendar *workCalendar) bool {
+	return !r.Reply.SentTime.After(calendar.businessDayEnd(r.Original.ReceivedTime))
+}
+
+// latencyBand 是回复时长分布中的一档，各档互不重叠，按顺序取第一个符合的
+type latencyBand struct {
+	Label string
+	Count int
+	match func(r emailReply, latency time.Duration, calendar *workCalendar) bool
+}
+
+type replyLatencyStats struct {
+	Count  int
+	Median time.Duration
+	P75    time.Duration
+	P90    time.Duration
+	Bands  []latencyBand
+}
+
+func newLatencyBands() []latencyBand {
+	return []latencyBand{
+		{Label: "1小时内", match: func(r emailReply, d time.Duration, calendar *workCalendar) bool { return d < time.Hour }},
+		{Label: "1-4小时", match: func(r emailReply, d time.Duration, calendar *workCalendar) bool { return d < 4*time.Hour }},
+		{Label: "当天（超过4小时）", match: func(r emailReply, d time.Duration, calendar *workCalendar) bool { return r.sameBusinessDay(calendar) }},
+		// 不到24小时但已过收到邮件的工作日
+		{Label: "24小时内（非当天）", match: func(r emailReply, d time.Duration, calendar *workCalendar) bool { return d < 24*time.Hour }},
+		{Label: "1-3天", match: func(r emailReply, d time.Duration, calendar *workCalendar) bool { return d < 72*time.Hour }},
+		{Label: "3天以上", match: func(r emailReply, d time.Duration, calendar *workCalendar) bool { return true }},
+	}
+}
+
+// analyzeReplyLatency 统计回复时长的中位数、P75、P90和分档分布。“当天”按工作日历中的工作日划分
+func (oa *OutlookEmailAnalyzer) analyzeReplyLatency(replies []emailReply) replyLatencyStats {
+	stats := replyLatencyStats{Count: len(replies), Bands: newLatencyBands()}
+	if len(replies) == 0 {
+		return stats
+	}
+
+	latencies := make([]time.Duration, 0, len(replies))
+	for _, reply := range replies {
+		latency := reply.latency()
+		latencies = append(latencies, latency)
+		for i := range stats.Bands {
+			if stats.Bands[i].match(reply, latency, oa.calendar) {
+				stats.Bands[i].Count++
+				break
+			}
+		}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	stats.Median = percentile(latencies, 0.5)
+	stats.P75 = percentile(latencies, 0.75)
+	stats.P90 = percentile(latencies, 0.9)
+	return stats
+}
+
+// percentile 对已排序的数据做线性插值
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := p * float64(len(sorted)-1)
+	lower := int(pos)
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	frac := pos - float64(lower)
+	return sorted[lower] + time.Duration(frac*float64(sorted[lower+1]-sorted[lower]))
+}
+
+func (oa *OutlookEmailAnalyzer) printReplyLatency(stats replyLatencyStats) {
+	fmt.Printf("\n⏱️ 回复时效 (时区: %s):\n", oa.location)
+	if stats.Count == 0 {
+		fmt.Printf("   无回复数据\n")
+		return
+	}
+	fmt.Printf("   中位数: %s\n", formatLatency(stats.Median))
+	fmt.Printf("   P75: %s\n", formatLatency(stats.P75))
+	fmt.Printf("   P90: %s\n", formatLatency(stats.P90))
+	fmt.Printf("   分布:\n")
+	for _, band := range stats.Bands {
+		percentage := float64(band.Count) / float64(stats.Count) * 100
+		fmt.Printf("     %s %4d 封 (%5.1f%%) %s\n", padDisplay(band.Label, 18), band.Count, percentage, histogramBar(percentage))
+	}
+}
+
+func formatLatency(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%d分钟", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%.1f小时", d.Hours())
+	default:
+		return fmt.Sprintf("%.1f天", d.Hours()/24)
+	}
+}
+
+// displayWidth 是字符串在终端中的显示宽度，中文等全角字符占两列
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		if r >= 0x1100 {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	return w
+}
+
+// padDisplay 按终端显示宽度补齐空格
+func padDisplay(s string, width int) string {
+	for w := displayWidth(s); w < width; w++ {
+		s += " "
+	}
+	return s
+}
+
+// histogramBar 每5%一格
+func histogramBar(percentage float64) string {
+	bar := ""
+	for i := 0; i < int(percentage/5+0.5); i++ {
+		bar += "█"
+	}
+	return bar
+}
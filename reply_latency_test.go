@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplyLatencyBandsFollowWorkCalendar(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	at := func(day, hour, minute int) time.Time { return time.Date(2025, 3, day, hour, minute, 0, 0, loc) }
+	// 2025-03-07 是周五，03-10 是周一
+	holidayCalendar := newDefaultCalendar(loc)
+	holidayCalendar.holidays["2025-03-10"] = true
+	makeupCalendar := newDefaultCalendar(loc)
+	makeupCalendar.workdays["2025-03-08"] = true
+
+	tests := []struct {
+		name     string
+		received time.Time
+		sent     time.Time
+		calendar *workCalendar
+		want     string
+	}{
+		{"within the hour", at(7, 10, 0), at(7, 10, 30), nil, "1小时内"},
+		{"afternoon", at(7, 10, 0), at(7, 13, 0), nil, "1-4小时"},
+		{"before closing", at(7, 10, 0), at(7, 17, 30), nil, "当天（超过4小时）"},
+		{"after closing on the same date", at(7, 10, 0), at(7, 19, 0), nil, "24小时内（非当天）"},
+		{"friday evening to monday", at(7, 20, 0), at(10, 11, 0), nil, "当天（超过4小时）"},
+		{"saturday mail answered saturday", at(8, 10, 0), at(8, 16, 0), nil, "当天（超过4小时）"},
+		{"monday is a holiday", at(7, 20, 0), at(11, 11, 0), holidayCalendar, "当天（超过4小时）"},
+		{"tuesday without the holiday", at(7, 20, 0), at(11, 11, 0), nil, "3天以上"},
+		{"saturday is a makeup workday", at(7, 20, 0), at(10, 11, 0), makeupCalendar, "1-3天"},
+		{"after the next business day", at(7, 20, 0), at(10, 18, 30), nil, "1-3天"},
+		{"over three days", at(3, 9, 0), at(7, 9, 0), nil, "3天以上"},
+	}
+	for _, tt := range tests {
+		oa := &OutlookEmailAnalyzer{location: loc, calendar: newDefaultCalendar(loc)}
+		if tt.calendar != nil {
+			oa.calendar = tt.calendar
+		}
+		reply := emailReply{Original: EmailInfo{ReceivedTime: tt.received}, Reply: EmailInfo{SentTime: tt.sent}}
+		stats := oa.analyzeReplyLatency([]emailReply{reply})
+		got := ""
+		for _, band := range stats.Bands {
+			if band.Count > 0 {
+				got += band.Label
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: band = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReplyLatencyPercentiles(t *testing.T) {
+	loc := time.UTC
+	oa := &OutlookEmailAnalyzer{location: loc, calendar: newDefaultCalendar(loc)}
+	received := time.Date(2025, 3, 3, 9, 0, 0, 0, loc)
+	var replies []emailReply
+	for _, minutes := range []int{40, 10, 30, 20, 50} {
+		replies = append(replies, emailReply{
+			Original: EmailInfo{ReceivedTime: received},
+			Reply:    EmailInfo{SentTime: received.Add(time.Duration(minutes) * time.Minute)},
+		})
+	}
+	// 时钟偏差导致的负时长按0计算
+	replies = append(replies, emailReply{Original: EmailInfo{ReceivedTime: received}, Reply: EmailInfo{SentTime: received.Add(-time.Minute)}})
+
+	stats := oa.analyzeReplyLatency(replies)
+	if stats.Count != 6 || stats.Median != 25*time.Minute || stats.P75 != 37*time.Minute+30*time.Second || stats.P90 != 45*time.Minute {
+		t.Errorf("stats = count %d, median %v, P75 %v, P90 %v", stats.Count, stats.Median, stats.P75, stats.P90)
+	}
+	if stats.Bands[0].Count != 6 {
+		t.Errorf("1小时内 = %d, want 6", stats.Bands[0].Count)
+	}
+}
+
+// TestSameDayRepliesMatchLatencyBand 回复统计中的当天回复数与分布中的“当天”按同一工作日划分
+func TestSameDayRepliesMatchLatencyBand(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	at := func(day, hour int) time.Time { return time.Date(2025, 3, day, hour, 0, 0, 0, loc) }
+	oa := NewOutlookEmailAnalyzer(&stubMailSource{})
+	oa.location = loc
+	oa.calendar = newDefaultCalendar(loc)
+
+	// 2025-03-07 是周五
+	received := []EmailInfo{
+		{MessageID: "a@example.com", ReceivedTime: at(7, 10), IsRead: true},
+		// 同一日期但已下班
+		{MessageID: "b@example.com", ReceivedTime: at(7, 10), IsRead: true},
+		// 周五晚上收到，周一上午回复
+		{MessageID: "c@example.com", ReceivedTime: at(7, 20), IsRead: true},
+	}
+	sent := []EmailInfo{
+		{InReplyTo: "a@example.com", SentTime: at(7, 17)},
+		{InReplyTo: "b@example.com", SentTime: at(7, 19)},
+		{InReplyTo: "c@example.com", SentTime: at(10, 11)},
+	}
+	repliedCount, sameDayReplies, replies := oa.findRepliedEmails(received, sent)
+	if repliedCount != 3 || sameDayReplies != 2 {
+		t.Errorf("replied = %d, same day = %d", repliedCount, sameDayReplies)
+	}
+	sameDayBand := 0
+	for _, band := range oa.analyzeReplyLatency(replies).Bands[:3] {
+		sameDayBand += band.Count
+	}
+	if sameDayBand != sameDayReplies {
+		t.Errorf("bands up to 当天 = %d, same day replies = %d", sameDayBand, sameDayReplies)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// slaGroup 是一组回复（全部、某个分类或某个发件人）的SLA达成情况
+type slaGroup struct {
+	Name   string
+	Total  int
+	Met    int
+	Median time.Duration // 工作时间内的回复时长中位数
+
+	latencies []time.Duration
+}
+
+func (g slaGroup) attainment() float64 {
+	if g.Total == 0 {
+		return 0
+	}
+	return float64(g.Met) / float64(g.Total) * 100
+}
+
+type slaStats struct {
+	Target     time.Duration
+	Overall    slaGroup
+	ByCategory []slaGroup
+	BySender   []slaGroup
+}
+
+// maxSLASenders 是按发件人列出的最多行数
+const maxSLASenders = 10
+
+// analyzeSLA 按工作日历计算每封回复的工作时间时长，统计在目标时长内回复的比例
+func (oa *OutlookEmailAnalyzer) analyzeSLA(replies []emailReply) slaStats {
+	stats := slaStats{Target: oa.slaTarget}
+
+	categories := make(map[*emailCategory]*slaGroup)
+	for _, category := range oa.classifier.categories() {
+		categories[category] = &slaGroup{Name: category.Name}
+	}
+	senders := make(map[string]*slaGroup)
+
+	for _, reply := range replies {
+		elapsed := oa.calendar.businessDuration(reply.Original.ReceivedTime, reply.Reply.SentTime)
+		met := elapsed <= oa.slaTarget
+
+		sender := oa.contacts.senderIdentity(reply.Original)
+		if senders[sender] == nil {
+			senders[sender] = &slaGroup{Name: oa.contacts.label(sender)}
+		}
+
+		groups := []*slaGroup{&stats.Overall, senders[sender]}
+		// 一封邮件属于多个分类时计入每个分类
+		for _, label := range oa.classifier.labels(reply.Original) {
+			groups = append(groups, categories[label.Category])
+		}
+		for _, group := range groups {
+			group.Total++
+			if met {
+				group.Met++
+			}
+			group.latencies = append(group.latencies, elapsed)
+		}
+	}
+
+	finish := func(group *slaGroup) {
+		sort.Slice(group.latencies, func(i, j int) bool { return group.latencies[i] < group.latencies[j] })
+		group.Median = percentile(group.latencies, 0.5)
+	}
+	finish(&stats.Overall)
+	for _, category := range oa.classifier.categories() {
+		if group := categories[category]; group.Total > 0 {
+			finish(group)
+			stats.ByCategory = append(stats.ByCategory, *group)
+		}
+	}
+	for _, group := range senders {
+		finish(group)
+		stats.BySender = append(stats.BySender, *group)
+	}
+	sort.Slice(stats.BySender, func(i, j int) bool {
+		if stats.BySender[i].Total != stats.BySender[j].Total {
+			return stats.BySender[i].Total > stats.BySender[j].Total
+		}
+		return stats.BySender[i].Name < stats.BySender[j].Name
+	})
+	if len(stats.BySender) > maxSLASenders {
+		stats.BySender = stats.BySender[:maxSLASenders]
+	}
+	return stats
+}
+
+func (oa *OutlookEmailAnalyzer) printSLA(stats slaStats) {
+	fmt.Printf("\n🎯 SLA达成率 (目标: %s工作时间内回复, 工作日历: %s, 时区: %s):\n",
+		formatSLATarget(stats.Target), oa.calendar.name, oa.calendar.location)
+	if stats.Overall.Total == 0 {
+		fmt.Printf("   无回复数据\n")
+		return
+	}
+	fmt.Printf("   总体: %d/%d 封 (%.1f%%)，工作时间回复中位数: %s\n",
+		stats.Overall.Met, stats.Overall.Total, stats.Overall.attainment(), formatLatency(stats.Overall.Median))
+
+	fmt.Printf("   按分类:\n")
+	for _, group := range stats.ByCategory {
+		printSLAGroup(group, 12)
+	}
+
+	fmt.Printf("   按发件人 (回复最多的前%d名):\n", maxSLASenders)
+	width := 0
+	for _, group := range stats.BySender {
+		if w := displayWidth(group.Name); w > width {
+			width = w
+		}
+	}
+	for _, group := range stats.BySender {
+		printSLAGroup(group, width+2)
+	}
+}
+
+func printSLAGroup(group slaGroup, width int) {
+	fmt.Printf("     %s %7s 封 (%5.1f%%) 中位数 %s\n", padDisplay(group.Name, width),
+		fmt.Sprintf("%d/%d", group.Met, group.Total), group.attainment(), formatLatency(group.Median))
+}
+
+// formatSLATarget 整小时的目标显示为“4小时”，其余按回复时长的格式显示
+func formatSLATarget(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%d小时", int(d.Hours()))
+	}
+	return formatLatency(d)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/unicode"
+)
+
+// CsvSource 读取Outlook“文件 > 打开和导出 > 导入/导出 > 导出到文件 > 逗号分隔值”生成的CSV文件。
+// 发送邮件来自sentPath（导出的“已发送邮件”文件夹），或者发件人为当前账户的行
+type CsvSource struct {
+	path     string
+	sentPath string
+
+	messages []csvMessage
+	loaded   bool
+	// 导出时没有映射日期字段，无法按日期过滤
+	undated bool
+}
+
+type csvMessage struct {
+	info   EmailInfo
+	isSent bool
+}
+
+// CSV列对应的字段
+const (
+	csvSubject = iota
+	csvBody
+	csvFromName
+	csvFromAddress
+	csvToName
+	csvToAddress
+	csvCCName
+	csvCCAddress
+	csvReceived
+	csvSent
+	csvDate
+	csvRead
+	csvUnread
+)
+
+// csvColumnNames 是各字段在英文、简体中文和繁体中文Outlook中的列名，比较前经过normalizeCSVHeader处理。
+// 默认的邮件导出映射不含日期和已读状态，这些列需要在导出时通过“映射自定义字段”添加
+var csvColumnNames = map[string]int{
+	"subject": csvSubject, "主题": csvSubject, "主旨": csvSubject,
+	"body": csvBody, "正文": csvBody, "內文": csvBody,
+	"from:(name)": csvFromName, "发件人:(姓名)": csvFromName, "寄件者:(名稱)": csvFromName, "寄件者:(姓名)": csvFromName,
+	"from:(address)": csvFromAddress, "发件人:(地址)": csvFromAddress, "寄件者:(地址)": csvFromAddress,
+	"to:(name)": csvToName, "收件人:(姓名)": csvToName, "收件者:(名稱)": csvToName, "收件者:(姓名)": csvToName,
+	"to:(address)": csvToAddress, "收件人:(地址)": csvToAddress, "收件者:(地址)": csvToAddress,
+	"cc:(name)": csvCCName, "抄送:(姓名)": csvCCName, "副本:(名稱)": csvCCName, "副本:(姓名)": csvCCName,
+	"cc:(address)": csvCCAddress, "抄送:(地址)": csvCCAddress, "副本:(地址)": csvCCAddress,
+	"received": csvReceived, "receiveddate": csvReceived, "datereceived": csvReceived, "receivedtime": csvReceived,
+	"收到时间": csvReceived, "接收时间": csvReceived, "收到日期": csvReceived, "接收日期": csvReceived, "收件日期": csvReceived,
+	"sent": csvSent, "senton": csvSent, "sentdate": csvSent, "datesent": csvSent, "senttime": csvSent,
+	"发送时间": csvSent, "发送日期": csvSent, "已发送": csvSent, "寄件日期": csvSent, "寄件時間": csvSent,
+	"date": csvDate, "日期": csvDate,
+	"read": csvRead, "已读": csvRead, "已讀": csvRead,
+	"unread": csvUnread, "未读": csvUnread, "未讀": csvUnread,
+}
+
+// Outlook按系统区域设置输出日期，常见格式如下。斜杠格式的英文日期按美国习惯（月/日/年）解析
+var csvDateLayouts = []string{
+	"2006/1/2 15:04:05", "2006/1/2 15:04", "2006/1/2 3:04:05 PM", "2006/1/2 3:04 PM", "2006/1/2",
+	"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02",
+	"1/2/2006 15:04:05", "1/2/2006 15:04", "1/2/2006 3:04:05 PM", "1/2/2006 3:04 PM", "1/2/2006",
+	"2006年1月2日 15:04:05", "2006年1月2日 15:04", "2006年1月2日",
+	"Mon 1/2/2006 3:04 PM", "Monday, January 2, 2006 3:04 PM",
+}
+
+func NewCsvSource(path, sentPath string) (*CsvSource, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("无法打开CSV文件: %v", err)
+	}
+	return &CsvSource{path: path, sentPath: sentPath}, nil
+}
+
+func (cs *CsvSource) Close() {}
+
+func (cs *CsvSource) ReceivedEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	if err := cs.load(); err != nil {
+		return nil, err
+	}
+
+	var emails []EmailInfo
+	for _, msg := range cs.messages {
+		if msg.isSent || isOwnAddress(msg.info.SenderEmail, account) {
+			continue
+		}
+		if cs.inRange(msg.info.ReceivedTime, startDate, endDate) {
+			emails = append(emails, msg.info)
+		}
+	}
+	fmt.Printf("✓ 总共找到 %d 封邮件\n", len(emails))
+	return emails, nil
+}
+
+func (cs *CsvSource) SentEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	if err := cs.load(); err != nil {
+		return nil, err
+	}
+
+	var sentEmails []EmailInfo
+	for _, msg := range cs.messages {
+		if !msg.isSent && !isOwnAddress(msg.info.SenderEmail, account) {
+			continue
+		}
+		if cs.inRange(msg.info.SentTime, startDate, endDate) {
+			sentEmails = append(sentEmails, msg.info)
+		}
+	}
+	fmt.Printf("✓ 找到 %d 封发送邮件\n", len(sentEmails))
+	return sentEmails, nil
+}
+
+func (cs *CsvSource) isUndated() bool {
+	return cs.undated
+}
+
+// inRange 没有日期的行无法过滤，全部保留
+func (cs *CsvSource) inRange(t, startDate, endDate time.Time) bool {
+	return t.IsZero() || inDateRange(t, startDate, endDate)
+}
+
+func (cs *CsvSource) load() error {
+	if cs.loaded {
+		return nil
+	}
+
+	fmt.Printf("正在读取CSV文件: %s\n", cs.path)
+	if err := cs.readFile(cs.path, false); err != nil {
+		return err
+	}
+	if cs.sentPath != "" {
+		fmt.Printf("正在读取发送邮件CSV文件: %s\n", cs.sentPath)
+		if err := cs.readFile(cs.sentPath, true); err != nil {
+			fmt.Printf("⚠️  读取发送邮件失败: %v\n", err)
+		}
+	}
+	if cs.undated {
+		fmt.Println("⚠️  CSV文件中没有日期列，无法按日期范围过滤，将分析所有邮件")
+		fmt.Println("   导出时可在“映射自定义字段”中添加“收到时间”和“发送时间”")
+	}
+
+	cs.loaded = true
+	return nil
+}
+
+func (cs *CsvSource) readFile(path string, isSent bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("无法打开CSV文件: %v", err)
+	}
+
+	reader := csv.NewReader(strings.NewReader(decodeCSVText(data)))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("解析CSV文件失败: %v", err)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("CSV文件为空")
+	}
+
+	columns := make(map[int]int)
+	for i, name := range records[0] {
+		if field, ok := csvColumnNames[normalizeCSVHeader(name)]; ok {
+			if _, exists := columns[field]; !exists {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns[csvSubject]; !ok {
+		return fmt.Errorf("不是Outlook导出的邮件CSV文件（找不到“主题/Subject”列）")
+	}
+	_, hasReceived := columns[csvReceived]
+	_, hasSent := columns[csvSent]
+	_, hasDate := columns[csvDate]
+	if !hasReceived && !hasSent && !hasDate {
+		cs.undated = true
+	}
+
+	count := 0
+	for _, record := range records[1:] {
+		field := func(f int) string {
+			if i, ok := columns[f]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		info := EmailInfo{
+			Subject:     field(csvSubject),
+			SenderName:  field(csvFromName),
+			SenderEmail: field(csvFromAddress),
+			To:          csvRecipients(field(csvToName), field(csvToAddress)),
+			CC:          csvRecipients(field(csvCCName), field(csvCCAddress)),
+			Body:        truncateBody(field(csvBody)),
+			Folder:      filepath.Base(path),
+		}
+		info.Recipients = append(csvRecipientList(field(csvToName), field(csvToAddress), RecipientTo),
+			csvRecipientList(field(csvCCName), field(csvCCAddress), RecipientCC)...)
+		date := parseCSVDate(field(csvDate))
+		info.ReceivedTime = parseCSVDate(field(csvReceived))
+		info.SentTime = parseCSVDate(field(csvSent))
+		if info.ReceivedTime.IsZero() {
+			info.ReceivedTime = date
+		}
+		if info.SentTime.IsZero() {
+			info.SentTime = date
+		}
+		if info.ReceivedTime.IsZero() {
+			info.ReceivedTime = info.SentTime
+		}
+		if info.SentTime.IsZero() {
+			info.SentTime = info.ReceivedTime
+		}
+
+		if value, ok := parseCSVBool(field(csvRead)); ok {
+			info.IsRead = value
+		} else if value, ok := parseCSVBool(field(csvUnread)); ok {
+			info.IsRead = !value
+		}
+
+		if info.Subject == "" {
+			continue
+		}
+		cs.messages = append(cs.messages, csvMessage{info: info, isSent: isSent})
+		count++
+	}
+
+	fmt.Printf("  ✓ 读取 %d 封邮件\n", count)
+	return nil
+}
+
+// decodeCSVText 处理Outlook导出文件的编码：中文系统下为ANSI（GBK），较新版本可能带UTF-8或UTF-16 BOM
+func decodeCSVText(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:])
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data, unicode.LittleEndian)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data, unicode.BigEndian)
+	case utf8.Valid(data):
+		return string(data)
+	}
+	return decodeCharset(data, fallbackCharset)
+}
+
+// decodeUTF16 按BOM指示的字节序解码，ExpectBOM会去掉BOM，不会留下U+FEFF
+func decodeUTF16(data []byte, endianness unicode.Endianness) string {
+	text, err := unicode.UTF16(endianness, unicode.ExpectBOM).NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(text)
+}
+
+// normalizeCSVHeader 统一全角标点、大小写和空格，如 "发件人：（姓名）" 与 "From: (Name)" 的写法差异；
+// 并去掉解码后可能残留在第一个列名前的BOM
+func normalizeCSVHeader(name string) string {
+	name = strings.NewReplacer("：", ":", "（", "(", "）", ")", " ", "", "　", "").Replace(name)
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+}
+
+// csvRecipients 将Outlook导出的分号分隔名称转换为与To/CC属性相同的格式，没有名称时使用地址
+func csvRecipients(names, addresses string) string {
+	value := names
+	if value == "" {
+		value = addresses
+	}
+	return strings.Join(splitCSVList(value), "; ")
+}
+
+// csvRecipientList 按位置对应名称列和地址列中分号分隔的各项。两列项数不同时无法对应，只保留名称
+func csvRecipientList(names, addresses string, kind RecipientType) []Recipient {
+	nameList, addressList := splitCSVList(names), splitCSVList(addresses)
+	if len(nameList) == 0 {
+		nameList = make([]string, len(addressList))
+	}
+	if len(addressList) != len(nameList) {
+		addressList = make([]string, len(nameList))
+	}
+	var recipients []Recipient
+	for i, name := range nameList {
+		recipients = append(recipients, Recipient{Name: name, Address: addressList[i], Type: kind, Resolved: addressList[i] != ""})
+	}
+	return recipients
+}
+
+func splitCSVList(value string) []string {
+	var parts []string
+	for _, part := range strings.Split(value, ";") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func parseCSVDate(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	// 中文区域设置的12小时制写作 "2025/3/2 下午 3:04"
+	for marker, suffix := range map[string]string{"上午": "AM", "下午": "PM"} {
+		if strings.Contains(value, marker) {
+			value = strings.TrimSpace(strings.Replace(value, marker, "", 1)) + " " + suffix
+		}
+	}
+	value = strings.Join(strings.Fields(value), " ")
+	for _, layout := range csvDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func parseCSVBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "1", "是", "真":
+		return true, true
+	case "false", "no", "0", "否", "假":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// 简体中文Outlook“导出到文件 > 逗号分隔值（Windows）”的默认邮件映射，另加了“收到时间”列
+const csvOutlookExportZH = "\"主题\",\"正文\",\"发件人: (姓名)\",\"发件人: (地址)\",\"发件人: (类型)\"," +
+	"\"收件人: (姓名)\",\"收件人: (地址)\",\"收件人: (类型)\",\"抄送: (姓名)\",\"抄送: (地址)\",\"抄送: (类型)\"," +
+	"\"密件抄送: (姓名)\",\"密件抄送: (地址)\",\"密件抄送: (类型)\",\"记帐信息\",\"类别\",\"重要性\",\"里程\",\"敏感度\",\"收到时间\"\r\n" +
+	"\"季度预算审批\",\"请审批附件中的预算。\r\n谢谢\",\"张三\",\"zhangsan@example.com\",\"SMTP\"," +
+	"\"李四;王五\",\"lisi@example.com;wangwu@example.com\",\"SMTP;SMTP\",\"\",\"\",\"\"," +
+	"\"\",\"\",\"\",\"\",\"\",\"普通\",\"\",\"普通\",\"2025/3/4 9:30:00\"\r\n"
+
+func encodeUTF16(t *testing.T, text string, endianness unicode.Endianness) []byte {
+	t.Helper()
+	data, err := unicode.UTF16(endianness, unicode.UseBOM).NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodeCSVText(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("主题,正文"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"utf-8", []byte("主题,正文")},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, "主题,正文"...)},
+		{"utf-16le bom", encodeUTF16(t, "主题,正文", unicode.LittleEndian)},
+		{"utf-16be bom", encodeUTF16(t, "主题,正文", unicode.BigEndian)},
+		{"ansi gbk", gbk},
+	}
+	for _, tt := range tests {
+		if got := decodeCSVText(tt.data); got != "主题,正文" {
+			t.Errorf("%s: decodeCSVText = %q", tt.name, got)
+		}
+	}
+}
+
+func TestNormalizeCSVHeader(t *testing.T) {
+	tests := map[string]string{
+		"From: (Name)":  "from:(name)",
+		"发件人：（姓名）":      "发件人:(姓名)",
+		"\ufeff主题":      "主题",
+		"\ufeffSubject": "subject",
+		" 收到　时间 ":       "收到时间",
+	}
+	for input, want := range tests {
+		if got := normalizeCSVHeader(input); got != want {
+			t.Errorf("normalizeCSVHeader(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestCsvSourceReadsUTF16Export(t *testing.T) {
+	for _, endianness := range []unicode.Endianness{unicode.LittleEndian, unicode.BigEndian} {
+		path := filepath.Join(t.TempDir(), "收件箱.csv")
+		if err := os.WriteFile(path, encodeUTF16(t, csvOutlookExportZH, endianness), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cs, err := NewCsvSource(path, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)
+		emails, err := cs.ReceivedEmails("", start, start.AddDate(0, 1, 0))
+		if err != nil {
+			t.Fatalf("endianness %v: ReceivedEmails: %v", endianness, err)
+		}
+		if len(emails) != 1 {
+			t.Fatalf("endianness %v: got %d emails", endianness, len(emails))
+		}
+		email := emails[0]
+		// 第一列“主题”前的BOM若未去掉，主题列就找不到
+		if email.Subject != "季度预算审批" || email.SenderEmail != "zhangsan@example.com" || email.Folder != "收件箱.csv" {
+			t.Errorf("email = %q from %q in %q", email.Subject, email.SenderEmail, email.Folder)
+		}
+		if email.Body != "请审批附件中的预算。\n谢谢" {
+			t.Errorf("body = %q", email.Body)
+		}
+		if email.To != "李四; 王五" || len(email.Recipients) != 2 || email.Recipients[1].Address != "wangwu@example.com" {
+			t.Errorf("To = %q, recipients = %+v", email.To, email.Recipients)
+		}
+		if want := time.Date(2025, 3, 4, 9, 30, 0, 0, time.Local); !email.ReceivedTime.Equal(want) {
+			t.Errorf("ReceivedTime = %v, want %v", email.ReceivedTime, want)
+		}
+		if cs.undated {
+			t.Error("export with 收到时间 column reported as undated")
+		}
+	}
+}
+
+// TestCsvSourceUndatedExport 默认映射导出的CSV没有日期列：不再读取之后的邮件，回复也不计入时效统计
+func TestCsvSourceUndatedExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.csv")
+	content := "Subject,Body,From: (Name),From: (Address),To: (Name),To: (Address),Read\r\n" +
+		"季度预算,请确认,张三,zhangsan@example.com,我,me@example.com,True\r\n" +
+		"RE: 季度预算,已确认,我,me@example.com,张三,zhangsan@example.com,True\r\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cs, err := NewCsvSource(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oa := NewOutlookEmailAnalyzer(cs)
+	oa.calendar = newDefaultCalendar(time.Local)
+	end := time.Date(2025, 3, 31, 0, 0, 0, 0, time.Local)
+	received, err := cs.ReceivedEmails("me@example.com", end.AddDate(0, -1, 0), end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent, err := cs.SentEmails("me@example.com", end.AddDate(0, -1, 0), end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cs.isUndated() || len(received) != 1 || len(sent) != 1 {
+		t.Fatalf("undated = %v, received %d, sent %d", cs.isUndated(), len(received), len(sent))
+	}
+
+	if laterReceived, laterSent, complete := oa.fetchLaterEmails("me@example.com", end, end.AddDate(0, 1, 0)); laterReceived != nil || laterSent != nil || !complete {
+		t.Errorf("later mail = %v / %v (complete %v)", laterReceived, laterSent, complete)
+	}
+
+	repliedCount, sameDayReplies, replies := oa.findRepliedEmails(received, sent)
+	if repliedCount != 1 || sameDayReplies != 0 || len(replies) != 0 {
+		t.Errorf("replied = %d, same day = %d, dated replies = %d", repliedCount, sameDayReplies, len(replies))
+	}
+	if latency := oa.analyzeReplyLatency(replies); latency.Count != 0 {
+		t.Errorf("latency count = %d", latency.Count)
+	}
+	if sla := oa.analyzeSLA(replies); sla.Overall.Total != 0 {
+		t.Errorf("SLA total = %d", sla.Overall.Total)
+	}
+}
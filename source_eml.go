@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MessageDirSource 读取目录（含子目录）中的单封邮件文件（.eml、.msg）。
+// 发送邮件来自sentDir，或者发件人为当前账户的邮件
+type MessageDirSource struct {
+	dir      string
+	sentDir  string
+	ext      string
+	readFile func(path string) (EmailInfo, error)
+}
+
+func newMessageDirSource(dir, sentDir, ext string, readFile func(string) (EmailInfo, error)) (*MessageDirSource, error) {
+	stat, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("%s 不是目录", dir)
+	}
+	return &MessageDirSource{dir: dir, sentDir: sentDir, ext: ext, readFile: readFile}, nil
+}
+
+func NewEmlSource(dir, sentDir string) (*MessageDirSource, error) {
+	source, err := newMessageDirSource(dir, sentDir, ".eml", readMessageFile)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开.eml目录: %v", err)
+	}
+	return source, nil
+}
+
+func (es *MessageDirSource) Close() {}
+
+func (es *MessageDirSource) ReceivedEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	fmt.Printf("正在读取目录: %s\n", es.dir)
+	var emails []EmailInfo
+	err := es.walk(es.dir, func(path string, info EmailInfo) {
+		if isOwnAddress(info.SenderEmail, account) {
+			return
+		}
+		if inDateRange(info.ReceivedTime, startDate, endDate) {
+			emails = append(emails, info)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取%s目录失败: %v", es.ext, err)
+	}
+
+	fmt.Printf("✓ 总共找到 %d 封邮件\n", len(emails))
+	return emails, nil
+}
+
+func (es *MessageDirSource) SentEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	fmt.Println("正在获取发送邮件...")
+	var sentEmails []EmailInfo
+	keep := func(path string, info EmailInfo) {
+		if inDateRange(info.SentTime, startDate, endDate) {
+			sentEmails = append(sentEmails, info)
+		}
+	}
+
+	if es.sentDir != "" {
+		if err := es.walk(es.sentDir, keep); err != nil {
+			fmt.Printf("⚠️  读取发送邮件目录失败: %v\n", err)
+		}
+	}
+	if account != "" && account != "default" {
+		err := es.walk(es.dir, func(path string, info EmailInfo) {
+			if isOwnAddress(info.SenderEmail, account) {
+				keep(path, info)
+			}
+		})
+		if err != nil {
+			return sentEmails, fmt.Errorf("读取发送邮件失败: %v", err)
+		}
+	}
+
+	fmt.Printf("✓ 找到 %d 封发送邮件\n", len(sentEmails))
+	return sentEmails, nil
+}
+
+// walk 遍历目录中所有指定扩展名的文件，发送邮件目录位于dir内部时跳过该目录
+func (es *MessageDirSource) walk(dir string, handle func(path string, info EmailInfo)) error {
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			fmt.Printf("  ⚠️  无法访问 %s: %v\n", path, err)
+			return nil
+		}
+		if entry.IsDir() {
+			if path != dir && es.sentDir != "" && dir != es.sentDir && sameFile(path, es.sentDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.EqualFold(filepath.Ext(path), es.ext) {
+			return nil
+		}
+
+		info, err := es.readFile(path)
+		if err != nil {
+			fmt.Printf("  ⚠️  跳过无法解析的邮件 %s: %v\n", filepath.Base(path), err)
+			return nil
+		}
+		// 文件夹为相对于dir的子目录
+		info.Folder = filepath.Base(dir)
+		if rel, err := filepath.Rel(dir, filepath.Dir(path)); err == nil && rel != "." {
+			info.Folder = filepath.ToSlash(rel)
+		}
+		handle(path, info)
+		count++
+		if count%200 == 0 {
+			fmt.Printf("  已读取 %d 封邮件...\n", count)
+		}
+		return nil
+	})
+	return err
+}
+
+func readMessageFile(path string) (EmailInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return EmailInfo{}, err
+	}
+	defer file.Close()
+
+	info, _, err := parseMessage(file)
+	if err != nil {
+		return info, err
+	}
+	if info.SentTime.IsZero() || info.ReceivedTime.IsZero() {
+		if stat, err := file.Stat(); err == nil {
+			if info.SentTime.IsZero() {
+				info.SentTime = stat.ModTime()
+			}
+			if info.ReceivedTime.IsZero() {
+				info.ReceivedTime = stat.ModTime()
+			}
+		}
+	}
+	return info, nil
+}
+
+func sameFile(a, b string) bool {
+	statA, errA := os.Stat(a)
+	statB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(statA, statB)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageDirSource(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, from, subject string, date time.Time) {
+		t.Helper()
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		content := fmt.Sprintf("From: %s\r\nTo: me@example.com\r\nSubject: %s\r\nDate: %s\r\n\r\nbody\r\n", from, subject, date.Format(time.RFC1123Z))
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	day := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 10, 0, 0, 0, time.UTC) }
+	write("a.eml", "pm@example.com", "顶层", day(3, 3))
+	write("sub/deeper/b.EML", "pm@example.com", "子目录", day(3, 4))
+	write("old.eml", "pm@example.com", "范围之外", day(1, 1))
+	write("notes.txt", "pm@example.com", "不是邮件", day(3, 3))
+	// 收件目录中自己发出的邮件
+	write("mine.eml", "Me@Example.com", "自己发的", day(3, 5))
+	// 位于收件目录内的发送目录
+	write("Sent/r.eml", "me@example.com", "RE: 顶层", day(3, 6))
+	write("Sent/old.eml", "me@example.com", "RE: 旧邮件", day(1, 2))
+
+	source, err := NewEmlSource(dir, filepath.Join(dir, "Sent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, end := day(3, 1), day(3, 31)
+	subjects := func(emails []EmailInfo) string {
+		var list []string
+		for _, email := range emails {
+			list = append(list, email.Folder+":"+email.Subject)
+		}
+		sort.Strings(list)
+		return strings.Join(list, " ")
+	}
+	sorted := func(list ...string) string {
+		sort.Strings(list)
+		return strings.Join(list, " ")
+	}
+	top := filepath.Base(dir)
+
+	received, err := source.ReceivedEmails("me@example.com", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := subjects(received); got != sorted(top+":顶层", "sub/deeper:子目录") {
+		t.Errorf("received = %s", got)
+	}
+	sent, err := source.SentEmails("me@example.com", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := subjects(sent); got != sorted(top+":自己发的", "Sent:RE: 顶层") {
+		t.Errorf("sent = %s", got)
+	}
+
+	// 默认账户无法按地址判断，只有发送目录中的邮件是发送邮件
+	received, _ = source.ReceivedEmails("default", start, end)
+	sent, _ = source.SentEmails("default", start, end)
+	if len(received) != 3 || subjects(sent) != "Sent:RE: 顶层" {
+		t.Errorf("default account: received %s, sent %s", subjects(received), subjects(sent))
+	}
+
+	if _, err := NewEmlSource(filepath.Join(dir, "a.eml"), ""); err == nil {
+		t.Error("file accepted as .eml directory")
+	}
+}
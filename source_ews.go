@@ -0,0 +1,532 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EwsSource 通过Exchange Web Services读取本地部署的Exchange邮箱（Exchange 2013及以上）。
+// 文件夹遍历与Outlook数据源一致：收件箱及其所有子文件夹按DateTimeReceived过滤，已发送邮件按DateTimeSent过滤
+type EwsSource struct {
+	client   *http.Client
+	endpoint string
+	user     string
+	password string
+	// 认证方式在第一次收到401时根据WWW-Authenticate确定：ntlm 或 basic
+	auth string
+}
+
+const (
+	ewsPageSize     = 100
+	ewsGetItemBatch = 50
+	ewsMaxRetries   = 5
+)
+
+const ewsEnvelopeFormat = `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:t="http://schemas.microsoft.com/exchange/services/2006/types" xmlns:m="http://schemas.microsoft.com/exchange/services/2006/messages">
+<soap:Header><t:RequestServerVersion Version="Exchange2013"/></soap:Header>
+<soap:Body>%s</soap:Body>
+</soap:Envelope>`
+
+// ewsItemProperties 是GetItem请求的属性，只包含EmailInfo需要的部分
+var ewsItemProperties = []string{"item:Subject", "message:From", "message:ToRecipients", "message:CcRecipients",
+	"message:BccRecipients", "item:DateTimeReceived", "item:DateTimeSent", "message:IsRead", "item:Body",
+	"message:InternetMessageId", "message:InReplyTo", "message:References", "message:ConversationIndex",
+	"item:InternetMessageHeaders", "item:ItemClass"}
+
+type ewsEnvelope struct {
+	Body struct {
+		Fault struct {
+			FaultString string `xml:"faultstring"`
+		} `xml:"Fault"`
+		Response struct {
+			ResponseMessages struct {
+				Messages []ewsResponseMessage `xml:",any"`
+			} `xml:"ResponseMessages"`
+		} `xml:",any"`
+	} `xml:"Body"`
+}
+
+type ewsResponseMessage struct {
+	ResponseClass string `xml:"ResponseClass,attr"`
+	MessageText   string `xml:"MessageText"`
+	ResponseCode  string `xml:"ResponseCode"`
+	BackOff       string `xml:"MessageXml>Value"`
+	RootFolder    struct {
+		IncludesLastItemInRange bool        `xml:"IncludesLastItemInRange,attr"`
+		Folders                 []ewsFolder `xml:"Folders>Folder"`
+		Items                   ewsItemList `xml:"Items"`
+	} `xml:"RootFolder"`
+	Folders []ewsFolder `xml:"Folders>Folder"`
+	Items   ewsItemList `xml:"Items"`
+}
+
+// ewsItemList 中的元素可能是Message、MeetingRequest等不同类型，按相同字段解析
+type ewsItemList struct {
+	Items []ewsItem `xml:",any"`
+}
+
+type ewsFolder struct {
+	FolderID    ewsID  `xml:"FolderId"`
+	ParentID    ewsID  `xml:"ParentFolderId"`
+	DisplayName string `xml:"DisplayName"`
+	FolderClass string `xml:"FolderClass"`
+}
+
+// ewsID 对应FolderId、ItemId等只有Id属性有意义的元素
+type ewsID struct {
+	ID string `xml:"Id,attr"`
+}
+
+type ewsMailbox struct {
+	Name         string `xml:"Name"`
+	EmailAddress string `xml:"EmailAddress"`
+	RoutingType  string `xml:"RoutingType"`
+}
+
+type ewsItem struct {
+	ItemID           ewsID        `xml:"ItemId"`
+	Subject          string       `xml:"Subject"`
+	From             ewsMailbox   `xml:"From>Mailbox"`
+	ToRecipients     []ewsMailbox `xml:"ToRecipients>Mailbox"`
+	CcRecipients     []ewsMailbox `xml:"CcRecipients>Mailbox"`
+	BccRecipients    []ewsMailbox `xml:"BccRecipients>Mailbox"`
+	DateTimeReceived string       `xml:"DateTimeReceived"`
+	DateTimeSent     string       `xml:"DateTimeSent"`
+	IsRead           bool         `xml:"IsRead"`
+	Body             struct {
+		BodyType string `xml:"BodyType,attr"`
+		Text     string `xml:",chardata"`
+	} `xml:"Body"`
+	InternetMessageID string `xml:"InternetMessageId"`
+	InReplyTo         string `xml:"InReplyTo"`
+	References        string `xml:"References"`
+	ConversationIndex string `xml:"ConversationIndex"`
+	ItemClass         string `xml:"ItemClass"`
+	Headers           []struct {
+		Name  string `xml:"HeaderName,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"InternetMessageHeaders>InternetMessageHeader"`
+}
+
+// NewEwsSource 连接EWS终结点。server 可以是完整的 https://主机/EWS/Exchange.asmx 地址，
+// 也可以只写主机名；user 可以是 "域\用户名" 或 UPN
+func NewEwsSource(server, user, password string) (*EwsSource, error) {
+	if server == "" || user == "" {
+		return nil, fmt.Errorf("EWS数据源需要 -server 和 -user 参数")
+	}
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("无效的EWS地址: %s", server)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/EWS/Exchange.asmx"
+	}
+
+	// NTLM认证的是TCP连接，三次握手必须在同一连接上完成，因此只保留一个连接
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = 1
+	es := &EwsSource{
+		client:   &http.Client{Timeout: 2 * time.Minute, Transport: transport},
+		endpoint: u.String(),
+		user:     user,
+		password: password,
+	}
+
+	fmt.Printf("正在连接EWS: %s\n", es.endpoint)
+	inbox, err := es.getFolder("inbox")
+	if err != nil {
+		return nil, fmt.Errorf("无法访问Exchange邮箱: %v", err)
+	}
+	fmt.Printf("✓ 已连接Exchange（%s认证），收件箱: %s\n", strings.ToUpper(es.auth), inbox.DisplayName)
+	return es, nil
+}
+
+func (es *EwsSource) Close() {
+	es.client.CloseIdleConnections()
+}
+
+func (es *EwsSource) ReceivedEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	folders, err := es.getInboxFolders()
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []EmailInfo
+	fmt.Printf("正在分析 %d 个文件夹的邮件...\n", len(folders))
+	for folderIndex, folder := range folders {
+		fmt.Printf("正在读取文件夹 %d/%d: %s\n", folderIndex+1, len(folders), folder.DisplayName)
+		folderEmails, err := es.getEmailsInDateRange(`<t:FolderId Id="`+xmlEscape(folder.FolderID.ID)+`"/>`, "item:DateTimeReceived", startDate, endDate)
+		if err != nil {
+			fmt.Printf("  ⚠️  无法访问文件夹内容: %v\n", err)
+			continue
+		}
+		fmt.Printf("  ✓ 找到 %d 封符合条件的邮件\n", len(folderEmails))
+		setFolder(folderEmails, folder.DisplayName)
+		emails = append(emails, folderEmails...)
+	}
+
+	fmt.Printf("✓ 总共找到 %d 封邮件\n", len(emails))
+	return emails, nil
+}
+
+func (es *EwsSource) SentEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	fmt.Println("正在获取发送邮件...")
+
+	sentEmails, err := es.getEmailsInDateRange(`<t:DistinguishedFolderId Id="sentitems"/>`, "item:DateTimeSent", startDate, endDate)
+	if err != nil {
+		fmt.Printf("⚠️  无法访问发送文件夹: %v\n", err)
+		fmt.Println("   跳过发送邮件分析，继续其他功能...")
+		return []EmailInfo{}, nil
+	}
+
+	fmt.Printf("✓ 找到 %d 封发送邮件\n", len(sentEmails))
+	return sentEmails, nil
+}
+
+func (es *EwsSource) getFolder(distinguishedID string) (ewsFolder, error) {
+	body := `<m:GetFolder><m:FolderShape><t:BaseShape>Default</t:BaseShape></m:FolderShape>` +
+		`<m:FolderIds><t:DistinguishedFolderId Id="` + distinguishedID + `"/></m:FolderIds></m:GetFolder>`
+	messages, err := es.call(body)
+	if err != nil {
+		return ewsFolder{}, err
+	}
+	if len(messages) == 0 || len(messages[0].Folders) == 0 {
+		return ewsFolder{}, fmt.Errorf("服务器没有返回文件夹 %s", distinguishedID)
+	}
+	return messages[0].Folders[0], nil
+}
+
+// getInboxFolders 用Deep遍历的FindFolder一次取得收件箱下的所有文件夹，再按ParentFolderId拼出完整路径。
+// 日历、联系人等非邮件文件夹的FolderClass不是IPF.Note，予以跳过
+func (es *EwsSource) getInboxFolders() ([]ewsFolder, error) {
+	inbox, err := es.getFolder("inbox")
+	if err != nil {
+		return nil, fmt.Errorf("无法访问收件箱: %v", err)
+	}
+	fmt.Printf("✓ 使用收件箱: %s\n", inbox.DisplayName)
+
+	var subfolders []ewsFolder
+	for offset := 0; ; {
+		body := `<m:FindFolder Traversal="Deep"><m:FolderShape><t:BaseShape>Default</t:BaseShape>` +
+			`<t:AdditionalProperties><t:FieldURI FieldURI="folder:FolderClass"/><t:FieldURI FieldURI="folder:ParentFolderId"/></t:AdditionalProperties>` +
+			`</m:FolderShape>` + ewsPageView("IndexedPageFolderView", offset) +
+			`<m:ParentFolderIds><t:DistinguishedFolderId Id="inbox"/></m:ParentFolderIds></m:FindFolder>`
+		messages, err := es.call(body)
+		if err != nil {
+			fmt.Printf("⚠️  无法获取子文件夹: %v\n", err)
+			break
+		}
+		if len(messages) == 0 {
+			break
+		}
+		root := messages[0].RootFolder
+		subfolders = append(subfolders, root.Folders...)
+		offset += len(root.Folders)
+		if root.IncludesLastItemInRange || len(root.Folders) == 0 {
+			break
+		}
+	}
+
+	names := map[string]string{inbox.FolderID.ID: inbox.DisplayName}
+	parents := make(map[string]string)
+	for _, folder := range subfolders {
+		names[folder.FolderID.ID] = folder.DisplayName
+		parents[folder.FolderID.ID] = folder.ParentID.ID
+	}
+
+	folders := []ewsFolder{inbox}
+	for _, folder := range subfolders {
+		if folder.FolderClass != "" && !strings.HasPrefix(folder.FolderClass, "IPF.Note") {
+			continue
+		}
+		path := folder.DisplayName
+		for parent, depth := parents[folder.FolderID.ID], 0; parent != "" && depth < 32; parent, depth = parents[parent], depth+1 {
+			path = names[parent] + "/" + path
+		}
+		folder.DisplayName = path
+		folders = append(folders, folder)
+	}
+	fmt.Printf("✓ 总共找到 %d 个文件夹\n", len(folders))
+	return folders, nil
+}
+
+// getEmailsInDateRange 用FindItem的Restriction在服务器端过滤，范围与Outlook数据源的Restrict相同：
+// [开始日期, 结束日期+1天]。FindItem只取ItemId，再分批用GetItem读取正文和已读状态
+func (es *EwsSource) getEmailsInDateRange(folderID, dateField string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	restriction := fmt.Sprintf(`<m:Restriction><t:And>`+
+		`<t:IsGreaterThanOrEqualTo><t:FieldURI FieldURI="%[1]s"/><t:FieldURIOrConstant><t:Constant Value="%[2]s"/></t:FieldURIOrConstant></t:IsGreaterThanOrEqualTo>`+
+		`<t:IsLessThanOrEqualTo><t:FieldURI FieldURI="%[1]s"/><t:FieldURIOrConstant><t:Constant Value="%[3]s"/></t:FieldURIOrConstant></t:IsLessThanOrEqualTo>`+
+		`</t:And></m:Restriction>`,
+		dateField, startDate.UTC().Format(time.RFC3339), endDate.AddDate(0, 0, 1).UTC().Format(time.RFC3339))
+
+	var ids []string
+	for offset := 0; ; {
+		body := `<m:FindItem Traversal="Shallow"><m:ItemShape><t:BaseShape>IdOnly</t:BaseShape></m:ItemShape>` +
+			ewsPageView("IndexedPageItemView", offset) + restriction +
+			`<m:ParentFolderIds>` + folderID + `</m:ParentFolderIds></m:FindItem>`
+		messages, err := es.call(body)
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) == 0 {
+			break
+		}
+		root := messages[0].RootFolder
+		for _, item := range root.Items.Items {
+			ids = append(ids, item.ItemID.ID)
+		}
+		offset += len(root.Items.Items)
+		if root.IncludesLastItemInRange || len(root.Items.Items) == 0 {
+			break
+		}
+	}
+
+	var emails []EmailInfo
+	totalCount := len(ids)
+	fmt.Printf("  处理 %d 封邮件...\n", totalCount)
+	for start := 0; start < totalCount; start += ewsGetItemBatch {
+		end := start + ewsGetItemBatch
+		if end > totalCount {
+			end = totalCount
+		}
+		if start > 0 {
+			fmt.Printf("  进度: %d/%d (%.1f%%)\n", start, totalCount, float64(start)/float64(totalCount)*100)
+		}
+
+		var sb strings.Builder
+		sb.WriteString(`<m:GetItem><m:ItemShape><t:BaseShape>IdOnly</t:BaseShape><t:BodyType>Text</t:BodyType><t:AdditionalProperties>`)
+		for _, property := range ewsItemProperties {
+			sb.WriteString(`<t:FieldURI FieldURI="` + property + `"/>`)
+		}
+		sb.WriteString(`</t:AdditionalProperties></m:ItemShape><m:ItemIds>`)
+		for _, id := range ids[start:end] {
+			sb.WriteString(`<t:ItemId Id="` + xmlEscape(id) + `"/>`)
+		}
+		sb.WriteString(`</m:ItemIds></m:GetItem>`)
+
+		messages, err := es.call(sb.String())
+		if err != nil {
+			return emails, err
+		}
+		for _, message := range messages {
+			for _, item := range message.Items.Items {
+				if emailInfo := item.emailInfo(); emailInfo.Subject != "" {
+					emails = append(emails, emailInfo)
+				}
+			}
+		}
+	}
+	return emails, nil
+}
+
+func ewsPageView(element string, offset int) string {
+	return fmt.Sprintf(`<m:%s MaxEntriesReturned="%d" Offset="%d" BasePoint="Beginning"/>`, element, ewsPageSize, offset)
+}
+
+// call 发送SOAP请求并返回ResponseMessages中的各条响应。
+// 服务器繁忙（ErrorServerBusy）时按返回的BackOffMilliseconds等待后重试，其他错误直接返回
+func (es *EwsSource) call(body string) ([]ewsResponseMessage, error) {
+	payload := []byte(fmt.Sprintf(ewsEnvelopeFormat, body))
+	for attempt := 0; ; attempt++ {
+		data, err := es.post(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		var envelope ewsEnvelope
+		if err := xml.Unmarshal(data, &envelope); err != nil {
+			return nil, fmt.Errorf("无法解析EWS响应: %v", err)
+		}
+		if fault := envelope.Body.Fault.FaultString; fault != "" {
+			return nil, fmt.Errorf("SOAP错误: %s", fault)
+		}
+
+		messages := envelope.Body.Response.ResponseMessages.Messages
+		busy := false
+		for _, message := range messages {
+			if message.ResponseClass != "Error" {
+				continue
+			}
+			if message.ResponseCode == "ErrorServerBusy" && attempt < ewsMaxRetries {
+				busy = true
+				wait := time.Duration(1<<uint(attempt)) * time.Second
+				if ms, err := strconv.Atoi(message.BackOff); err == nil {
+					wait = time.Duration(ms) * time.Millisecond
+				}
+				fmt.Printf("  ⚠️  Exchange服务器繁忙，%v 后重试...\n", wait)
+				time.Sleep(wait)
+				break
+			}
+			// GetItem中个别邮件读取失败不影响其他邮件
+			if len(messages) > 1 {
+				continue
+			}
+			return nil, fmt.Errorf("%s: %s", message.ResponseCode, message.MessageText)
+		}
+		if !busy {
+			return messages, nil
+		}
+	}
+}
+
+// post 发送请求并处理认证。第一次请求不带认证信息，根据401响应中服务器支持的方式选择NTLM或Basic
+func (es *EwsSource) post(payload []byte) ([]byte, error) {
+	resp, data, err := es.send(payload, es.authorization())
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && es.auth == "" {
+		es.auth = ewsAuthScheme(resp.Header.Values("WWW-Authenticate"))
+		if es.auth == "" {
+			return nil, fmt.Errorf("服务器不支持NTLM或Basic认证")
+		}
+		resp, data, err = es.send(payload, es.authorization())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && es.auth == "ntlm" {
+		challenge := ""
+		for _, value := range resp.Header.Values("WWW-Authenticate") {
+			if strings.HasPrefix(value, "NTLM ") {
+				challenge = strings.TrimPrefix(value, "NTLM ")
+			}
+		}
+		challengeBytes, err := base64.StdEncoding.DecodeString(challenge)
+		if err != nil || challenge == "" {
+			return nil, fmt.Errorf("用户名或密码错误")
+		}
+		authenticate, err := ntlmAuthenticateMessage(challengeBytes, es.user, es.password)
+		if err != nil {
+			return nil, err
+		}
+		resp, data, err = es.send(payload, "NTLM "+base64.StdEncoding.EncodeToString(authenticate))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, fmt.Errorf("用户名或密码错误")
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusInternalServerError:
+		// SOAP错误以500返回，交给调用方解析Fault
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return data, nil
+}
+
+// authorization 返回第一次请求使用的Authorization头。NTLM需要先发送NEGOTIATE消息
+func (es *EwsSource) authorization() string {
+	switch es.auth {
+	case "ntlm":
+		return "NTLM " + base64.StdEncoding.EncodeToString(ntlmNegotiateMessage())
+	case "basic":
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(es.user+":"+es.password))
+	}
+	return ""
+}
+
+func (es *EwsSource) send(payload []byte, authorization string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(http.MethodPost, es.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := es.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	// 读完响应体才能复用连接，NTLM依赖这一点
+	data, err := io.ReadAll(resp.Body)
+	return resp, data, err
+}
+
+func ewsAuthScheme(challenges []string) string {
+	scheme := ""
+	for _, challenge := range challenges {
+		switch strings.ToLower(strings.Fields(challenge + " ")[0]) {
+		case "ntlm":
+			return "ntlm"
+		case "basic":
+			scheme = "basic"
+		}
+	}
+	return scheme
+}
+
+func (item ewsItem) emailInfo() EmailInfo {
+	emailInfo := EmailInfo{
+		Subject:     item.Subject,
+		SenderName:  item.From.Name,
+		SenderEmail: item.From.EmailAddress,
+		IsRead:      item.IsRead,
+	}
+	emailInfo.Recipients = append(addressRecipients(ewsAddresses(item.ToRecipients), RecipientTo),
+		addressRecipients(ewsAddresses(item.CcRecipients), RecipientCC)...)
+	emailInfo.Recipients = append(emailInfo.Recipients, addressRecipients(ewsAddresses(item.BccRecipients), RecipientBCC)...)
+	emailInfo.To = recipientNames(emailInfo.Recipients, RecipientTo)
+	emailInfo.CC = recipientNames(emailInfo.Recipients, RecipientCC)
+	if t, err := time.Parse(time.RFC3339, item.DateTimeReceived); err == nil {
+		emailInfo.ReceivedTime = t.Local()
+	}
+	if t, err := time.Parse(time.RFC3339, item.DateTimeSent); err == nil {
+		emailInfo.SentTime = t.Local()
+	}
+
+	body := item.Body.Text
+	if strings.EqualFold(item.Body.BodyType, "HTML") {
+		body = htmlToText(body)
+	}
+	emailInfo.Body = truncateBody(body)
+
+	emailInfo.MessageID = firstMessageID(item.InternetMessageID)
+	emailInfo.InReplyTo = firstMessageID(item.InReplyTo)
+	emailInfo.References = parseMessageIDs(item.References)
+	emailInfo.ConversationIndex = decodeThreadIndex(item.ConversationIndex)
+	emailInfo.ConversationID = conversationIDFromIndex(emailInfo.ConversationIndex)
+	emailInfo.MessageClass = item.ItemClass
+
+	header := mail.Header{}
+	for _, h := range item.Headers {
+		key := textproto.CanonicalMIMEHeaderKey(h.Name)
+		header[key] = append(header[key], h.Value)
+	}
+	applyListHeaders(&emailInfo, header)
+	applyAutoHeaders(&emailInfo, header)
+	return emailInfo
+}
+
+func ewsAddresses(mailboxes []ewsMailbox) []mail.Address {
+	addresses := make([]mail.Address, 0, len(mailboxes))
+	for _, mailbox := range mailboxes {
+		addresses = append(addresses, mail.Address{Name: mailbox.Name, Address: mailbox.EmailAddress})
+	}
+	return addresses
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	graphDefaultBaseURL = "https://graph.microsoft.com/v1.0"
+	graphLoginURL       = "https://login.microsoftonline.com"
+	graphScope          = "https://graph.microsoft.com/Mail.Read"
+	// 被限流时最多重试的次数
+	graphMaxRetries = 5
+	graphPageSize   = 100
+)
+
+// graphMessageFields 是$select中请求的字段，只包含EmailInfo需要的部分
+const graphMessageFields = "subject,from,toRecipients,ccRecipients,bccRecipients,receivedDateTime,sentDateTime,isRead,body," +
+	"internetMessageId,conversationId,conversationIndex,internetMessageHeaders"
+
+// graphMessageClassExpand 通过扩展属性读取PR_MESSAGE_CLASS，Graph没有对应的标准字段
+const graphMessageClassExpand = "singleValueExtendedProperties($filter=id eq 'String 0x001A')"
+
+// GraphSource 通过Microsoft Graph读取邮箱，文件夹遍历方式与Outlook数据源一致：
+// 收件箱及其所有子文件夹按receivedDateTime过滤，已发送邮件按sentDateTime过滤
+type GraphSource struct {
+	client   *http.Client
+	baseURL  string
+	loginURL string
+	token    string
+	// 限流重试和设备代码轮询之间的等待
+	sleep func(time.Duration)
+}
+
+type graphFolder struct {
+	ID               string `json:"id"`
+	DisplayName      string `json:"displayName"`
+	ChildFolderCount int    `json:"childFolderCount"`
+}
+
+type graphRecipient struct {
+	EmailAddress struct {
+		Name    string `json:"name"`
+		Address string `json:"address"`
+	} `json:"emailAddress"`
+}
+
+type graphMessage struct {
+	Subject          string           `json:"subject"`
+	From             *graphRecipient  `json:"from"`
+	ToRecipients     []graphRecipient `json:"toRecipients"`
+	CcRecipients     []graphRecipient `json:"ccRecipients"`
+	BccRecipients    []graphRecipient `json:"bccRecipients"`
+	ReceivedDateTime time.Time        `json:"receivedDateTime"`
+	SentDateTime     time.Time        `json:"sentDateTime"`
+	IsRead           bool             `json:"isRead"`
+	Body             struct {
+		ContentType string `json:"contentType"`
+		Content     string `json:"content"`
+	} `json:"body"`
+	InternetMessageID string `json:"internetMessageId"`
+	ConversationID    string `json:"conversationId"`
+	ConversationIndex []byte `json:"conversationIndex"`
+	// 邮件头只在$select中明确请求时返回，部分邮件（如本地创建的）没有
+	InternetMessageHeaders []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"internetMessageHeaders"`
+	ExtendedProperties []struct {
+		ID    string `json:"id"`
+		Value string `json:"value"`
+	} `json:"singleValueExtendedProperties"`
+}
+
+// NewGraphSource 使用给定的访问令牌；没有令牌时通过设备代码流程登录，需要提供应用的clientID。
+// baseURL为空时使用全球版Graph终结点
+func NewGraphSource(baseURL, token, tenant, clientID string) (*GraphSource, error) {
+	if baseURL == "" {
+		baseURL = graphDefaultBaseURL
+	}
+	gs := &GraphSource{
+		client:   &http.Client{Timeout: 60 * time.Second},
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		loginURL: graphLoginURL,
+		token:    token,
+		sleep:    time.Sleep,
+	}
+
+	if gs.token == "" {
+		if clientID == "" {
+			return nil, fmt.Errorf("Graph数据源需要 -token 访问令牌，或提供 -client-id 以使用设备代码登录")
+		}
+		if tenant == "" {
+			tenant = "organizations"
+		}
+		token, err := gs.deviceCodeLogin(tenant, clientID)
+		if err != nil {
+			return nil, fmt.Errorf("设备代码登录失败: %v", err)
+		}
+		gs.token = token
+	}
+
+	var me struct {
+		DisplayName       string `json:"displayName"`
+		Mail              string `json:"mail"`
+		UserPrincipalName string `json:"userPrincipalName"`
+	}
+	if err := gs.get(gs.baseURL+"/me?$select=displayName,mail,userPrincipalName", &me); err != nil {
+		return nil, fmt.Errorf("无法访问Graph邮箱: %v", err)
+	}
+	address := me.Mail
+	if address == "" {
+		address = me.UserPrincipalName
+	}
+	fmt.Printf("✓ 已连接Microsoft Graph: %s <%s>\n", me.DisplayName, address)
+	return gs, nil
+}
+
+func (gs *GraphSource) Close() {}
+
+// deviceCodeLogin 实现OAuth 2.0设备授权流程（RFC 8628）：显示验证码，然后轮询令牌终结点直到用户完成登录
+func (gs *GraphSource) deviceCodeLogin(tenant, clientID string) (string, error) {
+	endpoint := gs.loginURL + "/" + url.PathEscape(tenant) + "/oauth2/v2.0"
+
+	var code struct {
+		DeviceCode string `json:"device_code"`
+		Message    string `json:"message"`
+		ExpiresIn  int    `json:"expires_in"`
+		Interval   int    `json:"interval"`
+	}
+	if err := gs.postForm(endpoint+"/devicecode", url.Values{
+		"client_id": {clientID},
+		"scope":     {graphScope},
+	}, &code); err != nil {
+		return "", err
+	}
+	fmt.Println(code.Message)
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		gs.sleep(interval)
+
+		var token struct {
+			AccessToken      string `json:"access_token"`
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		err := gs.postForm(endpoint+"/token", url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"client_id":   {clientID},
+			"device_code": {code.DeviceCode},
+		}, &token)
+		switch {
+		case token.AccessToken != "":
+			fmt.Println("✓ 登录成功")
+			return token.AccessToken, nil
+		case token.Error == "authorization_pending":
+			continue
+		case token.Error == "slow_down":
+			interval += 5 * time.Second
+			continue
+		case token.Error != "":
+			return "", fmt.Errorf("%s: %s", token.Error, token.ErrorDescription)
+		case err != nil:
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("验证码已过期")
+}
+
+// postForm 提交表单并解析JSON响应。令牌终结点在等待授权时返回400，响应体中带有error字段，
+// 因此即使状态码不是200也会先解析响应体
+func (gs *GraphSource) postForm(endpoint string, form url.Values, result interface{}) error {
+	resp, err := gs.client.PostForm(endpoint, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// get 发送GET请求并解析JSON响应。遇到429或503时按Retry-After等待后重试
+func (gs *GraphSource) get(requestURL string, result interface{}) error {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodGet, requestURL, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+gs.token)
+		req.Header.Set("Accept", "application/json")
+		// 让服务器直接返回纯文本正文，省去HTML转换
+		req.Header.Set("Prefer", `outlook.body-content-type="text"`)
+
+		resp, err := gs.client.Do(req)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return json.Unmarshal(data, result)
+		case (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) && attempt < graphMaxRetries:
+			wait := retryAfter(resp.Header.Get("Retry-After"), attempt)
+			fmt.Printf("  ⚠️  请求被限流，%v 后重试...\n", wait)
+			gs.sleep(wait)
+		default:
+			return graphError(resp.StatusCode, data)
+		}
+	}
+}
+
+// retryAfter 解析Retry-After头（秒数或HTTP日期），缺失时按指数退避
+func retryAfter(value string, attempt int) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(t); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+	return time.Duration(1<<uint(attempt)) * time.Second
+}
+
+func graphError(status int, data []byte) error {
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error.Code != "" {
+		return fmt.Errorf("HTTP %d %s: %s", status, body.Error.Code, body.Error.Message)
+	}
+	return fmt.Errorf("HTTP %d", status)
+}
+
+// getAll 沿@odata.nextLink读取所有分页，每一页交给handle处理
+func (gs *GraphSource) getAll(requestURL string, handle func(json.RawMessage) error) error {
+	for requestURL != "" {
+		var page struct {
+			Value    []json.RawMessage `json:"value"`
+			NextLink string            `json:"@odata.nextLink"`
+		}
+		if err := gs.get(requestURL, &page); err != nil {
+			return err
+		}
+		for _, item := range page.Value {
+			if err := handle(item); err != nil {
+				return err
+			}
+		}
+		requestURL = page.NextLink
+	}
+	return nil
+}
+
+func (gs *GraphSource) ReceivedEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	folders, err := gs.getInboxFolders()
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []EmailInfo
+	fmt.Printf("正在分析 %d 个文件夹的邮件...\n", len(folders))
+	for folderIndex, folder := range folders {
+		fmt.Printf("正在读取文件夹 %d/%d: %s\n", folderIndex+1, len(folders), folder.DisplayName)
+		folderEmails, err := gs.getEmailsInDateRange(folder.ID, "receivedDateTime", startDate, endDate)
+		if err != nil {
+			fmt.Printf("  ⚠️  无法访问文件夹内容: %v\n", err)
+			continue
+		}
+		fmt.Printf("  ✓ 找到 %d 封符合条件的邮件\n", len(folderEmails))
+		setFolder(folderEmails, folder.DisplayName)
+		emails = append(emails, folderEmails...)
+	}
+
+	fmt.Printf("✓ 总共找到 %d 封邮件\n", len(emails))
+	return emails, nil
+}
+
+func (gs *GraphSource) SentEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	fmt.Println("正在获取发送邮件...")
+
+	sentEmails, err := gs.getEmailsInDateRange("sentitems", "sentDateTime", startDate, endDate)
+	if err != nil {
+		fmt.Printf("⚠️  无法访问发送文件夹: %v\n", err)
+		fmt.Println("   跳过发送邮件分析，继续其他功能...")
+		return []EmailInfo{}, nil
+	}
+
+	fmt.Printf("✓ 找到 %d 封发送邮件\n", len(sentEmails))
+	return sentEmails, nil
+}
+
+func (gs *GraphSource) getInboxFolders() ([]graphFolder, error) {
+	var inbox graphFolder
+	if err := gs.get(gs.baseURL+"/me/mailFolders/inbox?$select=id,displayName,childFolderCount", &inbox); err != nil {
+		return nil, fmt.Errorf("无法访问收件箱: %v", err)
+	}
+	fmt.Printf("✓ 使用收件箱: %s\n", inbox.DisplayName)
+
+	folders := []graphFolder{inbox}
+	gs.getSubfolders(inbox, &folders)
+	fmt.Printf("✓ 总共找到 %d 个文件夹\n", len(folders))
+	return folders, nil
+}
+
+// getSubfolders 递归获取子文件夹，显示名称带上父文件夹路径
+func (gs *GraphSource) getSubfolders(parent graphFolder, folderList *[]graphFolder) {
+	if parent.ChildFolderCount == 0 {
+		return
+	}
+	requestURL := fmt.Sprintf("%s/me/mailFolders/%s/childFolders?$select=id,displayName,childFolderCount&$top=%d",
+		gs.baseURL, url.PathEscape(parent.ID), graphPageSize)
+	var children []graphFolder
+	err := gs.getAll(requestURL, func(item json.RawMessage) error {
+		var folder graphFolder
+		if err := json.Unmarshal(item, &folder); err != nil {
+			return err
+		}
+		children = append(children, folder)
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("  ⚠️  无法获取 %s 的子文件夹: %v\n", parent.DisplayName, err)
+		return
+	}
+
+	for _, child := range children {
+		child.DisplayName = parent.DisplayName + "/" + child.DisplayName
+		*folderList = append(*folderList, child)
+		gs.getSubfolders(child, folderList)
+	}
+}
+
+// getEmailsInDateRange 用$filter在服务器端过滤，范围与Outlook数据源的Restrict相同：[开始日期, 结束日期+1天]
+func (gs *GraphSource) getEmailsInDateRange(folderID, dateField string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	filter := fmt.Sprintf("%s ge %s and %s le %s", dateField, startDate.UTC().Format(time.RFC3339),
+		dateField, endDate.AddDate(0, 0, 1).UTC().Format(time.RFC3339))
+	query := url.Values{
+		"$filter":  {filter},
+		"$select":  {graphMessageFields},
+		"$expand":  {graphMessageClassExpand},
+		"$orderby": {dateField + " desc"},
+		"$top":     {strconv.Itoa(graphPageSize)},
+	}
+	requestURL := fmt.Sprintf("%s/me/mailFolders/%s/messages?%s", gs.baseURL, url.PathEscape(folderID), query.Encode())
+
+	var emails []EmailInfo
+	readCount := 0
+	err := gs.getAll(requestURL, func(item json.RawMessage) error {
+		var message graphMessage
+		if err := json.Unmarshal(item, &message); err != nil {
+			return err
+		}
+		readCount++
+		if readCount%graphPageSize == 0 {
+			fmt.Printf("  进度: 已读取 %d 封邮件\n", readCount)
+		}
+		if emailInfo := message.emailInfo(); emailInfo.Subject != "" {
+			emails = append(emails, emailInfo)
+		}
+		return nil
+	})
+	return emails, err
+}
+
+func (m graphMessage) emailInfo() EmailInfo {
+	emailInfo := EmailInfo{
+		Subject:      m.Subject,
+		ReceivedTime: m.ReceivedDateTime.Local(),
+		SentTime:     m.SentDateTime.Local(),
+		IsRead:       m.IsRead,
+	}
+	emailInfo.Recipients = append(addressRecipients(graphAddresses(m.ToRecipients), RecipientTo),
+		addressRecipients(graphAddresses(m.CcRecipients), RecipientCC)...)
+	emailInfo.Recipients = append(emailInfo.Recipients, addressRecipients(graphAddresses(m.BccRecipients), RecipientBCC)...)
+	emailInfo.To = recipientNames(emailInfo.Recipients, RecipientTo)
+	emailInfo.CC = recipientNames(emailInfo.Recipients, RecipientCC)
+	if m.From != nil {
+		emailInfo.SenderName = m.From.EmailAddress.Name
+		emailInfo.SenderEmail = m.From.EmailAddress.Address
+	}
+
+	body := m.Body.Content
+	if strings.EqualFold(m.Body.ContentType, "html") {
+		body = htmlToText(body)
+	}
+	emailInfo.Body = truncateBody(body)
+
+	header := mail.Header{}
+	for _, h := range m.InternetMessageHeaders {
+		key := textproto.CanonicalMIMEHeaderKey(h.Name)
+		header[key] = append(header[key], h.Value)
+	}
+	// In-Reply-To和References只在邮件头中，Message-ID和会话索引以Graph的字段为准
+	applyThreadHeaders(&emailInfo, header)
+	if id := firstMessageID(m.InternetMessageID); id != "" {
+		emailInfo.MessageID = id
+	}
+	if len(m.ConversationIndex) > 0 {
+		emailInfo.ConversationIndex = m.ConversationIndex
+		emailInfo.ConversationID = conversationIDFromIndex(m.ConversationIndex)
+	}
+	if emailInfo.ConversationID == "" {
+		emailInfo.ConversationID = m.ConversationID
+	}
+	applyListHeaders(&emailInfo, header)
+	applyAutoHeaders(&emailInfo, header)
+	// 只请求了PR_MESSAGE_CLASS一个扩展属性
+	for _, property := range m.ExtendedProperties {
+		emailInfo.MessageClass = property.Value
+	}
+	return emailInfo
+}
+
+func graphAddresses(recipients []graphRecipient) []mail.Address {
+	addresses := make([]mail.Address, 0, len(recipients))
+	for _, recipient := range recipients {
+		addresses = append(addresses, mail.Address{Name: recipient.EmailAddress.Name, Address: recipient.EmailAddress.Address})
+	}
+	return addresses
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestGraphSource 返回指向本地httptest服务器的GraphSource，等待时间只记录不实际休眠
+func newTestGraphSource(t *testing.T, handler http.Handler) (*GraphSource, *[]time.Duration) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	var sleeps []time.Duration
+	gs := &GraphSource{
+		client:   server.Client(),
+		baseURL:  server.URL + "/v1.0",
+		loginURL: server.URL,
+		token:    "test-token",
+		sleep:    func(d time.Duration) { sleeps = append(sleeps, d) },
+	}
+	return gs, &sleeps
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprint(w, body)
+}
+
+func TestGraphPagingFollowsNextLink(t *testing.T) {
+	var serverURL string
+	var mu sync.Mutex
+	var requests []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.0/me/mailFolders/inbox", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"id":"inbox-id","displayName":"Inbox","childFolderCount":1}`)
+	})
+	mux.HandleFunc("/v1.0/me/mailFolders/inbox-id/childFolders", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"value":[{"id":"projects-id","displayName":"Projects","childFolderCount":0}]}`)
+	})
+	message := func(subject string, day int) string {
+		return fmt.Sprintf(`{"subject":%q,"from":{"emailAddress":{"name":"Zhang San","address":"zhangsan@example.com"}},
+			"toRecipients":[{"emailAddress":{"name":"Me","address":"me@example.com"}}],
+			"receivedDateTime":"2025-03-%02dT09:00:00Z","sentDateTime":"2025-03-%02dT08:59:00Z","isRead":true,
+			"body":{"contentType":"text","content":"body"},"internetMessageId":"<%s@example.com>"}`, subject, day, day, subject)
+	}
+	mux.HandleFunc("/v1.0/me/mailFolders/inbox-id/messages", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.RawQuery)
+		mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			writeJSON(w, http.StatusUnauthorized, `{"error":{"code":"InvalidAuthenticationToken","message":"no token"}}`)
+			return
+		}
+		if r.URL.Query().Get("$skiptoken") == "page2" {
+			writeJSON(w, http.StatusOK, `{"value":[`+message("third", 5)+`]}`)
+			return
+		}
+		next := serverURL + "/v1.0/me/mailFolders/inbox-id/messages?$skiptoken=page2"
+		writeJSON(w, http.StatusOK, `{"value":[`+message("first", 3)+`,`+message("second", 4)+`],"@odata.nextLink":"`+next+`"}`)
+	})
+	mux.HandleFunc("/v1.0/me/mailFolders/projects-id/messages", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"value":[`+message("project", 6)+`]}`)
+	})
+
+	gs, _ := newTestGraphSource(t, mux)
+	serverURL = strings.TrimSuffix(gs.baseURL, "/v1.0")
+
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	emails, err := gs.ReceivedEmails("", start, end)
+	if err != nil {
+		t.Fatalf("ReceivedEmails: %v", err)
+	}
+	var subjects []string
+	for _, email := range emails {
+		subjects = append(subjects, email.Folder+":"+email.Subject)
+	}
+	want := "Inbox:first,Inbox:second,Inbox:third,Inbox/Projects:project"
+	if strings.Join(subjects, ",") != want {
+		t.Errorf("emails = %v, want %s", subjects, want)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("inbox message requests = %d, want 2 (first page and nextLink)", len(requests))
+	}
+	if !strings.Contains(requests[0], "%24filter=receivedDateTime+ge+2025-03-01T00%3A00%3A00Z") {
+		t.Errorf("first request has no date filter: %s", requests[0])
+	}
+}
+
+func TestGraphRetriesThrottledRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		wantSleep  time.Duration
+	}{
+		{"429 seconds", http.StatusTooManyRequests, "7", 7 * time.Second},
+		{"503 seconds", http.StatusServiceUnavailable, "3", 3 * time.Second},
+		{"429 without header", http.StatusTooManyRequests, "", time.Second},
+	}
+	for _, tt := range tests {
+		calls := 0
+		gs, sleeps := newTestGraphSource(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				writeJSON(w, tt.status, `{"error":{"code":"TooManyRequests","message":"slow down"}}`)
+				return
+			}
+			writeJSON(w, http.StatusOK, `{"displayName":"Me"}`)
+		}))
+
+		var me struct {
+			DisplayName string `json:"displayName"`
+		}
+		if err := gs.get(gs.baseURL+"/me", &me); err != nil {
+			t.Errorf("%s: get: %v", tt.name, err)
+			continue
+		}
+		if me.DisplayName != "Me" || calls != 2 {
+			t.Errorf("%s: result %q after %d calls", tt.name, me.DisplayName, calls)
+		}
+		if len(*sleeps) != 1 || (*sleeps)[0] != tt.wantSleep {
+			t.Errorf("%s: sleeps = %v, want [%v]", tt.name, *sleeps, tt.wantSleep)
+		}
+	}
+}
+
+func TestGraphGivesUpAfterMaxRetries(t *testing.T) {
+	gs, sleeps := newTestGraphSource(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		writeJSON(w, http.StatusTooManyRequests, `{"error":{"code":"TooManyRequests","message":"slow down"}}`)
+	}))
+	var result map[string]interface{}
+	err := gs.get(gs.baseURL+"/me", &result)
+	if err == nil || !strings.Contains(err.Error(), "TooManyRequests") {
+		t.Errorf("err = %v, want the throttling error", err)
+	}
+	if len(*sleeps) != graphMaxRetries {
+		t.Errorf("retried %d times, want %d", len(*sleeps), graphMaxRetries)
+	}
+}
+
+func TestGraphDeviceCodePolling(t *testing.T) {
+	tests := []struct {
+		name       string
+		responses  []string // 令牌终结点依次返回的响应体（状态码400，成功时200）
+		wantToken  string
+		wantErr    string
+		wantSleeps []time.Duration
+	}{
+		{
+			name: "pending, slow_down, success",
+			responses: []string{
+				`{"error":"authorization_pending"}`,
+				`{"error":"slow_down"}`,
+				`{"access_token":"granted"}`,
+			},
+			wantToken:  "granted",
+			wantSleeps: []time.Duration{2 * time.Second, 2 * time.Second, 7 * time.Second},
+		},
+		{
+			name:       "declined",
+			responses:  []string{`{"error":"authorization_declined","error_description":"user said no"}`},
+			wantErr:    "authorization_declined: user said no",
+			wantSleeps: []time.Duration{2 * time.Second},
+		},
+		{
+			name:       "expired",
+			responses:  []string{`{"error":"authorization_pending"}`, `{"error":"expired_token","error_description":"code expired"}`},
+			wantErr:    "expired_token",
+			wantSleeps: []time.Duration{2 * time.Second, 2 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		polls := 0
+		mux := http.NewServeMux()
+		mux.HandleFunc("/contoso/oauth2/v2.0/devicecode", func(w http.ResponseWriter, r *http.Request) {
+			if r.FormValue("client_id") != "app-id" || r.FormValue("scope") != graphScope {
+				writeJSON(w, http.StatusBadRequest, `{"error":"invalid_request"}`)
+				return
+			}
+			writeJSON(w, http.StatusOK, `{"device_code":"dev-123","message":"Enter ABC","expires_in":900,"interval":2}`)
+		})
+		mux.HandleFunc("/contoso/oauth2/v2.0/token", func(w http.ResponseWriter, r *http.Request) {
+			if r.FormValue("device_code") != "dev-123" || r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" {
+				writeJSON(w, http.StatusBadRequest, `{"error":"invalid_grant"}`)
+				return
+			}
+			body := tt.responses[polls]
+			polls++
+			status := http.StatusBadRequest
+			if strings.Contains(body, "access_token") {
+				status = http.StatusOK
+			}
+			writeJSON(w, status, body)
+		})
+
+		gs, sleeps := newTestGraphSource(t, mux)
+		token, err := gs.deviceCodeLogin("contoso", "app-id")
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+		} else if err != nil || token != tt.wantToken {
+			t.Errorf("%s: token = %q, err = %v", tt.name, token, err)
+		}
+		if fmt.Sprint(*sleeps) != fmt.Sprint(tt.wantSleeps) {
+			t.Errorf("%s: sleeps = %v, want %v", tt.name, *sleeps, tt.wantSleeps)
+		}
+	}
+}
+
+func TestGraphMessageThreadHeaders(t *testing.T) {
+	raw := `{"subject":"RE: 预算","internetMessageId":"<reply@example.com>","conversationId":"AAQk",
+		"internetMessageHeaders":[
+			{"name":"Message-ID","value":"<header-id@example.com>"},
+			{"name":"In-Reply-To","value":"<original@example.com>"},
+			{"name":"references","value":"<root@example.com> <original@example.com>"},
+			{"name":"List-Id","value":"<team.example.com>"}
+		]}`
+	var message graphMessage
+	if err := json.Unmarshal([]byte(raw), &message); err != nil {
+		t.Fatal(err)
+	}
+	info := message.emailInfo()
+	if info.MessageID != "reply@example.com" {
+		t.Errorf("MessageID = %q, want the internetMessageId field", info.MessageID)
+	}
+	if info.InReplyTo != "original@example.com" {
+		t.Errorf("InReplyTo = %q", info.InReplyTo)
+	}
+	if strings.Join(info.References, " ") != "root@example.com original@example.com" {
+		t.Errorf("References = %v", info.References)
+	}
+	if info.ConversationID != "AAQk" || info.ListID != "<team.example.com>" {
+		t.Errorf("ConversationID/ListID = %q, %q", info.ConversationID, info.ListID)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	future := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	if got := retryAfter(future, 0); got < 28*time.Second || got > 30*time.Second {
+		t.Errorf("retryAfter(HTTP date) = %v", got)
+	}
+	if got := retryAfter("Mon, 01 Jan 2001 00:00:00 GMT", 0); got != 0 {
+		t.Errorf("retryAfter(past date) = %v", got)
+	}
+	if got := retryAfter("", 3); got != 8*time.Second {
+		t.Errorf("retryAfter(\"\", 3) = %v", got)
+	}
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ImapSource 通过IMAP4rev1读取服务器上的邮件。收件箱子树和\Sent文件夹通过LIST发现，
+// 日期过滤在服务器端用SEARCH完成，FETCH使用BODY.PEEK，分析过程不会把邮件标记为已读
+type ImapSource struct {
+	client *imapClient
+	user   string
+	// LIST结果在第一次使用时加载
+	folders []imapFolder
+}
+
+type imapFolder struct {
+	name      string // 服务器上的原始名称（修改版UTF-7）
+	delimiter string
+	flags     []string
+}
+
+// 每次FETCH的最大邮件数量
+const imapFetchBatch = 100
+
+const imapDateLayout = "2-Jan-2006"
+
+// imapFetchItems 只取分析需要的部分：信封、标志、解码正文和会话关联所需的头字段以及前500字节正文
+const imapFetchItems = "(UID FLAGS INTERNALDATE ENVELOPE BODY.PEEK[HEADER.FIELDS (CONTENT-TYPE CONTENT-TRANSFER-ENCODING REFERENCES THREAD-INDEX LIST-ID LIST-UNSUBSCRIBE PRECEDENCE AUTO-SUBMITTED X-AUTO-RESPONSE-SUPPRESS X-FAILED-RECIPIENTS)] BODY.PEEK[TEXT]<0.500>)"
+
+// NewImapSource 连接并登录服务器。server 支持 imaps://主机[:端口]（默认993）、
+// imap://主机[:端口]（默认143，服务器支持时自动升级STARTTLS）和不带协议的 主机[:端口]（按imaps处理）
+func NewImapSource(server, user, password string) (*ImapSource, error) {
+	if server == "" || user == "" {
+		return nil, fmt.Errorf("IMAP数据源需要 -server 和 -user 参数")
+	}
+	address, useTLS, err := parseImapServer(server)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("正在连接IMAP服务器 %s...\n", address)
+	client, err := dialIMAP(address, useTLS)
+	if err != nil {
+		return nil, fmt.Errorf("无法连接IMAP服务器: %v", err)
+	}
+
+	if !useTLS && client.caps["STARTTLS"] {
+		if err := client.startTLS(address); err != nil {
+			client.conn.Close()
+			return nil, fmt.Errorf("STARTTLS失败: %v", err)
+		}
+		fmt.Println("✓ 已通过STARTTLS加密连接")
+	} else if !useTLS {
+		fmt.Println("⚠️  连接未加密，密码将以明文传输")
+	}
+
+	if err := client.login(user, password); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("IMAP登录失败: %v", err)
+	}
+	// 部分服务器登录后才公布完整的能力列表
+	client.capability()
+	fmt.Printf("✓ 已登录: %s\n", user)
+
+	return &ImapSource{client: client, user: user}, nil
+}
+
+func parseImapServer(server string) (address string, useTLS bool, err error) {
+	if !strings.Contains(server, "://") {
+		server = "imaps://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Hostname() == "" {
+		return "", false, fmt.Errorf("无效的IMAP服务器地址: %s", server)
+	}
+	port := u.Port()
+	switch strings.ToLower(u.Scheme) {
+	case "imaps":
+		useTLS = true
+		if port == "" {
+			port = "993"
+		}
+	case "imap":
+		if port == "" {
+			port = "143"
+		}
+	default:
+		return "", false, fmt.Errorf("不支持的协议: %s（请使用 imap:// 或 imaps://）", u.Scheme)
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+func (c *imapClient) startTLS(address string) error {
+	if _, err := c.command("STARTTLS"); err != nil {
+		return err
+	}
+	host, _, _ := net.SplitHostPort(address)
+	tlsConn := tls.Client(c.conn, &tls.Config{ServerName: host})
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	c.conn = tlsConn
+	c.r.Reset(tlsConn)
+	return c.capability()
+}
+
+func (is *ImapSource) Close() {
+	is.client.Close()
+}
+
+// listAvailableAccounts 列出服务器上的文件夹，对应Outlook数据源列出的账户信息
+func (is *ImapSource) listAvailableAccounts() error {
+	folders, err := is.listFolders()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n=== 账户 %s 的文件夹 ===\n", is.user)
+	for _, folder := range folders {
+		if len(folder.flags) > 0 {
+			fmt.Printf("  %s %s\n", decodeModifiedUTF7(folder.name), strings.Join(folder.flags, " "))
+		} else {
+			fmt.Printf("  %s\n", decodeModifiedUTF7(folder.name))
+		}
+	}
+	fmt.Println()
+	return nil
+}
+
+func (is *ImapSource) ReceivedEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	folders, err := is.getInboxFolders()
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []EmailInfo
+	fmt.Printf("正在分析 %d 个文件夹的邮件...\n", len(folders))
+	for folderIndex, folder := range folders {
+		fmt.Printf("正在读取文件夹 %d/%d: %s\n", folderIndex+1, len(folders), decodeModifiedUTF7(folder.name))
+		folderEmails, err := is.getEmailsInDateRange(folder, false, startDate, endDate)
+		if err != nil {
+			fmt.Printf("  ⚠️  无法访问文件夹内容: %v\n", err)
+			continue
+		}
+		fmt.Printf("  ✓ 找到 %d 封符合条件的邮件\n", len(folderEmails))
+		setFolder(folderEmails, decodeModifiedUTF7(folder.name))
+		emails = append(emails, folderEmails...)
+	}
+
+	fmt.Printf("✓ 总共找到 %d 封邮件\n", len(emails))
+	return emails, nil
+}
+
+func (is *ImapSource) SentEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	fmt.Println("正在获取发送邮件...")
+
+	sentFolder, err := is.getSentFolder()
+	if err != nil {
+		fmt.Printf("⚠️  无法访问发送文件夹: %v\n", err)
+		fmt.Println("   跳过发送邮件分析，继续其他功能...")
+		return []EmailInfo{}, nil
+	}
+	fmt.Printf("✓ 使用发送文件夹: %s\n", decodeModifiedUTF7(sentFolder.name))
+
+	sentEmails, err := is.getEmailsInDateRange(sentFolder, true, startDate, endDate)
+	if err != nil {
+		return sentEmails, fmt.Errorf("读取发送邮件失败: %v", err)
+	}
+
+	fmt.Printf("✓ 找到 %d 封发送邮件\n", len(sentEmails))
+	return sentEmails, nil
+}
+
+func (is *ImapSource) listFolders() ([]imapFolder, error) {
+	if is.folders != nil {
+		return is.folders, nil
+	}
+	// 支持RFC 6154的服务器需要显式请求SPECIAL-USE属性
+	cmd := `LIST "" "*"`
+	if is.client.caps["SPECIAL-USE"] {
+		cmd = `LIST (SPECIAL-USE) "" "*"`
+	}
+	responses, err := is.client.command(cmd)
+	if err != nil && is.client.caps["SPECIAL-USE"] {
+		responses, err = is.client.command(`LIST "" "*"`)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法获取文件夹列表: %v", err)
+	}
+
+	folders := []imapFolder{}
+	for _, resp := range responses {
+		// * LIST (\HasNoChildren \Sent) "/" "Sent"
+		if len(resp) < 5 || !strings.EqualFold(imapString(resp[1]), "LIST") {
+			continue
+		}
+		folder := imapFolder{delimiter: imapNString(resp[3]), name: imapString(resp[4])}
+		if attrs, ok := resp[2].([]interface{}); ok {
+			for _, attr := range attrs {
+				folder.flags = append(folder.flags, imapString(attr))
+			}
+		}
+		folders = append(folders, folder)
+	}
+	sort.Slice(folders, func(i, j int) bool { return folders[i].name < folders[j].name })
+	is.folders = folders
+	return folders, nil
+}
+
+// getInboxFolders 返回INBOX及其下的所有可选择子文件夹。
+// 有些服务器把所有文件夹都放在INBOX下（如 INBOX.Sent），这些特殊用途的文件夹需要排除
+func (is *ImapSource) getInboxFolders() ([]imapFolder, error) {
+	folders, err := is.listFolders()
+	if err != nil {
+		return nil, err
+	}
+
+	var inboxFolders []imapFolder
+	for _, folder := range folders {
+		if !isImapInboxSubtree(folder) || folder.hasFlag(`\Noselect`) || folder.hasFlag(`\NonExistent`) {
+			continue
+		}
+		if !strings.EqualFold(folder.name, "INBOX") && (folder.isSpecialUse() || isSpecialFolderName(folder.leafName())) {
+			continue
+		}
+		inboxFolders = append(inboxFolders, folder)
+	}
+	if len(inboxFolders) == 0 {
+		// 按RFC 3501，INBOX总是存在，即使LIST没有返回
+		inboxFolders = append(inboxFolders, imapFolder{name: "INBOX"})
+	}
+	fmt.Printf("✓ 总共找到 %d 个文件夹\n", len(inboxFolders))
+	return inboxFolders, nil
+}
+
+// getSentFolder 优先使用带\Sent属性的文件夹，服务器不支持SPECIAL-USE时按常见名称查找
+func (is *ImapSource) getSentFolder() (imapFolder, error) {
+	folders, err := is.listFolders()
+	if err != nil {
+		return imapFolder{}, err
+	}
+	for _, folder := range folders {
+		if folder.hasFlag(`\Sent`) {
+			return folder, nil
+		}
+	}
+	for _, name := range maildirSentFolders {
+		for _, folder := range folders {
+			if strings.EqualFold(folder.leafName(), name) && !folder.hasFlag(`\Noselect`) {
+				return folder, nil
+			}
+		}
+	}
+	return imapFolder{}, fmt.Errorf("服务器上没有发送文件夹")
+}
+
+// getEmailsInDateRange 相当于Outlook数据源中的Restrict过滤。SEARCH只比较日期且使用服务器时区，
+// 因此查询范围前后各放宽一天，取回后再用inDateRange精确过滤
+func (is *ImapSource) getEmailsInDateRange(folder imapFolder, isSent bool, startDate, endDate time.Time) ([]EmailInfo, error) {
+	// EXAMINE以只读方式打开文件夹，不会改变\Recent等标志
+	if _, err := is.client.command("EXAMINE " + imapQuote(folder.name)); err != nil {
+		return nil, err
+	}
+
+	since := startDate.AddDate(0, 0, -1).Format(imapDateLayout)
+	before := endDate.AddDate(0, 0, 2).Format(imapDateLayout)
+	criteria := fmt.Sprintf("SINCE %s BEFORE %s", since, before)
+	if isSent {
+		// 发送邮件按Date头（对应SentOn）过滤
+		criteria = fmt.Sprintf("SENTSINCE %s SENTBEFORE %s", since, before)
+	}
+	responses, err := is.client.command("UID SEARCH " + criteria)
+	if err != nil {
+		return nil, err
+	}
+	var uids []string
+	for _, resp := range responses {
+		if len(resp) > 1 && strings.EqualFold(imapString(resp[1]), "SEARCH") {
+			for _, uid := range resp[2:] {
+				uids = append(uids, imapString(uid))
+			}
+		}
+	}
+
+	var emails []EmailInfo
+	totalCount := len(uids)
+	fmt.Printf("  处理 %d 封邮件...\n", totalCount)
+	for start := 0; start < totalCount; start += imapFetchBatch {
+		end := start + imapFetchBatch
+		if end > totalCount {
+			end = totalCount
+		}
+		if start > 0 {
+			fmt.Printf("  进度: %d/%d (%.1f%%)\n", start, totalCount, float64(start)/float64(totalCount)*100)
+		}
+
+		responses, err := is.client.command("UID FETCH " + strings.Join(uids[start:end], ",") + " " + imapFetchItems)
+		if err != nil {
+			return emails, err
+		}
+		for _, resp := range responses {
+			if len(resp) < 4 || !strings.EqualFold(imapString(resp[2]), "FETCH") {
+				continue
+			}
+			attrs, ok := resp[3].([]interface{})
+			if !ok {
+				continue
+			}
+			emailInfo := imapEmailInfo(attrs)
+			checkTime := emailInfo.ReceivedTime
+			if isSent {
+				checkTime = emailInfo.SentTime
+			}
+			if emailInfo.Subject != "" && inDateRange(checkTime, startDate, endDate) {
+				emails = append(emails, emailInfo)
+			}
+		}
+	}
+	return emails, nil
+}
+
+// imapEmailInfo 将FETCH响应中的属性列表转换为EmailInfo
+func imapEmailInfo(attrs []interface{}) EmailInfo {
+	var emailInfo EmailInfo
+	var bodyHeader, bodyText string
+	for i := 0; i+1 < len(attrs); i += 2 {
+		name := strings.ToUpper(imapString(attrs[i]))
+		value := attrs[i+1]
+		switch {
+		case name == "FLAGS":
+			if flags, ok := value.([]interface{}); ok {
+				for _, flag := range flags {
+					if strings.EqualFold(imapString(flag), `\Seen`) {
+						emailInfo.IsRead = true
+					}
+				}
+			}
+		case name == "INTERNALDATE":
+			if t, err := time.Parse("_2-Jan-2006 15:04:05 -0700", imapString(value)); err == nil {
+				emailInfo.ReceivedTime = t
+			}
+		case name == "ENVELOPE":
+			if envelope, ok := value.([]interface{}); ok {
+				applyImapEnvelope(&emailInfo, envelope)
+			}
+		case strings.HasPrefix(name, "BODY[HEADER"):
+			bodyHeader = imapNString(value)
+		case strings.HasPrefix(name, "BODY[TEXT]"):
+			bodyText = imapNString(value)
+		}
+	}
+	if emailInfo.ReceivedTime.IsZero() {
+		emailInfo.ReceivedTime = emailInfo.SentTime
+	}
+
+	// 正文只取了前500字节，多部分邮件的最后一部分会被截断，messageText会返回截断前已解码的内容
+	header := textproto.MIMEHeader{}
+	if bodyHeader != "" {
+		if msg, err := mail.ReadMessage(strings.NewReader(bodyHeader + "\r\n")); err == nil {
+			header = textproto.MIMEHeader(msg.Header)
+			emailInfo.References = parseMessageIDs(msg.Header.Get("References"))
+			emailInfo.ConversationIndex = decodeThreadIndex(msg.Header.Get("Thread-Index"))
+			emailInfo.ConversationID = conversationIDFromIndex(emailInfo.ConversationIndex)
+			applyListHeaders(&emailInfo, msg.Header)
+			applyAutoHeaders(&emailInfo, msg.Header)
+		}
+	}
+	if body, err := messageText(header, strings.NewReader(bodyText)); err == nil || body != "" {
+		emailInfo.Body = truncateBody(body)
+	}
+	return emailInfo
+}
+
+// applyImapEnvelope 解析ENVELOPE结构:
+// (date subject from sender reply-to to cc bcc in-reply-to message-id)
+func applyImapEnvelope(emailInfo *EmailInfo, envelope []interface{}) {
+	if len(envelope) < 10 {
+		return
+	}
+	if date, err := mail.ParseDate(imapNString(envelope[0])); err == nil {
+		emailInfo.SentTime = date
+	}
+	emailInfo.Subject = decodeHeader(imapNString(envelope[1]))
+	if from := imapAddresses(envelope[2]); len(from) > 0 {
+		emailInfo.SenderName = from[0].Name
+		emailInfo.SenderEmail = from[0].Address
+	}
+	emailInfo.Recipients = append(addressRecipients(imapAddresses(envelope[5]), RecipientTo),
+		addressRecipients(imapAddresses(envelope[6]), RecipientCC)...)
+	emailInfo.Recipients = append(emailInfo.Recipients, addressRecipients(imapAddresses(envelope[7]), RecipientBCC)...)
+	emailInfo.To = recipientNames(emailInfo.Recipients, RecipientTo)
+	emailInfo.CC = recipientNames(emailInfo.Recipients, RecipientCC)
+	emailInfo.InReplyTo = firstMessageID(imapNString(envelope[8]))
+	emailInfo.MessageID = firstMessageID(imapNString(envelope[9]))
+}
+
+// imapAddresses 解析地址列表，每个地址为 (name adl mailbox host)。
+// host为NIL的条目是RFC 2822组语法的开始/结束标记，予以忽略
+func imapAddresses(value interface{}) []mail.Address {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	var addresses []mail.Address
+	for _, item := range list {
+		fields, ok := item.([]interface{})
+		if !ok || len(fields) < 4 {
+			continue
+		}
+		mailbox, host := imapNString(fields[2]), imapNString(fields[3])
+		if host == "" {
+			continue
+		}
+		addresses = append(addresses, mail.Address{
+			Name:    decodeHeader(imapNString(fields[0])),
+			Address: mailbox + "@" + host,
+		})
+	}
+	return addresses
+}
+
+func isImapInboxSubtree(folder imapFolder) bool {
+	if strings.EqualFold(folder.name, "INBOX") {
+		return true
+	}
+	return folder.delimiter != "" && len(folder.name) > 6 &&
+		strings.EqualFold(folder.name[:5], "INBOX") && strings.HasPrefix(folder.name[5:], folder.delimiter)
+}
+
+func (f imapFolder) hasFlag(flag string) bool {
+	for _, attr := range f.flags {
+		if strings.EqualFold(attr, flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSpecialUse 判断文件夹是否带有RFC 6154定义的特殊用途属性
+func (f imapFolder) isSpecialUse() bool {
+	for _, flag := range []string{`\Sent`, `\Drafts`, `\Trash`, `\Junk`, `\All`, `\Archive`, `\Flagged`} {
+		if f.hasFlag(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// leafName 返回解码后的最后一级文件夹名称
+func (f imapFolder) leafName() string {
+	name := f.name
+	if f.delimiter != "" {
+		if idx := strings.LastIndex(name, f.delimiter); idx >= 0 {
+			name = name[idx+len(f.delimiter):]
+		}
+	}
+	return decodeModifiedUTF7(name)
+}
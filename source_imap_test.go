@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeIMAPMessage struct {
+	uid      int
+	folder   string
+	internal time.Time // INTERNALDATE，SEARCH SINCE/BEFORE按此过滤
+	date     time.Time // Date头，SEARCH SENTSINCE/SENTBEFORE按此过滤
+	subject  string
+	from     string // "Name <mailbox@host>"
+	to       string
+	seen     bool
+	header   string
+	text     string
+}
+
+// fakeIMAPServer 是进程内的IMAP服务器，只实现ImapSource用到的命令，并记录收到的命令
+type fakeIMAPServer struct {
+	listener net.Listener
+	user     string
+	password string
+	folders  []string // LIST响应中的 (属性) "分隔符" "名称" 部分
+	messages []fakeIMAPMessage
+
+	mu       sync.Mutex
+	commands []string
+}
+
+func newFakeIMAPServer(t *testing.T, messages []fakeIMAPMessage) *fakeIMAPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeIMAPServer{
+		listener: listener,
+		user:     "me@example.com",
+		password: `p"a\ss`,
+		folders: []string{
+			`(\HasChildren) "/" "INBOX"`,
+			`(\HasNoChildren) "/" "INBOX/&mHl27g-"`,
+			`(\HasNoChildren \Sent) "/" "Sent"`,
+			`(\HasNoChildren \Trash) "/" "Trash"`,
+		},
+		messages: messages,
+	}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeIMAPServer) address() string {
+	return "imap://" + s.listener.Addr().String()
+}
+
+func (s *fakeIMAPServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+func (s *fakeIMAPServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(w, format+"\r\n", args...)
+	}
+	reply("* OK fake IMAP ready")
+	w.Flush()
+
+	selected := ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		tag, cmd, _ := strings.Cut(line, " ")
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd)
+		s.mu.Unlock()
+
+		verb := strings.ToUpper(strings.Fields(cmd)[0])
+		if verb == "UID" {
+			verb += " " + strings.ToUpper(strings.Fields(cmd)[1])
+		}
+		switch verb {
+		case "CAPABILITY":
+			reply("* CAPABILITY IMAP4rev1 LITERAL+")
+			reply("%s OK CAPABILITY completed", tag)
+		case "LOGIN":
+			if cmd == "LOGIN "+imapQuote(s.user)+" "+imapQuote(s.password) {
+				reply("%s OK LOGIN completed", tag)
+			} else {
+				reply("%s NO [AUTHENTICATIONFAILED] invalid credentials", tag)
+			}
+		case "LIST":
+			for _, folder := range s.folders {
+				reply("* LIST %s", folder)
+			}
+			reply("%s OK LIST completed", tag)
+		case "SELECT", "EXAMINE":
+			name, _ := strconv.Unquote(strings.TrimPrefix(cmd, strings.Fields(cmd)[0]+" "))
+			selected = name
+			reply("* FLAGS (\\Seen \\Answered)")
+			reply("%s OK [READ-ONLY] %s completed", tag, verb)
+		case "UID SEARCH":
+			var uids []string
+			for _, msg := range s.search(selected, strings.Fields(cmd)[2:]) {
+				uids = append(uids, strconv.Itoa(msg.uid))
+			}
+			reply("* SEARCH %s", strings.Join(uids, " "))
+			reply("%s OK SEARCH completed", tag)
+		case "UID FETCH":
+			wanted := make(map[string]bool)
+			for _, uid := range strings.Split(strings.Fields(cmd)[2], ",") {
+				wanted[uid] = true
+			}
+			seq := 0
+			for _, msg := range s.messages {
+				if msg.folder != selected {
+					continue
+				}
+				seq++
+				if wanted[strconv.Itoa(msg.uid)] {
+					w.WriteString(msg.fetchResponse(seq))
+				}
+			}
+			reply("%s OK FETCH completed", tag)
+		case "LOGOUT":
+			reply("* BYE logging out")
+			reply("%s OK LOGOUT completed", tag)
+			w.Flush()
+			return
+		default:
+			reply("%s BAD unknown command", tag)
+		}
+		w.Flush()
+	}
+}
+
+// search 实现 SINCE/BEFORE（按INTERNALDATE）和 SENTSINCE/SENTBEFORE（按Date头）
+func (s *fakeIMAPServer) search(folder string, criteria []string) []fakeIMAPMessage {
+	var result []fakeIMAPMessage
+	for _, msg := range s.messages {
+		if msg.folder != folder {
+			continue
+		}
+		match := true
+		for i := 0; i+1 < len(criteria); i += 2 {
+			day, err := time.Parse(imapDateLayout, criteria[i+1])
+			if err != nil {
+				return nil
+			}
+			internal := time.Date(msg.internal.Year(), msg.internal.Month(), msg.internal.Day(), 0, 0, 0, 0, time.UTC)
+			sent := time.Date(msg.date.Year(), msg.date.Month(), msg.date.Day(), 0, 0, 0, 0, time.UTC)
+			switch strings.ToUpper(criteria[i]) {
+			case "SINCE":
+				match = match && !internal.Before(day)
+			case "BEFORE":
+				match = match && internal.Before(day)
+			case "SENTSINCE":
+				match = match && !sent.Before(day)
+			case "SENTBEFORE":
+				match = match && sent.Before(day)
+			}
+		}
+		if match {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+func imapLiteral(s string) string {
+	return fmt.Sprintf("{%d}\r\n%s", len(s), s)
+}
+
+func imapAddressList(address string) string {
+	if address == "" {
+		return "NIL"
+	}
+	name, addr, _ := strings.Cut(strings.TrimSuffix(address, ">"), " <")
+	mailbox, host, _ := strings.Cut(addr, "@")
+	return fmt.Sprintf("((%s NIL %q %q))", imapLiteral(name), mailbox, host)
+}
+
+func (m fakeIMAPMessage) fetchResponse(seq int) string {
+	flags := ""
+	if m.seen {
+		flags = `\Seen`
+	}
+	envelope := fmt.Sprintf("(%q %s %s %s %s %s NIL NIL NIL %q)",
+		m.date.Format(time.RFC1123Z), imapLiteral(m.subject),
+		imapAddressList(m.from), imapAddressList(m.from), imapAddressList(m.from), imapAddressList(m.to),
+		fmt.Sprintf("<%d@example.com>", m.uid))
+	return fmt.Sprintf("* %d FETCH (UID %d FLAGS (%s) INTERNALDATE %q ENVELOPE %s "+
+		"BODY[HEADER.FIELDS (CONTENT-TYPE REFERENCES)] %s BODY[TEXT]<0> %s)\r\n",
+		seq, m.uid, flags, m.internal.Format("_2-Jan-2006 15:04:05 -0700"), envelope,
+		imapLiteral(m.header), imapLiteral(m.text))
+}
+
+func TestImapSourceAgainstFakeServer(t *testing.T) {
+	cst := time.FixedZone("CST", 8*3600)
+	header := "Content-Type: text/plain; charset=utf-8\r\nReferences: <root@example.com>\r\n\r\n"
+	server := newFakeIMAPServer(t, []fakeIMAPMessage{
+		{uid: 1, folder: "INBOX", internal: time.Date(2025, 3, 3, 9, 0, 0, 0, cst), date: time.Date(2025, 3, 3, 8, 59, 0, 0, cst),
+			subject: "季度预算审批", from: "张三 <zhangsan@example.com>", to: "Me <me@example.com>", seen: true,
+			header: header, text: "请审批附件中的预算"},
+		{uid: 2, folder: "INBOX", internal: time.Date(2025, 3, 4, 10, 0, 0, 0, cst), date: time.Date(2025, 3, 4, 10, 0, 0, 0, cst),
+			subject: "Unread", from: "Li Si <lisi@example.com>", to: "Me <me@example.com>", header: header, text: "hello"},
+		{uid: 3, folder: "INBOX", internal: time.Date(2025, 1, 10, 10, 0, 0, 0, cst), date: time.Date(2025, 1, 10, 10, 0, 0, 0, cst),
+			subject: "Too old", from: "Li Si <lisi@example.com>", header: header, text: "old"},
+		{uid: 7, folder: "INBOX/&mHl27g-", internal: time.Date(2025, 3, 5, 10, 0, 0, 0, cst), date: time.Date(2025, 3, 5, 10, 0, 0, 0, cst),
+			subject: "Project", from: "Wang Wu <wangwu@example.com>", header: header, text: "status"},
+		{uid: 9, folder: "Trash", internal: time.Date(2025, 3, 5, 10, 0, 0, 0, cst), date: time.Date(2025, 3, 5, 10, 0, 0, 0, cst),
+			subject: "Deleted", from: "Wang Wu <wangwu@example.com>", header: header, text: "deleted"},
+		// 服务器在4月才收到（如导入的邮件），但Date头在3月，发送邮件按Date头过滤
+		{uid: 4, folder: "Sent", internal: time.Date(2025, 4, 20, 10, 0, 0, 0, cst), date: time.Date(2025, 3, 3, 11, 0, 0, 0, cst),
+			subject: "RE: 季度预算审批", from: "Me <me@example.com>", to: "张三 <zhangsan@example.com>", seen: true,
+			header: header, text: "已审批"},
+	})
+
+	source, err := NewImapSource(server.address(), server.user, server.password)
+	if err != nil {
+		t.Fatalf("NewImapSource: %v", err)
+	}
+
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, cst)
+	end := time.Date(2025, 3, 31, 0, 0, 0, 0, cst)
+	received, err := source.ReceivedEmails(server.user, start, end)
+	if err != nil {
+		t.Fatalf("ReceivedEmails: %v", err)
+	}
+	if len(received) != 3 {
+		t.Fatalf("got %d received emails, want 3: %+v", len(received), received)
+	}
+
+	first := received[0]
+	if first.Subject != "季度预算审批" || first.SenderName != "张三" || first.SenderEmail != "zhangsan@example.com" {
+		t.Errorf("subject/sender = %q, %q <%s>", first.Subject, first.SenderName, first.SenderEmail)
+	}
+	if !first.IsRead || received[1].IsRead {
+		t.Errorf("\\Seen flag: read = %v, %v; want true, false", first.IsRead, received[1].IsRead)
+	}
+	if !first.ReceivedTime.Equal(time.Date(2025, 3, 3, 9, 0, 0, 0, cst)) {
+		t.Errorf("ReceivedTime = %v", first.ReceivedTime)
+	}
+	if first.Body != "请审批附件中的预算" || first.MessageID != "1@example.com" {
+		t.Errorf("body/message id = %q, %q", first.Body, first.MessageID)
+	}
+	if len(first.References) != 1 || first.References[0] != "root@example.com" {
+		t.Errorf("References = %v", first.References)
+	}
+	if first.Folder != "INBOX" || received[2].Folder != "INBOX/项目" {
+		t.Errorf("folders = %q, %q", first.Folder, received[2].Folder)
+	}
+
+	sent, err := source.SentEmails(server.user, start, end)
+	if err != nil {
+		t.Fatalf("SentEmails: %v", err)
+	}
+	if len(sent) != 1 || sent[0].Subject != "RE: 季度预算审批" || sent[0].To != "张三" {
+		t.Fatalf("SentEmails = %+v", sent)
+	}
+	source.Close()
+
+	var searches []string
+	for _, cmd := range server.received() {
+		if strings.HasPrefix(cmd, "SELECT") {
+			t.Errorf("folder opened read-write: %s", cmd)
+		}
+		if strings.HasPrefix(cmd, "UID SEARCH") {
+			searches = append(searches, cmd)
+		}
+		if strings.HasPrefix(cmd, "UID FETCH") && !strings.Contains(cmd, "BODY.PEEK[") {
+			t.Errorf("FETCH without BODY.PEEK would set \\Seen: %s", cmd)
+		}
+	}
+	wantSearches := []string{
+		"UID SEARCH SINCE 28-Feb-2025 BEFORE 2-Apr-2025",
+		"UID SEARCH SINCE 28-Feb-2025 BEFORE 2-Apr-2025",
+		"UID SEARCH SENTSINCE 28-Feb-2025 SENTBEFORE 2-Apr-2025",
+	}
+	if strings.Join(searches, "\n") != strings.Join(wantSearches, "\n") {
+		t.Errorf("SEARCH commands = %q, want %q", searches, wantSearches)
+	}
+}
+
+func TestImapLoginRejected(t *testing.T) {
+	server := newFakeIMAPServer(t, nil)
+	if _, err := NewImapSource(server.address(), server.user, "wrong"); err == nil {
+		t.Fatal("login with a wrong password succeeded")
+	}
+}
+
+func TestImapLiteralLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"valid", "* 1 FETCH (BODY[TEXT] {5}\r\nhello)\r\n", false},
+		{"non-sync", "* 1 FETCH (BODY[TEXT] {5+}\r\nhello)\r\n", false},
+		{"negative", "* 1 FETCH (BODY[TEXT] {-1}\r\n)\r\n", true},
+		{"huge", "* 1 FETCH (BODY[TEXT] {99999999999}\r\n)\r\n", true},
+		{"over limit", fmt.Sprintf("* 1 FETCH (BODY[TEXT] {%d}\r\n)\r\n", imapMaxLiteral+1), true},
+		{"truncated", "* 1 FETCH (BODY[TEXT] {10}\r\nshort", true},
+	}
+	for _, tt := range tests {
+		c := &imapClient{r: bufio.NewReader(strings.NewReader(tt.input))}
+		resp, err := c.readResponse()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr {
+			attrs := resp[3].([]interface{})
+			if imapString(attrs[1]) != "hello" {
+				t.Errorf("%s: literal = %q", tt.name, imapString(attrs[1]))
+			}
+		}
+	}
+}
+
+func TestDecodeModifiedUTF7(t *testing.T) {
+	tests := map[string]string{
+		"INBOX":          "INBOX",
+		"&XfJSIJZk-":     "已删除",
+		"Tom &- Jerry":   "Tom & Jerry",
+		"INBOX/&mHl27g-": "INBOX/项目",
+		"&broken":        "&broken",
+	}
+	for in, want := range tests {
+		if got := decodeModifiedUTF7(in); got != want {
+			t.Errorf("decodeModifiedUTF7(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaildirSource 读取Maildir/Maildir++目录。根目录视为收件箱，
+// ".名称" 形式的子目录按Maildir++约定视为收件箱下的子文件夹
+type MaildirSource struct {
+	root     string
+	sentPath string
+}
+
+// 这些文件夹不属于收件箱子树（对应Outlook中与收件箱同级的默认文件夹）
+var specialFolderNames = []string{"sent", "sent items", "sent messages", "已发送", "已发送邮件",
+	"drafts", "草稿箱", "草稿", "trash", "deleted items", "deleted messages", "已删除", "已删除邮件",
+	"junk", "junk email", "spam", "垃圾邮件", "outbox", "发件箱", "recoverable items"}
+
+var maildirSentFolders = []string{"Sent", "Sent Items", "Sent Messages", "已发送", "已发送邮件"}
+
+func NewMaildirSource(root, sentPath string) (*MaildirSource, error) {
+	if !isMaildir(root) {
+		return nil, fmt.Errorf("%s 不是有效的Maildir目录（缺少cur/new子目录）", root)
+	}
+	md := &MaildirSource{root: root, sentPath: sentPath}
+	if sentPath != "" && md.getSentFolder() == "" {
+		return nil, fmt.Errorf("找不到发送文件夹 %s（应为Maildir目录或根目录下的文件夹名称）", sentPath)
+	}
+	return md, nil
+}
+
+func (md *MaildirSource) Close() {}
+
+func (md *MaildirSource) ReceivedEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	folders, err := md.getInboxFolders()
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []EmailInfo
+	fmt.Printf("正在分析 %d 个文件夹的邮件...\n", len(folders))
+	for folderIndex, folder := range folders {
+		fmt.Printf("正在读取文件夹 %d/%d: %s\n", folderIndex+1, len(folders), maildirFolderName(md.root, folder))
+		folderEmails := md.readFolder(folder, func(info EmailInfo) bool {
+			return inDateRange(info.ReceivedTime, startDate, endDate)
+		})
+		fmt.Printf("  ✓ 找到 %d 封符合条件的邮件\n", len(folderEmails))
+		setFolder(folderEmails, maildirFolderName(md.root, folder))
+		emails = append(emails, folderEmails...)
+	}
+
+	fmt.Printf("✓ 总共找到 %d 封邮件\n", len(emails))
+	return emails, nil
+}
+
+func (md *MaildirSource) SentEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	fmt.Println("正在获取发送邮件...")
+
+	sentFolder := md.getSentFolder()
+	if sentFolder == "" {
+		fmt.Println("⚠️  未找到发送文件夹")
+		fmt.Println("   跳过发送邮件分析，继续其他功能...")
+		return []EmailInfo{}, nil
+	}
+	fmt.Printf("✓ 使用发送文件夹: %s\n", maildirFolderName(md.root, sentFolder))
+
+	sentEmails := md.readFolder(sentFolder, func(info EmailInfo) bool {
+		return inDateRange(info.SentTime, startDate, endDate)
+	})
+
+	fmt.Printf("✓ 找到 %d 封发送邮件\n", len(sentEmails))
+	return sentEmails, nil
+}
+
+// getInboxFolders 返回根目录及所有Maildir++子文件夹，排除发送、草稿、垃圾等特殊文件夹及其下级
+func (md *MaildirSource) getInboxFolders() ([]string, error) {
+	folders := []string{md.root}
+
+	entries, err := os.ReadDir(md.root)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取Maildir目录: %v", err)
+	}
+
+	sentFolder := md.getSentFolder()
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !strings.HasPrefix(name, ".") || name == "." || name == ".." {
+			continue
+		}
+		path := filepath.Join(md.root, name)
+		if path == sentFolder || isMaildirSpecialFolder(name) || !isMaildir(path) {
+			continue
+		}
+		folders = append(folders, path)
+	}
+
+	// 按层级名称排序，使父文件夹排在子文件夹之前
+	sort.Strings(folders[1:])
+	fmt.Printf("✓ 总共找到 %d 个文件夹\n", len(folders))
+	return folders, nil
+}
+
+func (md *MaildirSource) getSentFolder() string {
+	if md.sentPath != "" {
+		if isMaildir(md.sentPath) {
+			return md.sentPath
+		}
+		// 也允许只给出文件夹名称，如 "Sent"
+		if path := filepath.Join(md.root, "."+strings.TrimPrefix(md.sentPath, ".")); isMaildir(path) {
+			return path
+		}
+		return ""
+	}
+	for _, name := range maildirSentFolders {
+		for _, prefix := range []string{".", ".INBOX."} {
+			if path := filepath.Join(md.root, prefix+name); isMaildir(path) {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// readFolder 读取一个Maildir文件夹中的邮件。new/ 和 tmp/ 中的邮件一律视为未读，
+// cur/ 中的邮件根据文件名中 ":2," 之后的标志判断，S 表示已读。
+// tmp/ 中是正在投递的邮件，可能不完整，无法解析的跳过
+func (md *MaildirSource) readFolder(folder string, keep func(EmailInfo) bool) []EmailInfo {
+	var emails []EmailInfo
+	for _, sub := range []string{"new", "cur", "tmp"} {
+		entries, err := os.ReadDir(filepath.Join(folder, sub))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			info, err := md.readMessage(filepath.Join(folder, sub, entry.Name()))
+			if err != nil {
+				fmt.Printf("  ⚠️  跳过无法解析的邮件 %s: %v\n", entry.Name(), err)
+				continue
+			}
+			info.IsRead = sub == "cur" && maildirSeen(entry.Name())
+			if keep(info) {
+				emails = append(emails, info)
+			}
+		}
+	}
+	return emails
+}
+
+func (md *MaildirSource) readMessage(path string) (EmailInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return EmailInfo{}, err
+	}
+	defer file.Close()
+
+	info, _, err := parseMessage(file)
+	if err != nil {
+		return info, err
+	}
+	if info.ReceivedTime.IsZero() {
+		info.ReceivedTime = maildirDeliveryTime(path)
+	}
+	if info.SentTime.IsZero() {
+		info.SentTime = info.ReceivedTime
+	}
+	return info, nil
+}
+
+// maildirSeen 解析文件名中的info部分，如 "1700000000.M1P2.host:2,RS"，
+// 判断是否带有S(Seen)标志。部分Windows实现用 "!" 代替 ":" 作为分隔符
+func maildirSeen(name string) bool {
+	idx := strings.LastIndexAny(name, ":!")
+	if idx < 0 || !strings.HasPrefix(name[idx+1:], "2,") {
+		return false
+	}
+	return strings.ContainsRune(name[idx+3:], 'S')
+}
+
+// maildirDeliveryTime 从文件名开头的Unix时间戳得到投递时间，失败时使用文件修改时间
+func maildirDeliveryTime(path string) time.Time {
+	name := filepath.Base(path)
+	if idx := strings.Index(name, "."); idx > 0 {
+		if sec, err := strconv.ParseInt(name[:idx], 10, 64); err == nil {
+			return time.Unix(sec, 0)
+		}
+	}
+	if stat, err := os.Stat(path); err == nil {
+		return stat.ModTime()
+	}
+	return time.Time{}
+}
+
+func isMaildir(path string) bool {
+	for _, sub := range []string{"cur", "new"} {
+		stat, err := os.Stat(filepath.Join(path, sub))
+		if err != nil || !stat.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// isMaildirSpecialFolder 判断 ".Sent"、".Sent.2024"、".INBOX.Trash" 这类文件夹是否属于特殊文件夹
+func isMaildirSpecialFolder(name string) bool {
+	parts := strings.Split(strings.TrimPrefix(name, "."), ".")
+	if len(parts) > 1 && strings.EqualFold(parts[0], "INBOX") {
+		parts = parts[1:]
+	}
+	return isSpecialFolderName(parts[0])
+}
+
+// isSpecialFolderName 判断文件夹名称是否为发送、草稿、已删除、垃圾邮件等特殊文件夹
+func isSpecialFolderName(name string) bool {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	for _, special := range specialFolderNames {
+		if lower == special {
+			return true
+		}
+	}
+	return false
+}
+
+func maildirFolderName(root, folder string) string {
+	if folder == root {
+		return "Inbox"
+	}
+	name := strings.TrimPrefix(filepath.Base(folder), ".")
+	if len(name) > len("INBOX.") && strings.EqualFold(name[:len("INBOX.")], "INBOX.") {
+		name = name[len("INBOX."):]
+	}
+	name = strings.ReplaceAll(name, ".", "/")
+	if isMaildirSpecialFolder(filepath.Base(folder)) {
+		return name
+	}
+	return "Inbox/" + name
+}
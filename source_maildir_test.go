@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaildirSeen(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"1700000000.M1P2.host:2,S", true},
+		{"1700000000.M1P2.host:2,RS", true},
+		{"1700000000.M1P2.host:2,FR", false},
+		{"1700000000.M1P2.host:2,", false},
+		{"1700000000.M1P2.host!2,S", true},
+		{"1700000000.M1P2.host:1,S", false},
+		{"1700000000.M1P2.host", false},
+	}
+	for _, tt := range tests {
+		if got := maildirSeen(tt.name); got != tt.want {
+			t.Errorf("maildirSeen(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMaildirDeliveryTime(t *testing.T) {
+	path := filepath.Join("cur", "1700000000.M1P2.host:2,S")
+	if got := maildirDeliveryTime(path); !got.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("maildirDeliveryTime(%q) = %v", path, got)
+	}
+}
+
+func TestMaildirFolderName(t *testing.T) {
+	root := filepath.Join("mail", "user")
+	tests := []struct {
+		folder string
+		want   string
+	}{
+		{root, "Inbox"},
+		{filepath.Join(root, ".Projects.Alpha"), "Inbox/Projects/Alpha"},
+		{filepath.Join(root, ".INBOX.Projects"), "Inbox/Projects"},
+		{filepath.Join(root, ".Sent"), "Sent"},
+	}
+	for _, tt := range tests {
+		if got := maildirFolderName(root, tt.folder); got != tt.want {
+			t.Errorf("maildirFolderName(%q) = %q, want %q", tt.folder, got, tt.want)
+		}
+	}
+}
+
+func TestIsMaildirSpecialFolder(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{".Sent", true},
+		{".Sent.2024", true},
+		{".INBOX.Trash", true},
+		{".Projects", false},
+		{".INBOX.Projects", false},
+	}
+	for _, tt := range tests {
+		if got := isMaildirSpecialFolder(tt.name); got != tt.want {
+			t.Errorf("isMaildirSpecialFolder(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// writeMaildir 在root下创建Maildir++文件夹（""为根目录），files为 子目录/文件名 -> 主题，
+// 邮件日期取自文件名开头的时间戳
+func writeMaildir(t *testing.T, root, folder string, files map[string]string) {
+	t.Helper()
+	dir := root
+	if folder != "" {
+		dir = filepath.Join(root, folder)
+	}
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, subject := range files {
+		var sec int64
+		fmt.Sscanf(filepath.Base(name), "%d", &sec)
+		content := fmt.Sprintf("From: pm@example.com\r\nTo: me@example.com\r\nSubject: %s\r\nDate: %s\r\n\r\nbody\r\n",
+			subject, time.Unix(sec, 0).UTC().Format(time.RFC1123Z))
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMaildirSourceReadsFolderTree(t *testing.T) {
+	root := t.TempDir()
+	// 1741000000 是 2025-03-03，1735700000 是 2025-01-01
+	writeMaildir(t, root, "", map[string]string{
+		"new/1741000000.M1.host":      "新邮件",
+		"cur/1741000100.M2.host:2,S":  "已读",
+		"cur/1741000200.M3.host:2,F":  "已标记未读",
+		"tmp/1741000300.M4.host":      "投递中",
+		"cur/1735700000.M5.host:2,S":  "范围之外",
+		"cur/.1741000400.M6.host:2,S": "隐藏文件",
+	})
+	writeMaildir(t, root, ".Projects", map[string]string{"cur/1741000500.M7.host:2,S": "项目"})
+	writeMaildir(t, root, ".Projects.Alpha", map[string]string{"new/1741000600.M8.host": "子项目"})
+	writeMaildir(t, root, ".INBOX.Trash", map[string]string{"cur/1741000700.M9.host:2,S": "已删除"})
+	writeMaildir(t, root, ".Sent Items", map[string]string{"cur/1741000800.M10.host:2,S": "RE: 新邮件"})
+	// 没有cur/new的目录不是文件夹
+	if err := os.MkdirAll(filepath.Join(root, ".Broken", "cur"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	md, err := NewMaildirSource(root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received, err := md.ReceivedEmails("me@example.com", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, email := range received {
+		got = append(got, fmt.Sprintf("%s/%s/%v", email.Folder, email.Subject, email.IsRead))
+	}
+	sort.Strings(got)
+	want := []string{"Inbox/Projects/Alpha/子项目/false", "Inbox/Projects/项目/true",
+		"Inbox/已标记未读/false", "Inbox/已读/true", "Inbox/投递中/false", "Inbox/新邮件/false"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("received = %v, want %v", got, want)
+	}
+
+	sent, err := md.SentEmails("me@example.com", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0].Subject != "RE: 新邮件" {
+		t.Errorf("sent = %+v", sent)
+	}
+
+	// 用文件夹名称指定发送文件夹，该文件夹不再计入收到的邮件
+	md, err = NewMaildirSource(root, "Projects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent, _ := md.SentEmails("", start, end); len(sent) != 1 || sent[0].Subject != "项目" {
+		t.Errorf("sent from Projects = %+v", sent)
+	}
+	if received, _ := md.ReceivedEmails("", start, end); len(received) != 5 {
+		t.Errorf("received with Projects as sent folder = %d", len(received))
+	}
+
+	if _, err := NewMaildirSource(root, "Outbox"); err == nil || !strings.Contains(err.Error(), "找不到发送文件夹") {
+		t.Errorf("unknown sent folder: err = %v", err)
+	}
+	if _, err := NewMaildirSource(filepath.Join(root, ".Broken"), ""); err == nil {
+		t.Error("directory without new/ accepted")
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MboxSource 从mbox文件（Thunderbird、Google Takeout导出等）读取邮件
+type MboxSource struct {
+	path     string
+	sentPath string
+	format   string // "mboxrd" 或 "mboxo"
+
+	messages []mboxMessage
+	loaded   bool
+}
+
+type mboxMessage struct {
+	info   EmailInfo
+	isSent bool
+}
+
+func NewMboxSource(path, sentPath, format string) (*MboxSource, error) {
+	if format == "" {
+		format = "mboxrd"
+	}
+	if format != "mboxrd" && format != "mboxo" {
+		return nil, fmt.Errorf("不支持的mbox格式: %s (可选 mboxrd 或 mboxo)", format)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("无法打开mbox文件: %v", err)
+	}
+	return &MboxSource{path: path, sentPath: sentPath, format: format}, nil
+}
+
+func (ms *MboxSource) Close() {}
+
+func (ms *MboxSource) ReceivedEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	if err := ms.load(); err != nil {
+		return nil, err
+	}
+
+	var emails []EmailInfo
+	for _, msg := range ms.messages {
+		if msg.isSent || isOwnAddress(msg.info.SenderEmail, account) {
+			continue
+		}
+		if inDateRange(msg.info.ReceivedTime, startDate, endDate) {
+			emails = append(emails, msg.info)
+		}
+	}
+	fmt.Printf("✓ 总共找到 %d 封邮件\n", len(emails))
+	return emails, nil
+}
+
+func (ms *MboxSource) SentEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	if err := ms.load(); err != nil {
+		return nil, err
+	}
+
+	var sentEmails []EmailInfo
+	for _, msg := range ms.messages {
+		if !msg.isSent && !isOwnAddress(msg.info.SenderEmail, account) {
+			continue
+		}
+		if inDateRange(msg.info.SentTime, startDate, endDate) {
+			sentEmails = append(sentEmails, msg.info)
+		}
+	}
+	fmt.Printf("✓ 找到 %d 封发送邮件\n", len(sentEmails))
+	return sentEmails, nil
+}
+
+func (ms *MboxSource) load() error {
+	if ms.loaded {
+		return nil
+	}
+
+	fmt.Printf("正在读取mbox文件: %s\n", ms.path)
+	if err := ms.readFile(ms.path, false); err != nil {
+		return err
+	}
+	if ms.sentPath != "" {
+		fmt.Printf("正在读取发送邮件mbox文件: %s\n", ms.sentPath)
+		if err := ms.readFile(ms.sentPath, true); err != nil {
+			fmt.Printf("⚠️  读取发送邮件失败: %v\n", err)
+		}
+	}
+
+	ms.loaded = true
+	return nil
+}
+
+func (ms *MboxSource) readFile(path string, isSent bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("无法打开mbox文件: %v", err)
+	}
+	defer file.Close()
+
+	count := 0
+	err = readMbox(file, ms.format, func(fromLine string, raw []byte) {
+		info, header, err := parseMessage(bytes.NewReader(raw))
+		if err != nil {
+			fmt.Printf("  ⚠️  跳过无法解析的邮件: %v\n", err)
+			return
+		}
+		if info.ReceivedTime.IsZero() {
+			info.ReceivedTime = fromLineTime(fromLine)
+		}
+		if info.SentTime.IsZero() {
+			info.SentTime = info.ReceivedTime
+		}
+		info.Folder = filepath.Base(path)
+		sent := isSent || hasGmailLabel(header.Get("X-Gmail-Labels"), "Sent")
+		ms.messages = append(ms.messages, mboxMessage{info: info, isSent: sent})
+		count++
+		if count%500 == 0 {
+			fmt.Printf("  已读取 %d 封邮件...\n", count)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("读取mbox文件失败: %v", err)
+	}
+
+	fmt.Printf("  ✓ 读取 %d 封邮件\n", count)
+	return nil
+}
+
+// readMbox 按 "From " 分隔行切分mbox文件，并还原被转义的 ">From " 行
+func readMbox(r io.Reader, format string, handle func(fromLine string, raw []byte)) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+
+	var fromLine string
+	var buf bytes.Buffer
+	started := false
+	prevBlank := true
+
+	flush := func() {
+		if started {
+			// 分隔行前的空行属于mbox格式而非邮件正文
+			raw := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+			handle(fromLine, raw)
+		}
+		buf.Reset()
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			trimmed := strings.TrimRight(line, "\r\n")
+			if prevBlank && strings.HasPrefix(trimmed, "From ") {
+				flush()
+				fromLine = trimmed
+				started = true
+			} else if started {
+				buf.WriteString(unescapeFromLine(trimmed, format))
+				buf.WriteByte('\n')
+			}
+			prevBlank = trimmed == ""
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	flush()
+	return nil
+}
+
+func unescapeFromLine(line, format string) string {
+	if !strings.HasPrefix(line, ">") {
+		return line
+	}
+	if format == "mboxo" {
+		// mboxo只转义 "From "，更多层的 ">" 属于原文
+		if strings.HasPrefix(line, ">From ") {
+			return line[1:]
+		}
+		return line
+	}
+	// mboxrd: 任意数量 ">" 后跟 "From " 的行都去掉一个 ">"
+	stripped := strings.TrimLeft(line, ">")
+	if strings.HasPrefix(stripped, "From ") {
+		return line[1:]
+	}
+	return line
+}
+
+// fromLineTime 解析分隔行中的asctime时间，如 "From a@b.com Thu Mar  6 10:00:00 2025"
+func fromLineTime(fromLine string) time.Time {
+	fields := strings.Fields(fromLine)
+	if len(fields) < 3 {
+		return time.Time{}
+	}
+	dateStr := strings.Join(fields[2:], " ")
+	layouts := []string{
+		"Mon Jan _2 15:04:05 2006",
+		"Mon Jan _2 15:04:05 -0700 2006",
+		"Mon Jan _2 15:04:05 MST 2006",
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, dateStr); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadMboxSplitsAndUnescapes(t *testing.T) {
+	input := "From alice@example.com Thu Mar  6 10:00:00 2025\n" +
+		"Subject: first\n" +
+		"\n" +
+		">From the start\n" +
+		">>From quoted\n" +
+		"\n" +
+		"From bob@example.com Fri Mar  7 11:30:00 2025\n" +
+		"Subject: second\n" +
+		"\n" +
+		"Body line\n" +
+		"From inside a paragraph is not a separator\n"
+
+	tests := []struct {
+		format string
+		first  string
+	}{
+		{"mboxrd", "Subject: first\n\nFrom the start\n>From quoted\n"},
+		{"mboxo", "Subject: first\n\nFrom the start\n>>From quoted\n"},
+	}
+	for _, tt := range tests {
+		var fromLines, raws []string
+		err := readMbox(strings.NewReader(input), tt.format, func(fromLine string, raw []byte) {
+			fromLines = append(fromLines, fromLine)
+			raws = append(raws, string(raw))
+		})
+		if err != nil {
+			t.Fatalf("%s: readMbox: %v", tt.format, err)
+		}
+		if len(raws) != 2 {
+			t.Fatalf("%s: got %d messages, want 2", tt.format, len(raws))
+		}
+		if raws[0] != tt.first {
+			t.Errorf("%s: first message = %q, want %q", tt.format, raws[0], tt.first)
+		}
+		if !strings.Contains(raws[1], "From inside a paragraph") {
+			t.Errorf("%s: \"From \" line without a preceding blank line was treated as a separator", tt.format)
+		}
+		if fromLines[1] != "From bob@example.com Fri Mar  7 11:30:00 2025" {
+			t.Errorf("%s: second From line = %q", tt.format, fromLines[1])
+		}
+	}
+}
+
+func TestFromLineTime(t *testing.T) {
+	tests := []struct {
+		line string
+		want time.Time
+	}{
+		{"From a@b.com Thu Mar  6 10:00:00 2025", time.Date(2025, 3, 6, 10, 0, 0, 0, time.UTC)},
+		{"From a@b.com Thu Mar 13 10:00:00 +0800 2025", time.Date(2025, 3, 13, 2, 0, 0, 0, time.UTC)},
+		{"From a@b.com", time.Time{}},
+		{"From a@b.com not a date", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := fromLineTime(tt.line); !got.Equal(tt.want) {
+			t.Errorf("fromLineTime(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestMboxReadStatus(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"X-Mozilla-Status: 0001", true},
+		{"X-Mozilla-Status: 0000", false},
+		{"Status: RO", true},
+		{"Status: O", false},
+		{"X-Gmail-Labels: Inbox,Unread", false},
+		{"X-Gmail-Labels: Inbox,Important", true},
+		{"Subject: none", false},
+	}
+	for _, tt := range tests {
+		_, header, err := parseMessage(strings.NewReader(tt.header + "\n\nbody\n"))
+		if err != nil {
+			t.Fatalf("parseMessage(%q): %v", tt.header, err)
+		}
+		if got := readStatus(header); got != tt.want {
+			t.Errorf("readStatus(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MAPI属性ID，对应extractEmailInfo中通过COM读取的属性
+const (
+	prSubject              = 0x0037
+	prClientSubmitTime     = 0x0039
+	prSenderName           = 0x0C1A
+	prSenderEmailAddress   = 0x0C1F
+	prDisplayCC            = 0x0E03
+	prDisplayTo            = 0x0E04
+	prMessageDeliveryTime  = 0x0E06
+	prMessageFlags         = 0x0E07
+	prBody                 = 0x1000
+	prHTML                 = 0x1013
+	prRecipientType        = 0x0C15
+	prDisplayName          = 0x3001
+	prDisplayType          = 0x3900
+	prEmailAddress         = 0x3003
+	prSMTPAddress          = 0x39FE
+	prInternetCodepage     = 0x3FDE
+	prMessageCodepage      = 0x3FFD
+	prSenderSMTPAddress    = 0x5D01
+	prSentRepresentingSMTP = 0x5D02
+	prSentRepresentingName = 0x0042
+	prConversationIndex    = 0x0071
+	prInternetMessageID    = 0x1035
+	prInternetReferences   = 0x1039
+	prInReplyToID          = 0x1042
+	prConversationID       = 0x3013
+	prTransportHeaders     = 0x007D
+	prMessageClass         = 0x001A
+)
+
+// MAPI属性类型
+const (
+	ptLong    = 0x0003
+	ptString8 = 0x001E
+	ptUnicode = 0x001F
+	ptSysTime = 0x0040
+	ptBinary  = 0x0102
+)
+
+const msgFlagRead = 0x0001 // PR_MESSAGE_FLAGS 中的 MSGFLAG_READ
+
+// mapiProps 保存一个属性集合，键为完整属性标签（ID<<16 | 类型）。
+// 定长属性保存8字节原始值，变长属性保存对应substg流的内容
+type mapiProps struct {
+	values   map[uint32][]byte
+	codepage int
+}
+
+func (p mapiProps) str(id uint16) string {
+	if raw, ok := p.values[uint32(id)<<16|ptUnicode]; ok {
+		return utf16String(raw)
+	}
+	if raw, ok := p.values[uint32(id)<<16|ptString8]; ok {
+		return strings.TrimRight(decodeCharset(raw, codepageCharset(p.codepage)), "\x00")
+	}
+	return ""
+}
+
+func (p mapiProps) int32(id uint16) (int32, bool) {
+	raw, ok := p.values[uint32(id)<<16|ptLong]
+	if !ok || len(raw) < 4 {
+		return 0, false
+	}
+	return int32(binary.LittleEndian.Uint32(raw)), true
+}
+
+func (p mapiProps) time(id uint16) time.Time {
+	raw, ok := p.values[uint32(id)<<16|ptSysTime]
+	if !ok || len(raw) < 8 {
+		return time.Time{}
+	}
+	return filetimeToTime(binary.LittleEndian.Uint64(raw))
+}
+
+func (p mapiProps) binary(id uint16) []byte {
+	return p.values[uint32(id)<<16|ptBinary]
+}
+
+// filetimeToTime 将Windows FILETIME（1601年起的100纳秒数）转换为time.Time
+func filetimeToTime(ft uint64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+	const epochDiff = 116444736000000000 // 1601-01-01 到 1970-01-01
+	// 超出int64纳秒范围（2262年以后，包括MAPI表示“永不”的0x7FFFFFFFFFFFFFFF）按无时间处理
+	const maxTicks = math.MaxInt64 / 100
+	if ft < epochDiff || ft-epochDiff > maxTicks {
+		return time.Time{}
+	}
+	ns := (ft - epochDiff) * 100
+	return time.Unix(0, int64(ns)).Local()
+}
+
+// codepageCharset 将Windows代码页转换为字符集名称
+func codepageCharset(codepage int) string {
+	switch codepage {
+	case 0, 65001:
+		return "utf-8"
+	case 936, 54936:
+		return "gb18030"
+	case 950:
+		return "big5"
+	case 20127:
+		return "us-ascii"
+	case 28591:
+		return "iso-8859-1"
+	case 932:
+		return "shift_jis"
+	case 949:
+		return "euc-kr"
+	}
+	return "windows-" + strconv.Itoa(codepage)
+}
+
+// readMsgProps 读取一个存储（邮件本身、收件人或附件）下的全部属性。
+// headerSize 为属性流头部长度：顶层邮件为32字节，收件人和附件为8字节
+func readMsgProps(cf *cfbFile, storage uint32, headerSize int) mapiProps {
+	props := mapiProps{values: make(map[uint32][]byte)}
+
+	for _, id := range cf.children(storage) {
+		entry := cf.entries[id]
+		if entry.kind != cfbStream {
+			continue
+		}
+		switch {
+		case strings.EqualFold(entry.name, "__properties_version1.0"):
+			data, err := cf.stream(id)
+			if err != nil {
+				continue
+			}
+			for off := headerSize; off+16 <= len(data); off += 16 {
+				tag := binary.LittleEndian.Uint32(data[off:])
+				propType := tag & 0xFFFF
+				// 变长属性的值在substg流中，这里只记录定长属性
+				if propType == ptUnicode || propType == ptString8 || propType == ptBinary || propType&0x1000 != 0 {
+					continue
+				}
+				props.values[tag] = data[off+8 : off+16]
+			}
+		case strings.HasPrefix(strings.ToLower(entry.name), "__substg1.0_"):
+			tag, err := strconv.ParseUint(entry.name[len("__substg1.0_"):], 16, 32)
+			if err != nil {
+				continue
+			}
+			if data, err := cf.stream(id); err == nil {
+				props.values[uint32(tag)] = data
+			}
+		}
+	}
+
+	if cp, ok := props.int32(prInternetCodepage); ok {
+		props.codepage = int(cp)
+	} else if cp, ok := props.int32(prMessageCodepage); ok {
+		props.codepage = int(cp)
+	}
+	return props
+}
+
+// readMsgFile 解析.msg文件，生成与COM extractEmailInfo相同字段的EmailInfo
+func readMsgFile(path string) (EmailInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return EmailInfo{}, err
+	}
+	cf, err := openCFB(data)
+	if err != nil {
+		return EmailInfo{}, err
+	}
+	props := readMsgProps(cf, 0, 32)
+
+	// 收件人表：每个收件人是一个 __recip_version1.0_#XXXXXXXX 存储
+	var recipients []Recipient
+	for _, id := range cf.children(0) {
+		entry := cf.entries[id]
+		if entry.kind != cfbStorage || !strings.HasPrefix(strings.ToLower(entry.name), "__recip_version1.0_") {
+			continue
+		}
+		recipProps := readMsgProps(cf, id, 8)
+		recipProps.codepage = props.codepage
+		recipients = append(recipients, recipientFromProps(recipProps))
+	}
+
+	return mapiEmailInfo(props, recipients), nil
+}
+
+func recipientFromProps(props mapiProps) Recipient {
+	recipient := Recipient{
+		Name:    props.str(prDisplayName),
+		Address: props.str(prSMTPAddress),
+	}
+	if recipient.Address == "" {
+		recipient.Address = props.str(prEmailAddress)
+	}
+	recipient.Resolved = recipient.Address != ""
+	kind, _ := props.int32(prRecipientType)
+	recipient.Type = RecipientType(kind)
+	displayType, _ := props.int32(prDisplayType)
+	recipient.DistList = isDistListDisplayType(displayType)
+	return recipient
+}
+
+// mapiEmailInfo 由MAPI属性和收件人表生成EmailInfo，.msg和.pst共用
+func mapiEmailInfo(props mapiProps, recipients []Recipient) EmailInfo {
+	var emailInfo EmailInfo
+
+	emailInfo.Subject = props.str(prSubject)
+	emailInfo.SenderName = props.str(prSenderName)
+	emailInfo.SenderEmail = props.str(prSenderSMTPAddress)
+	if emailInfo.SenderEmail == "" {
+		emailInfo.SenderEmail = props.str(prSenderEmailAddress)
+	}
+	if emailInfo.SenderName == "" {
+		emailInfo.SenderName = props.str(prSentRepresentingName)
+	}
+	if emailInfo.SenderEmail == "" {
+		emailInfo.SenderEmail = props.str(prSentRepresentingSMTP)
+	}
+
+	emailInfo.SentTime = props.time(prClientSubmitTime)
+	emailInfo.ReceivedTime = props.time(prMessageDeliveryTime)
+	if emailInfo.ReceivedTime.IsZero() {
+		emailInfo.ReceivedTime = emailInfo.SentTime
+	}
+	if emailInfo.SentTime.IsZero() {
+		emailInfo.SentTime = emailInfo.ReceivedTime
+	}
+
+	if flags, ok := props.int32(prMessageFlags); ok {
+		emailInfo.IsRead = flags&msgFlagRead != 0
+	}
+
+	emailInfo.Recipients = recipients
+	emailInfo.To = props.str(prDisplayTo)
+	emailInfo.CC = props.str(prDisplayCC)
+	if emailInfo.To == "" && emailInfo.CC == "" {
+		emailInfo.To = recipientNames(recipients, RecipientTo)
+		emailInfo.CC = recipientNames(recipients, RecipientCC)
+	}
+
+	body := props.str(prBody)
+	if strings.TrimSpace(body) == "" {
+		if htmlBody := props.binary(prHTML); len(htmlBody) > 0 {
+			body = htmlToText(decodeCharset(htmlBody, codepageCharset(props.codepage)))
+		}
+	}
+	emailInfo.Body = truncateBody(body)
+
+	emailInfo.MessageID = firstMessageID(props.str(prInternetMessageID))
+	emailInfo.InReplyTo = firstMessageID(props.str(prInReplyToID))
+	emailInfo.References = parseMessageIDs(props.str(prInternetReferences))
+	emailInfo.ConversationIndex = props.binary(prConversationIndex)
+	if id := props.binary(prConversationID); len(id) == 16 {
+		emailInfo.ConversationID = strings.ToUpper(hex.EncodeToString(id))
+	} else {
+		emailInfo.ConversationID = conversationIDFromIndex(emailInfo.ConversationIndex)
+	}
+	header := parseTransportHeaders(props.str(prTransportHeaders))
+	applyListHeaders(&emailInfo, header)
+	applyAutoHeaders(&emailInfo, header)
+
+	// 未送达报告（NDR）的收件人表就是投递失败的收件人
+	emailInfo.MessageClass = props.str(prMessageClass)
+	if strings.HasSuffix(strings.ToUpper(emailInfo.MessageClass), ".NDR") && len(emailInfo.BouncedRecipients) == 0 {
+		for _, r := range recipients {
+			if r.Address != "" {
+				emailInfo.BouncedRecipients = append(emailInfo.BouncedRecipients, r.Address)
+			}
+		}
+	}
+
+	return emailInfo
+}
+
+func NewMsgSource(dir, sentDir string) (*MessageDirSource, error) {
+	source, err := newMessageDirSource(dir, sentDir, ".msg", readMsgFile)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开.msg目录: %v", err)
+	}
+	return source, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+func TestFiletimeToTime(t *testing.T) {
+	tests := []struct {
+		name string
+		ft   uint64
+		want time.Time
+	}{
+		{"zero", 0, time.Time{}},
+		{"before 1970", 100, time.Time{}},
+		{"unix epoch", 116444736000000000, time.Unix(0, 0)},
+		{"2025", 133856928000000000, time.Date(2025, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{"never", 0x7FFFFFFFFFFFFFFF, time.Time{}},
+		{"max uint64", ^uint64(0), time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := filetimeToTime(tt.ft); !got.Equal(tt.want) {
+			t.Errorf("%s: filetimeToTime(%d) = %v, want %v", tt.name, tt.ft, got, tt.want)
+		}
+	}
+}
+
+func unicodeProp(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	raw := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(raw[i*2:], u)
+	}
+	return raw
+}
+
+// fixedProps 生成 __properties_version1.0 流：headerSize字节的头部后跟16字节的属性项
+func fixedProps(headerSize int, values map[uint32]uint64) []byte {
+	raw := make([]byte, headerSize)
+	for tag, value := range values {
+		entry := make([]byte, 16)
+		binary.LittleEndian.PutUint32(entry, tag)
+		binary.LittleEndian.PutUint64(entry[8:], value)
+		raw = append(raw, entry...)
+	}
+	return raw
+}
+
+func TestReadMsgFile(t *testing.T) {
+	delivery := time.Date(2025, 3, 6, 2, 0, 0, 0, time.UTC)
+	filetime := uint64(delivery.UnixNano()/100) + 116444736000000000
+	substg := func(id, kind uint16) string {
+		return fmt.Sprintf("__substg1.0_%04X%04X", id, kind)
+	}
+
+	data := buildCFB([]testCFBEntry{
+		{name: "Root Entry", kind: cfbRoot},
+		{name: "__properties_version1.0", kind: cfbStream, parent: 0, data: fixedProps(32, map[uint32]uint64{
+			uint32(prMessageDeliveryTime)<<16 | ptSysTime: filetime,
+			uint32(prMessageFlags)<<16 | ptLong:           msgFlagRead,
+		})},
+		{name: substg(prSubject, ptUnicode), kind: cfbStream, parent: 0, data: unicodeProp("季度预算审批")},
+		{name: substg(prSenderName, ptUnicode), kind: cfbStream, parent: 0, data: unicodeProp("张三")},
+		{name: substg(prSenderSMTPAddress, ptUnicode), kind: cfbStream, parent: 0, data: unicodeProp("zhangsan@example.com")},
+		{name: substg(prBody, ptUnicode), kind: cfbStream, parent: 0, data: unicodeProp("请审批附件中的预算")},
+		{name: substg(prInternetMessageID, ptUnicode), kind: cfbStream, parent: 0, data: unicodeProp("<abc@example.com>")},
+		{name: substg(prMessageClass, ptUnicode), kind: cfbStream, parent: 0, data: unicodeProp("IPM.Note")},
+		{name: "__recip_version1.0_#00000000", kind: cfbStorage, parent: 0},
+		{name: "__properties_version1.0", kind: cfbStream, parent: 8, data: fixedProps(8, map[uint32]uint64{
+			uint32(prRecipientType)<<16 | ptLong: uint64(RecipientCC),
+		})},
+		{name: substg(prDisplayName, ptUnicode), kind: cfbStream, parent: 8, data: unicodeProp("李四")},
+		{name: substg(prSMTPAddress, ptUnicode), kind: cfbStream, parent: 8, data: unicodeProp("lisi@example.com")},
+		{name: "__recip_version1.0_#00000001", kind: cfbStorage, parent: 0},
+		{name: "__properties_version1.0", kind: cfbStream, parent: 12, data: fixedProps(8, map[uint32]uint64{
+			uint32(prRecipientType)<<16 | ptLong: uint64(RecipientTo),
+			uint32(prDisplayType)<<16 | ptLong:   displayTypeDistList,
+		})},
+		{name: substg(prDisplayName, ptUnicode), kind: cfbStream, parent: 12, data: unicodeProp("研发部全体")},
+		{name: substg(prSMTPAddress, ptUnicode), kind: cfbStream, parent: 12, data: unicodeProp("rd-all@example.com")},
+	})
+	path := filepath.Join(t.TempDir(), "test.msg")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := readMsgFile(path)
+	if err != nil {
+		t.Fatalf("readMsgFile: %v", err)
+	}
+	if info.Subject != "季度预算审批" || info.SenderName != "张三" || info.SenderEmail != "zhangsan@example.com" {
+		t.Errorf("subject/sender = %q, %q <%s>", info.Subject, info.SenderName, info.SenderEmail)
+	}
+	if !info.ReceivedTime.Equal(delivery) || !info.SentTime.Equal(delivery) {
+		t.Errorf("times = %v / %v, want %v", info.ReceivedTime, info.SentTime, delivery)
+	}
+	if !info.IsRead {
+		t.Error("MSGFLAG_READ not applied")
+	}
+	if info.Body != "请审批附件中的预算" || info.MessageID != "abc@example.com" || info.MessageClass != "IPM.Note" {
+		t.Errorf("body/id/class = %q, %q, %q", info.Body, info.MessageID, info.MessageClass)
+	}
+	want := []Recipient{
+		{Name: "李四", Address: "lisi@example.com", Type: RecipientCC, Resolved: true},
+		{Name: "研发部全体", Address: "rd-all@example.com", Type: RecipientTo, Resolved: true, DistList: true},
+	}
+	if !reflect.DeepEqual(info.Recipients, want) {
+		t.Errorf("Recipients = %+v, want %+v", info.Recipients, want)
+	}
+	if info.CC != "李四" || info.To != "研发部全体" {
+		t.Errorf("To/CC = %q / %q", info.To, info.CC)
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PST消息层使用的节点ID和属性
+const (
+	pstNidMessageStore  = 0x21
+	pstNidRootFolder    = 0x122
+	pstNidRecipientTbl  = 0x692
+	pstNidTypeHierarchy = 0x0D
+	pstNidTypeContents  = 0x0E
+
+	prIPMSubtreeEntryID  = 0x35E0
+	prIPMSentMailEntryID = 0x35E4
+	prContainerClass     = 0x3613
+	prLtpRowID           = 0x67F2
+)
+
+// PstSource 读取离线的.pst/.ost存档，遍历方式与Outlook数据源的getInboxFolders/getSentEmailsInDateRange一致
+type PstSource struct {
+	pst *pstFile
+}
+
+var pstInboxNames = []string{"inbox", "收件箱", "收件匣", "posteingang", "boîte de réception", "bandeja de entrada"}
+
+type pstFolder struct {
+	nid    uint32
+	name   string
+	isMail bool
+}
+
+func NewPstSource(path string) (*PstSource, error) {
+	pst, err := openPST(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开PST文件: %v", err)
+	}
+	fmt.Printf("✓ 已打开PST文件: %s\n", path)
+	return &PstSource{pst: pst}, nil
+}
+
+func (ps *PstSource) Close() {
+	ps.pst.Close()
+}
+
+func (ps *PstSource) ReceivedEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	folders, err := ps.getInboxFolders()
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []EmailInfo
+	fmt.Printf("正在分析 %d 个文件夹的邮件...\n", len(folders))
+	for folderIndex, folder := range folders {
+		fmt.Printf("正在读取文件夹 %d/%d: %s\n", folderIndex+1, len(folders), folder.name)
+		folderEmails, err := ps.getEmailsInDateRange(folder, false, startDate, endDate)
+		if err != nil {
+			fmt.Printf("  ⚠️  无法访问文件夹内容: %v\n", err)
+			continue
+		}
+		fmt.Printf("  ✓ 找到 %d 封符合条件的邮件\n", len(folderEmails))
+		setFolder(folderEmails, folder.name)
+		emails = append(emails, folderEmails...)
+	}
+
+	fmt.Printf("✓ 总共找到 %d 封邮件\n", len(emails))
+	return emails, nil
+}
+
+func (ps *PstSource) SentEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	fmt.Println("正在获取发送邮件...")
+
+	sentFolder, err := ps.getSentFolder()
+	if err != nil {
+		fmt.Printf("⚠️  无法访问发送文件夹: %v\n", err)
+		fmt.Println("   跳过发送邮件分析，继续其他功能...")
+		return []EmailInfo{}, nil
+	}
+	fmt.Printf("✓ 使用发送文件夹: %s\n", sentFolder.name)
+
+	sentEmails, err := ps.getEmailsInDateRange(sentFolder, true, startDate, endDate)
+	if err != nil {
+		return sentEmails, fmt.Errorf("读取发送邮件失败: %v", err)
+	}
+
+	fmt.Printf("✓ 找到 %d 封发送邮件\n", len(sentEmails))
+	return sentEmails, nil
+}
+
+// getInboxFolders 返回收件箱及其所有子文件夹。收件箱按名称在整个文件夹树中逐层查找，
+// 存档中没有收件箱时，退回到除发送、草稿、已删除等特殊文件夹以外的所有邮件文件夹
+func (ps *PstSource) getInboxFolders() ([]pstFolder, error) {
+	subtree, err := ps.storeFolder(prIPMSubtreeEntryID)
+	if err != nil {
+		subtree = pstFolder{nid: pstNidRootFolder, isMail: true}
+	}
+	sent, _ := ps.storeFolder(prIPMSentMailEntryID)
+
+	level, err := ps.childFolders(subtree.nid)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取文件夹列表: %v", err)
+	}
+
+	var folders []pstFolder
+	for depth := 0; len(level) > 0 && depth < 4; depth++ {
+		var next []pstFolder
+		for _, folder := range level {
+			if isPstInbox(folder.name) {
+				fmt.Printf("✓ 使用收件箱: %s\n", folder.name)
+				folders = append(folders, folder)
+				ps.getSubfolders(folder, &folders, nil)
+				fmt.Printf("✓ 总共找到 %d 个文件夹\n", len(folders))
+				return folders, nil
+			}
+			children, _ := ps.childFolders(folder.nid)
+			for _, child := range children {
+				child.name = folder.name + "/" + child.name
+				next = append(next, child)
+			}
+		}
+		level = next
+	}
+
+	fmt.Println("⚠️  未找到收件箱，将分析除特殊文件夹以外的所有邮件文件夹")
+	subtree.name = ""
+	ps.getSubfolders(subtree, &folders, func(folder pstFolder) bool {
+		return folder.nid == sent.nid || isSpecialFolderName(folder.name)
+	})
+	fmt.Printf("✓ 总共找到 %d 个文件夹\n", len(folders))
+	return folders, nil
+}
+
+// getSubfolders 递归收集子文件夹，跳过日历、联系人等非邮件文件夹以及skip返回true的子树
+func (ps *PstSource) getSubfolders(parent pstFolder, folderList *[]pstFolder, skip func(pstFolder) bool) {
+	children, err := ps.childFolders(parent.nid)
+	if err != nil {
+		return
+	}
+	for _, child := range children {
+		if !child.isMail {
+			continue
+		}
+		if skip != nil && skip(child) {
+			continue
+		}
+		// 损坏的存档中文件夹可能互相引用
+		if child.nid == parent.nid || containsPstFolder(*folderList, child.nid) {
+			continue
+		}
+		if parent.name != "" {
+			child.name = parent.name + "/" + child.name
+		}
+		*folderList = append(*folderList, child)
+		ps.getSubfolders(child, folderList, skip)
+	}
+}
+
+func containsPstFolder(folders []pstFolder, nid uint32) bool {
+	for _, folder := range folders {
+		if folder.nid == nid {
+			return true
+		}
+	}
+	return false
+}
+
+func (ps *PstSource) getSentFolder() (pstFolder, error) {
+	if folder, err := ps.storeFolder(prIPMSentMailEntryID); err == nil {
+		return folder, nil
+	}
+
+	subtree, err := ps.storeFolder(prIPMSubtreeEntryID)
+	if err != nil {
+		return pstFolder{}, err
+	}
+	children, err := ps.childFolders(subtree.nid)
+	if err != nil {
+		return pstFolder{}, err
+	}
+	for _, child := range children {
+		for _, name := range maildirSentFolders {
+			if strings.EqualFold(child.name, name) {
+				return child, nil
+			}
+		}
+	}
+	return pstFolder{}, fmt.Errorf("存档中没有发送文件夹")
+}
+
+// storeFolder 读取消息存储中保存的EntryID属性（如IPM根目录、已发送邮件），
+// EntryID的最后4个字节是文件夹的NID
+func (ps *PstSource) storeFolder(propID uint16) (pstFolder, error) {
+	store, err := ps.nodeProps(pstNidMessageStore)
+	if err != nil {
+		return pstFolder{}, err
+	}
+	entryID := store.binary(propID)
+	if len(entryID) < 24 {
+		return pstFolder{}, fmt.Errorf("消息存储中缺少属性 0x%04X", propID)
+	}
+	nid := binary.LittleEndian.Uint32(entryID[20:])
+	props, err := ps.nodeProps(nid)
+	if err != nil {
+		return pstFolder{}, err
+	}
+	return pstFolder{nid: nid, name: props.str(prDisplayName), isMail: true}, nil
+}
+
+func (ps *PstSource) nodeProps(nid uint32) (mapiProps, error) {
+	node, err := ps.pst.node(nid)
+	if err != nil {
+		return mapiProps{}, err
+	}
+	heap, err := ps.pst.openHeap(node)
+	if err != nil {
+		return mapiProps{}, err
+	}
+	return heap.propertyContext()
+}
+
+// table 读取文件夹的层次表或内容表，表节点的NID由文件夹NID替换类型位得到
+func (ps *PstSource) table(folderNid uint32, nidType uint32) ([]mapiProps, error) {
+	node, err := ps.pst.node(folderNid&^0x1F | nidType)
+	if err != nil {
+		return nil, err
+	}
+	heap, err := ps.pst.openHeap(node)
+	if err != nil {
+		return nil, err
+	}
+	return heap.tableContext()
+}
+
+func (ps *PstSource) childFolders(folderNid uint32) ([]pstFolder, error) {
+	rows, err := ps.table(folderNid, pstNidTypeHierarchy)
+	if err != nil {
+		return nil, err
+	}
+	var folders []pstFolder
+	for _, row := range rows {
+		nid, ok := row.int32(prLtpRowID)
+		if !ok {
+			continue
+		}
+		name := row.str(prDisplayName)
+		class := row.str(prContainerClass)
+		if name == "" || class == "" {
+			if props, err := ps.nodeProps(uint32(nid)); err == nil {
+				name = props.str(prDisplayName)
+				class = props.str(prContainerClass)
+			}
+		}
+		// 没有容器类的文件夹按邮件文件夹处理，与Outlook一致
+		isMail := class == "" || strings.HasPrefix(class, "IPF.Note")
+		folders = append(folders, pstFolder{nid: uint32(nid), name: name, isMail: isMail})
+	}
+	return folders, nil
+}
+
+// getEmailsInDateRange 先用内容表中的时间列过滤（相当于COM的Restrict），再读取符合条件的邮件
+func (ps *PstSource) getEmailsInDateRange(folder pstFolder, isSent bool, startDate, endDate time.Time) ([]EmailInfo, error) {
+	rows, err := ps.table(folder.nid, pstNidTypeContents)
+	if err != nil {
+		return nil, err
+	}
+
+	timeProp := uint16(prMessageDeliveryTime)
+	if isSent {
+		timeProp = prClientSubmitTime
+	}
+
+	var emails []EmailInfo
+	totalCount := len(rows)
+	fmt.Printf("  处理 %d 封邮件...\n", totalCount)
+	for i, row := range rows {
+		if (i+1)%50 == 0 {
+			fmt.Printf("  进度: %d/%d (%.1f%%)\n", i+1, totalCount, float64(i+1)/float64(totalCount)*100)
+		}
+		if t := row.time(timeProp); !t.IsZero() && !inDateRange(t, startDate, endDate) {
+			continue
+		}
+		nid, ok := row.int32(prLtpRowID)
+		if !ok {
+			continue
+		}
+
+		emailInfo, err := ps.readMessage(uint32(nid))
+		if err != nil {
+			continue
+		}
+		checkTime := emailInfo.ReceivedTime
+		if isSent {
+			checkTime = emailInfo.SentTime
+		}
+		if emailInfo.Subject != "" && inDateRange(checkTime, startDate, endDate) {
+			emails = append(emails, emailInfo)
+		}
+	}
+	return emails, nil
+}
+
+func (ps *PstSource) readMessage(nid uint32) (EmailInfo, error) {
+	node, err := ps.pst.node(nid)
+	if err != nil {
+		return EmailInfo{}, err
+	}
+	heap, err := ps.pst.openHeap(node)
+	if err != nil {
+		return EmailInfo{}, err
+	}
+	props, err := heap.propertyContext()
+	if err != nil {
+		return EmailInfo{}, err
+	}
+
+	var recipients []Recipient
+	if sub, ok := heap.subnodes[pstNidRecipientTbl]; ok {
+		if recipHeap, err := ps.pst.openHeap(sub); err == nil {
+			rows, _ := recipHeap.tableContext()
+			for _, row := range rows {
+				row.codepage = props.codepage
+				recipients = append(recipients, recipientFromProps(row))
+			}
+		}
+	}
+
+	return mapiEmailInfo(props, recipients), nil
+}
+
+func isPstInbox(name string) bool {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	for _, inbox := range pstInboxNames {
+		if lower == inbox {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 回复判断：按JWZ算法（https://www.jwz.org/doc/threading.html）用Message-ID、In-Reply-To和References
+// 建立父子关系；没有这些头时依次使用Outlook会话索引和主题匹配
+
+var (
+	messageIDPattern = regexp.MustCompile(`<([^<>\s]+)>`)
+	// 主题前缀：回复（Re、回复、答复、AW、SV）与转发（Fw、Fwd、转发、WG），可带 [2] 之类的计数，可重复出现
+	subjectPrefixPattern = regexp.MustCompile(`(?i)^\s*(re|fwd?|aw|wg|sv|vs|antw|回复|回覆|答复|答覆|转发|轉寄|转寄)\s*(\[\d+\])?\s*[:：]\s*`)
+	replyPrefixPattern   = regexp.MustCompile(`(?i)^(re|aw|sv|antw|回复|回覆|答复|答覆)$`)
+	// 邮件列表在主题前添加的 [标签]
+	subjectTagPattern = regexp.MustCompile(`^\s*\[[^\]]*\]\s*`)
+)
+
+// Outlook会话索引：22字节的头部（含会话GUID），每回复一次增加5字节
+const (
+	conversationIndexHeaderLen = 22
+	conversationIndexChildLen  = 5
+)
+
+// parseMessageIDs 提取头字段中的所有Message-ID（去掉尖括号）
+func parseMessageIDs(value string) []string {
+	var ids []string
+	for _, match := range messageIDPattern.FindAllStringSubmatch(value, -1) {
+		ids = append(ids, match[1])
+	}
+	if len(ids) == 0 {
+		// 少数客户端不加尖括号
+		for _, field := range strings.Fields(value) {
+			if strings.Contains(field, "@") {
+				ids = append(ids, strings.Trim(field, "<>,"))
+			}
+		}
+	}
+	return ids
+}
+
+func firstMessageID(value string) string {
+	if ids := parseMessageIDs(value); len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+// decodeThreadIndex 解码Outlook写入的Thread-Index头（base64编码的会话索引）
+func decodeThreadIndex(value string) []byte {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(data) < conversationIndexHeaderLen {
+		return nil
+	}
+	return data
+}
+
+// conversationIDFromIndex 与Outlook的ConversationID一致：会话索引第6到22字节（会话GUID）的十六进制形式
+func conversationIDFromIndex(index []byte) string {
+	if len(index) < conversationIndexHeaderLen {
+		return ""
+	}
+	return strings.ToUpper(hex.EncodeToString(index[6:conversationIndexHeaderLen]))
+}
+
+// normalizeSubject 去掉所有回复/转发前缀和列表标签，返回基础主题以及第一个前缀是否为回复
+func normalizeSubject(subject string) (string, bool) {
+	isReply := false
+	first := true
+	for {
+		if loc := subjectTagPattern.FindStringIndex(subject); loc != nil {
+			subject = subject[loc[1]:]
+			continue
+		}
+		match := subjectPrefixPattern.FindStringSubmatch(subject)
+		if match == nil {
+			break
+		}
+		if first {
+			isReply = replyPrefixPattern.MatchString(match[1])
+			first = false
+		}
+		subject = subject[len(match[0]):]
+	}
+	return strings.ToLower(strings.Join(strings.Fields(subject), " ")), isReply
+}
+
+// threadContainer 是JWZ算法中的容器，message为-1表示只在References中出现、本地没有的邮件
+type threadContainer struct {
+	id       string
+	message  int
+	parent   *threadContainer
+	children []*threadContainer
+}
+
+func (c *threadContainer) isAncestorOf(other *threadContainer) bool {
+	for p := other; p != nil; p = p.parent {
+		if p == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *threadContainer) setParent(parent *threadContainer) {
+	if c.parent == parent {
+		return
+	}
+	if c.parent != nil {
+		siblings := c.parent.children
+		for i, child := range siblings {
+			if child == c {
+				c.parent.children = append(siblings[:i], siblings[i+1:]...)
+				break
+			}
+		}
+	}
+	c.parent = parent
+	if parent != nil {
+		parent.children = append(parent.children, c)
+	}
+}
+
+// threadMessages 按JWZ算法的第一步建立容器表，返回Message-ID到容器的映射。
+// messages中的下标即容器的message值
+func threadMessages(messages []EmailInfo) map[string]*threadContainer {
+	containers := make(map[string]*threadContainer)
+	get := func(id string) *threadContainer {
+		c, ok := containers[id]
+		if !ok {
+			c = &threadContainer{id: id, message: -1}
+			containers[id] = c
+		}
+		return c
+	}
+
+	for i, msg := range messages {
+		if msg.MessageID == "" {
+			continue
+		}
+		container := get(msg.MessageID)
+		if container.message >= 0 {
+			// 重复的Message-ID（如同一封邮件在多个文件夹中），保留第一封
+			continue
+		}
+		container.message = i
+
+		references := msg.References
+		if msg.InReplyTo != "" && (len(references) == 0 || references[len(references)-1] != msg.InReplyTo) {
+			references = append(append([]string{}, references...), msg.InReplyTo)
+		}
+
+		// 依次连接References中相邻的两项，已有父节点或会形成环时不改变
+		var prev *threadContainer
+		for _, ref := range references {
+			current := get(ref)
+			if prev != nil && current.parent == nil && !current.isAncestorOf(prev) {
+				current.setParent(prev)
+			}
+			prev = current
+		}
+		// 邮件本身的父节点总是References的最后一项
+		if prev != nil && prev != container && !container.isAncestorOf(prev) {
+			container.setParent(prev)
+		} else if prev == nil {
+			container.setParent(nil)
+		}
+	}
+	return containers
+}
+
+// matchReplies 找出每封发送邮件回复的是哪封收到的邮件，返回received中的下标，-1表示不是回复或找不到原邮件
+func matchReplies(received, sent []EmailInfo) []int {
+	all := make([]EmailInfo, 0, len(received)+len(sent))
+	all = append(all, received...)
+	all = append(all, sent...)
+	containers := threadMessages(all)
+
+	byIndex := make(map[string]int)
+	bySubject := make(map[string][]int)
+	for i, msg := range received {
+		if len(msg.ConversationIndex) >= conversationIndexHeaderLen {
+			byIndex[hex.EncodeToString(msg.ConversationIndex)] = i
+		}
+		base, _ := normalizeSubject(msg.Subject)
+		if base != "" {
+			bySubject[base] = append(bySubject[base], i)
+		}
+	}
+	for _, indices := range bySubject {
+		sort.SliceStable(indices, func(a, b int) bool {
+			return messageTime(received[indices[a]]).Before(messageTime(received[indices[b]]))
+		})
+	}
+
+	matches := make([]int, len(sent))
+	for i, reply := range sent {
+		matches[i] = -1
+
+		// 1. 邮件头：父节点即被回复的邮件。父节点不在收到的邮件中时（如回复自己发出的邮件）不再猜测
+		if reply.InReplyTo != "" || len(reply.References) > 0 {
+			if container, ok := containers[reply.MessageID]; ok && container.message == len(received)+i {
+				if parent := container.parent; parent != nil && parent.message >= 0 && parent.message < len(received) {
+					matches[i] = parent.message
+				}
+			} else {
+				// 没有Message-ID的回复，直接查找被回复的邮件
+				parentID := reply.InReplyTo
+				if parentID == "" {
+					parentID = reply.References[len(reply.References)-1]
+				}
+				if parent, ok := containers[parentID]; ok && parent.message >= 0 && parent.message < len(received) {
+					matches[i] = parent.message
+				}
+			}
+			continue
+		}
+
+		// 2. Outlook会话索引：去掉最后一个5字节子块即为父邮件的索引
+		if n := len(reply.ConversationIndex); n >= conversationIndexHeaderLen+conversationIndexChildLen {
+			if parent, ok := byIndex[hex.EncodeToString(reply.ConversationIndex[:n-conversationIndexChildLen])]; ok {
+				matches[i] = parent
+				continue
+			}
+		}
+
+		// 3. 主题：只对带回复前缀的邮件使用，取回复之前的、同一会话且发件人在收件人中的最近一封。
+		// 任一方没有时间时（如没有日期列的CSV）不比较时间
+		base, isReply := normalizeSubject(reply.Subject)
+		if !isReply || base == "" {
+			continue
+		}
+		candidates := bySubject[base]
+		for j := len(candidates) - 1; j >= 0; j-- {
+			original := received[candidates[j]]
+			if originalTime, replyTime := messageTime(original), messageTime(reply); !originalTime.IsZero() && !replyTime.IsZero() && originalTime.After(replyTime) {
+				continue
+			}
+			if original.ConversationID != "" && reply.ConversationID != "" && original.ConversationID != reply.ConversationID {
+				continue
+			}
+			if !addressedTo(reply, original) {
+				continue
+			}
+			matches[i] = candidates[j]
+			break
+		}
+	}
+	return matches
+}
+
+// messageTime 返回邮件的发送时间，没有时使用接收时间。Outlook只为发出的邮件设置发送时间、
+// 为收到的邮件设置接收时间，matchReplies两个方向都使用时需要这样取
+func messageTime(email EmailInfo) time.Time {
+	if !email.SentTime.IsZero() {
+		return email.SentTime
+	}
+	return email.ReceivedTime
+}
+
+// addressedTo 判断回复是否发给了原邮件的发件人。收件人可能只有显示名称，因此同时比较名称和地址。
+// 任一方信息缺失（如部分数据源中发送的邮件没有发件人）时无法判断，不排除
+func addressedTo(reply, original EmailInfo) bool {
+	recipients := reply.recipientsOf(RecipientTo, RecipientCC)
+	if len(recipients) == 0 || original.SenderEmail == "" && original.SenderName == "" {
+		return true
+	}
+	for _, recipient := range recipients {
+		if recipient.Address != "" && strings.EqualFold(recipient.Address, original.SenderEmail) ||
+			recipient.Name != "" && strings.EqualFold(strings.TrimSpace(recipient.Name), strings.TrimSpace(original.SenderName)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		base    string
+		isReply bool
+	}{
+		{"季度预算", "季度预算", false},
+		{"RE: FW: 季度预算", "季度预算", true},
+		{"Fwd: Re: 季度预算", "季度预算", false},
+		{"回复：转发：季度预算", "季度预算", true},
+		{"答复: 季度预算", "季度预算", true},
+		{"RE[2]: re:  季度   预算 ", "季度 预算", true},
+		{"[dev-list] Re: [dev-list] Build  Failed", "build failed", true},
+		{"AW: WG: Angebot", "angebot", true},
+		{"Re：", "", true},
+		// 主题中间的Re:不是前缀
+		{"关于 Re: 的说明", "关于 re: 的说明", false},
+	}
+	for _, tt := range tests {
+		base, isReply := normalizeSubject(tt.subject)
+		if base != tt.base || isReply != tt.isReply {
+			t.Errorf("normalizeSubject(%q) = %q, %v, want %q, %v", tt.subject, base, isReply, tt.base, tt.isReply)
+		}
+	}
+}
+
+func TestParseMessageIDs(t *testing.T) {
+	tests := map[string][]string{
+		"<a@example.com>":                     {"a@example.com"},
+		"<a@example.com>\r\n <b@example.com>": {"a@example.com", "b@example.com"},
+		"a@example.com, b@example.com":        {"a@example.com", "b@example.com"},
+		"":                                    nil,
+	}
+	for value, want := range tests {
+		if got := parseMessageIDs(value); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseMessageIDs(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestThreadMessagesMissingMiddle(t *testing.T) {
+	// b 不在本地，c 的References为 a b，d 只有In-Reply-To: c
+	messages := []EmailInfo{
+		{MessageID: "a"},
+		{MessageID: "c", References: []string{"a", "b"}},
+		{MessageID: "d", InReplyTo: "c"},
+		// 重复的Message-ID只保留第一封
+		{MessageID: "a", References: []string{"d"}},
+	}
+	containers := threadMessages(messages)
+	b := containers["b"]
+	if b == nil || b.message != -1 || b.parent != containers["a"] {
+		t.Fatalf("missing message b = %+v", b)
+	}
+	if c := containers["c"]; c.message != 1 || c.parent != b {
+		t.Errorf("c parent = %+v", c.parent)
+	}
+	if d := containers["d"]; d.parent != containers["c"] {
+		t.Errorf("d parent = %+v", d.parent)
+	}
+	if a := containers["a"]; a.message != 0 || a.parent != nil {
+		t.Errorf("duplicate a = %+v", a)
+	}
+}
+
+func TestMatchReplies(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2025, 3, 3, hour, 0, 0, 0, time.UTC) }
+	index := func(children int) []byte {
+		data := make([]byte, conversationIndexHeaderLen+children*conversationIndexChildLen)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		return data
+	}
+	to := func(name, address string) []Recipient {
+		return []Recipient{{Name: name, Address: address, Type: RecipientTo}}
+	}
+
+	received := []EmailInfo{
+		{MessageID: "q1@example.com", Subject: "预算", SenderEmail: "pm@example.com", ReceivedTime: at(9)},
+		{MessageID: "q1@example.com", Subject: "预算（副本）", ReceivedTime: at(9)},
+		{Subject: "会议纪要", ConversationIndex: index(1), ReceivedTime: at(9)},
+		{Subject: "周报", SenderName: "王五", SenderEmail: "wangwu@example.com", ReceivedTime: at(9)},
+		{Subject: "周报", SenderName: "赵六", SenderEmail: "zhaoliu@example.com", ReceivedTime: at(10)},
+		{Subject: "[ops] 告警", SenderEmail: "ops@example.com", ReceivedTime: at(15)},
+	}
+	sent := []EmailInfo{
+		// 重复的Message-ID匹配到第一封
+		{MessageID: "r1@example.com", Subject: "RE: 预算", InReplyTo: "q1@example.com", SentTime: at(10)},
+		// 没有Message-ID的回复直接查找References的最后一项
+		{Subject: "RE: 预算", References: []string{"q1@example.com"}, SentTime: at(11)},
+		// 被回复的邮件不在收到的邮件中时不再用主题猜测
+		{MessageID: "r3@example.com", Subject: "RE: 预算", InReplyTo: "unknown@example.com", SentTime: at(11)},
+		// 会话索引去掉最后一个子块即为父邮件
+		{Subject: "答复: 会议纪要", ConversationIndex: index(2), SentTime: at(11)},
+		// 主题匹配取收件人中发件人发来的最近一封
+		{Subject: "回复：周报", Recipients: to("王五", ""), SentTime: at(11)},
+		{Subject: "RE: 周报", Recipients: to("", "zhaoliu@example.com"), SentTime: at(11)},
+		{Subject: "RE: 周报", Recipients: to("孙七", "sunqi@example.com"), SentTime: at(11)},
+		// 原邮件晚于回复
+		{Subject: "RE: [ops] 告警", SentTime: at(14)},
+		{Subject: "RE: [ops] 告警", SentTime: at(16)},
+		// 没有回复前缀的不按主题匹配
+		{Subject: "周报", SentTime: at(11)},
+	}
+	want := []int{0, 0, -1, 2, 3, 4, -1, -1, 5, -1}
+	if got := matchReplies(received, sent); !reflect.DeepEqual(got, want) {
+		t.Errorf("matchReplies = %v, want %v", got, want)
+	}
+}
+
+func TestConversationIDFromIndex(t *testing.T) {
+	data := make([]byte, conversationIndexHeaderLen+conversationIndexChildLen)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	index := decodeThreadIndex(" " + base64.StdEncoding.EncodeToString(data) + "\r\n")
+	if got := conversationIDFromIndex(index); got != "060708090A0B0C0D0E0F101112131415" {
+		t.Errorf("conversationIDFromIndex = %q", got)
+	}
+	if decodeThreadIndex(base64.StdEncoding.EncodeToString(data[:conversationIndexHeaderLen-1])) != nil || decodeThreadIndex("not base64") != nil {
+		t.Error("short or invalid Thread-Index decoded")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// unansweredEmail 是属于需要处理的分类（如需要批准、需要回复）、但在发送邮件中找不到回复的邮件
+type unansweredEmail struct {
+	Email    EmailInfo
+	Category *emailCategory
+	Age      float64 // 已等待的工作日数
+}
+
+// findUnansweredEmails 找出需要处理但尚未回复的邮件，按已等待的工作日从长到短排序。
+// 与回复统计不同，未读邮件同样列出。sentEmails应包含分析期间结束后发送的邮件，否则期间之后才回复的邮件也会列出
+func (oa *OutlookEmailAnalyzer) findUnansweredEmails(receivedEmails, sentEmails []EmailInfo, now time.Time) []unansweredEmail {
+	replied := make(map[int]bool)
+	for _, original := range matchReplies(receivedEmails, sentEmails) {
+		if original >= 0 {
+			replied[original] = true
+		}
+	}
+
+	var unanswered []unansweredEmail
+	seen := make(map[string]bool)
+	for i, email := range receivedEmails {
+		category := actionLabel(oa.classifier.labels(email))
+		if replied[i] || category == nil {
+			continue
+		}
+		// 同一封邮件可能出现在多个文件夹中
+		if email.MessageID != "" {
+			if seen[email.MessageID] {
+				continue
+			}
+			seen[email.MessageID] = true
+		}
+		unanswered = append(unanswered, unansweredEmail{
+			Email:    email,
+			Category: category,
+			Age:      oa.calendar.businessDays(email.ReceivedTime, now),
+		})
+	}
+
+	sort.SliceStable(unanswered, func(i, j int) bool {
+		if unanswered[i].Age != unanswered[j].Age {
+			return unanswered[i].Age > unanswered[j].Age
+		}
+		return unanswered[i].Email.ReceivedTime.Before(unanswered[j].Email.ReceivedTime)
+	})
+	return unanswered
+}
+
+func (oa *OutlookEmailAnalyzer) printUnanswered(unanswered []unansweredEmail, repliesUntilNow bool) {
+	fmt.Printf("\n📝 7. 待回复邮件 (需要处理但尚未回复，按等待时间排序):\n")
+	if !repliesUntilNow {
+		fmt.Printf("   注意: 未能读取分析期间之后的邮件，只在期间内查找回复，期间结束后才回复的邮件也会列出\n")
+	}
+	if len(unanswered) == 0 {
+		fmt.Printf("   无\n")
+		return
+	}
+	for i, item := range unanswered {
+		email := item.Email
+		sender := oa.contacts.label(oa.contacts.senderIdentity(email))
+		if sender == "" {
+			sender = "-"
+		}
+		folder := email.Folder
+		if folder == "" {
+			folder = "-"
+		}
+		fmt.Printf("   %d. [%s] %s\n", i+1, item.Category.Name, email.Subject)
+		fmt.Printf("      发件人: %s | 文件夹: %s | 收到: %s | 已等待 %.1f 个工作日\n",
+			sender, folder, email.ReceivedTime.In(oa.location).Format("2006-01-02 15:04"), item.Age)
+	}
+}
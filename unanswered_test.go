@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubMailSource 按日期范围从固定的邮件中筛选，并记录每次请求的范围
+type stubMailSource struct {
+	received, sent []EmailInfo
+	err            error
+	requests       []string
+}
+
+func (s *stubMailSource) ReceivedEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	s.requests = append(s.requests, fmt.Sprintf("received %s..%s", startDate.Format("01-02"), endDate.Format("01-02")))
+	return s.filter(s.received, startDate, endDate, func(e EmailInfo) time.Time { return e.ReceivedTime }), s.err
+}
+
+func (s *stubMailSource) SentEmails(account string, startDate, endDate time.Time) ([]EmailInfo, error) {
+	s.requests = append(s.requests, fmt.Sprintf("sent %s..%s", startDate.Format("01-02"), endDate.Format("01-02")))
+	return s.filter(s.sent, startDate, endDate, func(e EmailInfo) time.Time { return e.SentTime }), s.err
+}
+
+func (s *stubMailSource) filter(emails []EmailInfo, startDate, endDate time.Time, date func(EmailInfo) time.Time) []EmailInfo {
+	if s.err != nil {
+		return nil
+	}
+	var result []EmailInfo
+	for _, email := range emails {
+		if inDateRange(date(email), startDate, endDate) {
+			result = append(result, email)
+		}
+	}
+	return result
+}
+
+func (s *stubMailSource) Close() {}
+
+func TestFetchLaterEmails(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.Local) }
+	source := &stubMailSource{
+		received: []EmailInfo{{Subject: "in period", ReceivedTime: day(3).Add(10 * time.Hour)}, {Subject: "later", ReceivedTime: day(12).Add(9 * time.Hour)}},
+		sent:     []EmailInfo{{Subject: "RE: in period", SentTime: day(11).Add(15 * time.Hour)}},
+	}
+	oa := NewOutlookEmailAnalyzer(source)
+
+	received, sent, complete := oa.fetchLaterEmails("default", day(7), day(14))
+	if !complete || len(received) != 1 || received[0].Subject != "later" || len(sent) != 1 {
+		t.Errorf("later mail = %v / %v (complete %v)", received, sent, complete)
+	}
+	if fmt.Sprint(source.requests) != "[received 03-08..03-14 sent 03-08..03-14]" {
+		t.Errorf("requests = %v", source.requests)
+	}
+
+	// 期间截止到今天时不需要读取
+	source.requests = nil
+	if received, sent, complete := oa.fetchLaterEmails("default", day(14), day(14).Add(11*time.Hour)); !complete || received != nil || sent != nil || source.requests != nil {
+		t.Errorf("period ending today fetched %v", source.requests)
+	}
+
+	source.err = fmt.Errorf("offline")
+	if _, _, complete := oa.fetchLaterEmails("default", day(7), day(14)); complete {
+		t.Error("failed fetch reported as complete")
+	}
+}
+
+func TestFindUnansweredUsesLaterReplies(t *testing.T) {
+	oa := NewOutlookEmailAnalyzer(&stubMailSource{})
+	oa.calendar = newDefaultCalendar(time.UTC)
+	at := func(day, hour int) time.Time { return time.Date(2025, 3, day, hour, 0, 0, 0, time.UTC) }
+
+	received := []EmailInfo{
+		{Subject: "请审批三月预算", MessageID: "budget@example.com", SenderEmail: "boss@example.com", ReceivedTime: at(3, 10)},
+		{Subject: "项目进展请回复", MessageID: "status@example.com", SenderEmail: "pm@example.com", ReceivedTime: at(4, 10)},
+		{Subject: "周报", MessageID: "weekly@example.com", SenderEmail: "team@example.com", ReceivedTime: at(5, 10)},
+		// 同一封邮件出现在两个文件夹中
+		{Subject: "项目进展请回复", MessageID: "status@example.com", SenderEmail: "pm@example.com", ReceivedTime: at(4, 10), Folder: "项目"},
+	}
+	later := []EmailInfo{{Subject: "RE: 请审批三月预算", InReplyTo: "budget@example.com", SentTime: at(10, 9)}}
+	now := at(12, 10)
+
+	tests := []struct {
+		name string
+		sent []EmailInfo
+		want []string
+	}{
+		{"period only", nil, []string{"请审批三月预算", "项目进展请回复"}},
+		{"with later mail", later, []string{"项目进展请回复"}},
+	}
+	for _, tt := range tests {
+		unanswered := oa.findUnansweredEmails(received, tt.sent, now)
+		var got []string
+		for _, item := range unanswered {
+			got = append(got, item.Email.Subject)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: unanswered = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	unanswered := oa.findUnansweredEmails(received, later, now)
+	if unanswered[0].Category.Name != "需要回复" || unanswered[0].Age != 6 {
+		t.Errorf("item = %s, %.2f business days", unanswered[0].Category.Name, unanswered[0].Age)
+	}
+}
+
+func TestPrintUnansweredUsesContactLabel(t *testing.T) {
+	oa := NewOutlookEmailAnalyzer(&stubMailSource{})
+	oa.location = time.UTC
+	emails := []EmailInfo{
+		{Subject: "周报", SenderName: "Zhang San", SenderEmail: "zhangsan@corp.com"},
+		{Subject: "请回复", SenderName: "Zhang San", ReceivedTime: time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)},
+	}
+	oa.contacts.observeEmails(emails)
+	oa.contacts.finish()
+
+	output := captureStdout(t, func() {
+		oa.printUnanswered([]unansweredEmail{{Email: emails[1], Category: oa.classifier.categories()[0], Age: 1}}, true)
+	})
+	if !strings.Contains(output, "发件人: Zhang San <zhangsan@corp.com> |") {
+		t.Errorf("output = %q", output)
+	}
+}
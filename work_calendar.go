@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// workCalendar 描述工作时间：每周各天的工作时段、节假日，以及周末调休上班的日期。
+// 调休上班日使用周一的工作时段
+type workCalendar struct {
+	name     string
+	location *time.Location
+	weekly   [7][]workInterval // 按time.Weekday索引
+	holidays map[string]bool   // 日期格式 2006-01-02
+	workdays map[string]bool
+}
+
+// workInterval 是一天中的工作时段，以距离零点的分钟数表示
+type workInterval struct {
+	start int
+	end   int
+}
+
+const calendarDateLayout = "2006-01-02"
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+	"周日": time.Sunday, "周一": time.Monday, "周二": time.Tuesday, "周三": time.Wednesday,
+	"周四": time.Thursday, "周五": time.Friday, "周六": time.Saturday,
+}
+
+// newDefaultCalendar 返回周一至周五 9:00-18:00、没有节假日的日历
+func newDefaultCalendar(loc *time.Location) *workCalendar {
+	wc := &workCalendar{
+		name:     "默认（周一至周五 9:00-18:00）",
+		location: loc,
+		holidays: make(map[string]bool),
+		workdays: make(map[string]bool),
+	}
+	for day := time.Monday; day <= time.Friday; day++ {
+		wc.weekly[day] = []workInterval{{start: 9 * 60, end: 18 * 60}}
+	}
+	return wc
+}
+
+// loadWorkCalendar 按扩展名读取.ics或.yaml/.yml日历文件。
+// ICS文件只提供节假日和调休，工作时段使用默认值
+func loadWorkCalendar(path string, loc *time.Location) (*workCalendar, error) {
+	wc := newDefaultCalendar(loc)
+	wc.name = filepath.Base(path)
+
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ics":
+		err = wc.loadICS(path)
+	case ".yaml", ".yml":
+		err = wc.loadYAML(path)
+	default:
+		return nil, fmt.Errorf("不支持的日历文件格式: %s（可选 .ics、.yaml）", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取工作日历失败: %v", err)
+	}
+	return wc, nil
+}
+
+// loadICS 读取节假日日历（如中国法定节假日订阅）。全天事件的标题含“班”（补班、上班）视为调休上班，其余视为休息
+func (wc *workCalendar) loadICS(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// 展开折叠行：以空格或制表符开头的行是上一行的延续
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var inEvent bool
+	var summary string
+	var start, end time.Time
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		params := strings.Split(name, ";")
+		switch strings.ToUpper(params[0]) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent, summary, start, end = true, "", time.Time{}, time.Time{}
+			}
+		case "SUMMARY":
+			summary = value
+		case "DTSTART":
+			start = parseICSDate(value)
+		case "DTEND":
+			end = parseICSDate(value)
+		case "END":
+			if !inEvent || !strings.EqualFold(value, "VEVENT") {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				continue
+			}
+			// 全天事件的DTEND不包含在内
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			target := wc.holidays
+			if strings.Contains(summary, "班") {
+				target = wc.workdays
+			}
+			for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+				target[day.Format(calendarDateLayout)] = true
+			}
+		}
+	}
+	return nil
+}
+
+func parseICSDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if len(value) < 8 {
+		return time.Time{}
+	}
+	t, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// loadYAML 读取以下结构的YAML文件（只支持这一种结构，不依赖YAML库）:
+//
+//	timezone: Asia/Shanghai
+//	hours:
+//	  mon-fri: "09:00-12:00, 13:30-18:00"
+//	  sat: ""
+//	holidays:
+//	  - 2025-10-01..2025-10-08
+//	  - 2025-01-01
+//	workdays:            # 调休上班
+//	  - 2025-09-28
+func (wc *workCalendar) loadYAML(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	section := ""
+	hoursSet := false
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indented := line[0] == ' ' || line[0] == '\t'
+		line = strings.TrimSpace(line)
+
+		if !indented {
+			key, value, _ := strings.Cut(line, ":")
+			section = strings.ToLower(strings.TrimSpace(key))
+			value = unquoteYAML(value)
+			if section == "timezone" && value != "" {
+				loc, err := time.LoadLocation(value)
+				if err != nil {
+					return fmt.Errorf("第%d行: 无效的时区 %s", lineNo, value)
+				}
+				wc.location = loc
+			}
+			continue
+		}
+
+		switch section {
+		case "hours":
+			if !hoursSet {
+				// 一旦配置了hours，未列出的日子均为休息日
+				wc.weekly = [7][]workInterval{}
+				hoursSet = true
+			}
+			key, value, _ := strings.Cut(line, ":")
+			days, err := parseWeekdays(strings.TrimSpace(key))
+			if err != nil {
+				return fmt.Errorf("第%d行: %v", lineNo, err)
+			}
+			intervals, err := parseWorkIntervals(unquoteYAML(value))
+			if err != nil {
+				return fmt.Errorf("第%d行: %v", lineNo, err)
+			}
+			for _, day := range days {
+				wc.weekly[day] = intervals
+			}
+		case "holidays", "workdays":
+			if !strings.HasPrefix(line, "-") {
+				continue
+			}
+			target := wc.holidays
+			if section == "workdays" {
+				target = wc.workdays
+			}
+			if err := addDateRange(target, unquoteYAML(strings.TrimPrefix(line, "-"))); err != nil {
+				return fmt.Errorf("第%d行: %v", lineNo, err)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func unquoteYAML(value string) string {
+	return strings.Trim(strings.TrimSpace(value), `"'`)
+}
+
+// parseWeekdays 解析 "mon"、"mon-fri"、"sat,sun" 等写法
+func parseWeekdays(spec string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, part := range strings.Split(strings.ToLower(spec), ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok1 := weekdayNames[strings.TrimSpace(from)]
+		last, ok2 := first, true
+		if isRange {
+			last, ok2 = weekdayNames[strings.TrimSpace(to)]
+		}
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("无法识别的星期: %s", part)
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			days = append(days, day)
+			if day == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseWorkIntervals 解析 "09:00-12:00, 13:30-18:00"，空字符串表示休息
+func parseWorkIntervals(spec string) ([]workInterval, error) {
+	var intervals []workInterval
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(part, "-")
+		start, err1 := time.Parse("15:04", strings.TrimSpace(from))
+		end, err2 := time.Parse("15:04", strings.TrimSpace(to))
+		if !ok || err1 != nil || err2 != nil || !end.After(start) {
+			return nil, fmt.Errorf("无效的工作时段: %s", part)
+		}
+		intervals = append(intervals, workInterval{
+			start: start.Hour()*60 + start.Minute(),
+			end:   end.Hour()*60 + end.Minute(),
+		})
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start < intervals[j].start })
+	return intervals, nil
+}
+
+// addDateRange 解析 "2025-10-01" 或 "2025-10-01..2025-10-08"（含两端）
+func addDateRange(target map[string]bool, spec string) error {
+	from, to, isRange := strings.Cut(spec, "..")
+	start, err := time.Parse(calendarDateLayout, strings.TrimSpace(from))
+	if err != nil {
+		return fmt.Errorf("无效的日期: %s", spec)
+	}
+	end := start
+	if isRange {
+		if end, err = time.Parse(calendarDateLayout, strings.TrimSpace(to)); err != nil || end.Before(start) {
+			return fmt.Errorf("无效的日期范围: %s", spec)
+		}
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		target[day.Format(calendarDateLayout)] = true
+	}
+	return nil
+}
+
+// workingHours 返回某一天（按日历时区）的工作时段
+func (wc *workCalendar) workingHours(day time.Time) []workInterval {
+	key := day.Format(calendarDateLayout)
+	if wc.workdays[key] {
+		if len(wc.weekly[day.Weekday()]) > 0 {
+			return wc.weekly[day.Weekday()]
+		}
+		return wc.weekly[time.Monday]
+	}
+	if wc.holidays[key] {
+		return nil
+	}
+	return wc.weekly[day.Weekday()]
+}
+
+// businessDuration 计算两个时刻之间落在工作时段内的时长
+func (wc *workCalendar) businessDuration(start, end time.Time) time.Duration {
+	if !end.After(start) {
+		return 0
+	}
+	start, end = start.In(wc.location), end.In(wc.location)
+
+	var total time.Duration
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, wc.location)
+	for !day.After(end) {
+		for _, interval := range wc.workingHours(day) {
+			from := time.Date(day.Year(), day.Month(), day.Day(), 0, interval.start, 0, 0, wc.location)
+			to := time.Date(day.Year(), day.Month(), day.Day(), 0, interval.end, 0, 0, wc.location)
+			if from.Before(start) {
+				from = start
+			}
+			if to.After(end) {
+				to = end
+			}
+			if to.After(from) {
+				total += to.Sub(from)
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return total
+}
+
+// workdayLength 是一个标准工作日的工作时长（周一的工作时段，周一休息时取第一个有工作时段的日子）
+func (wc *workCalendar) workdayLength() time.Duration {
+	for _, day := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		var length time.Duration
+		for _, interval := range wc.weekly[day] {
+			length += time.Duration(interval.end-interval.start) * time.Minute
+		}
+		if length > 0 {
+			return length
+		}
+	}
+	return 8 * time.Hour
+}
+
+// businessDays 以工作日为单位表示两个时刻之间的工作时长
+func (wc *workCalendar) businessDays(start, end time.Time) float64 {
+	return float64(wc.businessDuration(start, end)) / float64(wc.workdayLength())
+}
+
+// businessDayEnd 返回t所属工作日的下班时刻：t早于当天最后一个工作时段的结束时刻时为当天，
+// 否则（下班后、周末、节假日）顺延到下一个有工作时段的日子
+func (wc *workCalendar) businessDayEnd(t time.Time) time.Time {
+	t = t.In(wc.location)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, wc.location)
+	// 日历可能一整年都没有工作时段，最多向后找一年
+	for i := 0; i <= 366; i, day = i+1, day.AddDate(0, 0, 1) {
+		intervals := wc.workingHours(day)
+		if len(intervals) == 0 {
+			continue
+		}
+		end := time.Date(day.Year(), day.Month(), day.Day(), 0, intervals[len(intervals)-1].end, 0, 0, wc.location)
+		if end.After(t) {
+			return end
+		}
+	}
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, wc.location)
+}
+
+// isWorkingTime 判断某一时刻是否在工作时段内
+func (wc *workCalendar) isWorkingTime(t time.Time) bool {
+	t = t.In(wc.location)
+	minute := t.Hour()*60 + t.Minute()
+	for _, interval := range wc.workingHours(t) {
+		if minute >= interval.start && minute < interval.end {
+			return true
+		}
+	}
+	return false
+}
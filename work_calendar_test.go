@@ -0,0 +1,206 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeCalendarFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadICSHolidays(t *testing.T) {
+	// 节选自常见的中国法定节假日订阅，含折叠行和带参数的DTSTART
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//holiday-cn//CN",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20251001",
+		"DTEND;VALUE=DATE:20251009",
+		"SUMMARY:国庆节、中秋",
+		" 节 休假",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20250928",
+		"SUMMARY:国庆节 补班",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20251011",
+		"DTEND;VALUE=DATE:20251012",
+		"SUMMARY:",
+		"\t调休上班",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:没有日期的事件",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	path := writeCalendarFile(t, "holidays.ics", ics)
+	wc, err := loadWorkCalendar(path, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wc.name != "holidays.ics" {
+		t.Errorf("name = %q", wc.name)
+	}
+	if len(wc.holidays) != 8 || !wc.holidays["2025-10-01"] || !wc.holidays["2025-10-08"] || wc.holidays["2025-10-09"] {
+		t.Errorf("holidays = %v, want 2025-10-01..2025-10-08", wc.holidays)
+	}
+	if !reflect.DeepEqual(wc.workdays, map[string]bool{"2025-09-28": true, "2025-10-11": true}) {
+		t.Errorf("workdays = %v", wc.workdays)
+	}
+	// ICS不改变工作时段
+	if !reflect.DeepEqual(wc.weekly, newDefaultCalendar(time.UTC).weekly) {
+		t.Errorf("weekly hours changed: %v", wc.weekly)
+	}
+}
+
+func TestLoadYAMLCalendar(t *testing.T) {
+	yaml := `# 工作时间配置
+timezone: "Asia/Shanghai"
+hours:
+  mon-fri: "13:30-18:00, 09:00-12:00"   # 午休
+  sat: '09:00-12:00'
+holidays:
+  - 2025-10-01..2025-10-03
+  - "2025-01-01"
+workdays:            # 调休上班
+  - 2025-09-28
+`
+	path := writeCalendarFile(t, "work.yaml", yaml)
+	wc, err := loadWorkCalendar(path, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wc.location.String() != "Asia/Shanghai" {
+		t.Errorf("location = %v", wc.location)
+	}
+	weekday := []workInterval{{9 * 60, 12 * 60}, {13*60 + 30, 18 * 60}}
+	for day := time.Monday; day <= time.Friday; day++ {
+		if !reflect.DeepEqual(wc.weekly[day], weekday) {
+			t.Errorf("%v hours = %v, want %v", day, wc.weekly[day], weekday)
+		}
+	}
+	if !reflect.DeepEqual(wc.weekly[time.Saturday], []workInterval{{9 * 60, 12 * 60}}) || wc.weekly[time.Sunday] != nil {
+		t.Errorf("weekend hours = %v / %v", wc.weekly[time.Saturday], wc.weekly[time.Sunday])
+	}
+	if !reflect.DeepEqual(wc.holidays, map[string]bool{"2025-10-01": true, "2025-10-02": true, "2025-10-03": true, "2025-01-01": true}) {
+		t.Errorf("holidays = %v", wc.holidays)
+	}
+	if !reflect.DeepEqual(wc.workdays, map[string]bool{"2025-09-28": true}) {
+		t.Errorf("workdays = %v", wc.workdays)
+	}
+}
+
+func TestLoadWorkCalendarErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"bad.yaml", "timezone: Mars/Olympus\n", "第1行: 无效的时区"},
+		{"bad.yaml", "hours:\n  mon-fry: \"09:00-18:00\"\n", "第2行: 无法识别的星期: mon-fry"},
+		{"bad.yaml", "hours:\n  mon: \"18:00-09:00\"\n", "第2行: 无效的工作时段"},
+		{"bad.yaml", "holidays:\n  - 2025-10-08..2025-10-01\n", "第2行: 无效的日期范围"},
+		{"bad.txt", "", "不支持的日历文件格式"},
+	}
+	for _, tt := range tests {
+		path := writeCalendarFile(t, tt.name, tt.content)
+		if _, err := loadWorkCalendar(path, time.UTC); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want %q", tt.content, err, tt.want)
+		}
+	}
+}
+
+func TestParseWeekdays(t *testing.T) {
+	tests := map[string][]time.Weekday{
+		"mon":     {time.Monday},
+		"Mon-Wed": {time.Monday, time.Tuesday, time.Wednesday},
+		"sat,sun": {time.Saturday, time.Sunday},
+		"fri-mon": {time.Friday, time.Saturday, time.Sunday, time.Monday},
+		"周一-周五":   {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	}
+	for spec, want := range tests {
+		got, err := parseWeekdays(spec)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("parseWeekdays(%q) = %v, %v, want %v", spec, got, err, want)
+		}
+	}
+}
+
+func TestBusinessDuration(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	at := func(day, hour, minute int) time.Time { return time.Date(2025, 9, day, hour, minute, 0, 0, loc) }
+	wc := newDefaultCalendar(loc)
+	wc.weekly[time.Monday] = []workInterval{{9 * 60, 12 * 60}, {13 * 60, 18 * 60}}
+	wc.holidays["2025-09-30"] = true // 周二
+	wc.workdays["2025-09-28"] = true // 周日调休，使用周一的工作时段
+
+	// 2025-09-26 是周五，09-29 是周一，10-01 是周三
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       time.Duration
+	}{
+		{"lunch break excluded", at(29, 11, 0), at(29, 14, 0), 2 * time.Hour},
+		// 周五17:00-18:00、周六休息、周日调休按周一的8小时、周一9:00-10:00
+		{"over a makeup weekend", at(26, 17, 0), at(29, 10, 0), 10 * time.Hour},
+		// 周一17:00-18:00、周二节假日、周三9:00-10:00
+		{"holiday skipped", at(29, 17, 0), time.Date(2025, 10, 1, 10, 0, 0, 0, loc), 2 * time.Hour},
+		{"reversed", at(29, 14, 0), at(29, 11, 0), 0},
+	}
+	for _, tt := range tests {
+		if got := wc.businessDuration(tt.start, tt.end); got != tt.want {
+			t.Errorf("%s: businessDuration = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if got := wc.workdayLength(); got != 8*time.Hour {
+		t.Errorf("workdayLength = %v", got)
+	}
+	if got := wc.businessDays(at(29, 9, 0), time.Date(2025, 10, 1, 13, 0, 0, 0, loc)); got != 1.5 {
+		t.Errorf("businessDays = %v, want 1.5", got)
+	}
+	if !wc.isWorkingTime(at(28, 9, 30)) || wc.isWorkingTime(at(29, 12, 30)) || wc.isWorkingTime(at(30, 10, 0)) {
+		t.Error("isWorkingTime ignores makeup workday, lunch break or holiday")
+	}
+}
+
+func TestBusinessDayEnd(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	wc := newDefaultCalendar(loc)
+	wc.holidays["2025-10-01"] = true
+	tests := []struct {
+		t    time.Time
+		want time.Time
+	}{
+		{time.Date(2025, 9, 30, 10, 0, 0, 0, loc), time.Date(2025, 9, 30, 18, 0, 0, 0, loc)},
+		{time.Date(2025, 9, 30, 18, 0, 0, 0, loc), time.Date(2025, 10, 2, 18, 0, 0, 0, loc)},
+		{time.Date(2025, 10, 4, 12, 0, 0, 0, loc), time.Date(2025, 10, 6, 18, 0, 0, 0, loc)},
+		// 其他时区的时刻按日历时区换算
+		{time.Date(2025, 9, 30, 2, 0, 0, 0, time.UTC), time.Date(2025, 9, 30, 18, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		if got := wc.businessDayEnd(tt.t); !got.Equal(tt.want) {
+			t.Errorf("businessDayEnd(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+
+	// 没有任何工作时段时不会无限循环
+	empty := &workCalendar{location: loc, holidays: map[string]bool{}, workdays: map[string]bool{}}
+	start := time.Date(2025, 9, 30, 10, 0, 0, 0, loc)
+	if got := empty.businessDayEnd(start); !got.Equal(time.Date(2025, 10, 1, 0, 0, 0, 0, loc)) {
+		t.Errorf("empty calendar businessDayEnd = %v", got)
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML 读取规则文件等配置使用的YAML子集（不依赖YAML库），结果与encoding/json解码得到的值类型相同：
+// map[string]interface{}、[]interface{}、string、float64、bool和nil。支持按缩进的映射和列表、
+// 列表项中的映射（- key: value）、单行或跨行的 [a, b] 列表、带引号的字符串和 # 注释；
+// 不支持 {a: b}、锚点、多文档和 | > 多行字符串
+func parseYAML(data []byte) (interface{}, error) {
+	var lines []yamlLine
+	text := strings.ReplaceAll(strings.TrimPrefix(string(data), "\ufeff"), "\r\n", "\n")
+	for i, raw := range strings.Split(text, "\n") {
+		content := stripYAMLComment(raw)
+		if strings.TrimSpace(content) == "" || strings.TrimSpace(content) == "---" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(content, " "), "\t") {
+			return nil, fmt.Errorf("第%d行: 不能用制表符缩进", i+1)
+		}
+		trimmed := strings.TrimLeft(content, " ")
+		lines = append(lines, yamlLine{no: i + 1, indent: len(content) - len(trimmed), text: strings.TrimRight(trimmed, " \t")})
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	p := &yamlParser{lines: lines}
+	value, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("第%d行: 缩进不正确", p.lines[p.pos].no)
+	}
+	return value, nil
+}
+
+type yamlLine struct {
+	no     int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func isYAMLListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block 读取从当前行开始、缩进为indent的映射或列表
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if isYAMLListItem(p.lines[p.pos].text) {
+		return p.list(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) list(indent int) ([]interface{}, error) {
+	items := []interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || !isYAMLListItem(line.text) {
+			break
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		switch {
+		case rest == "":
+			p.pos++
+			item, err := p.nested(indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		case isYAMLListItem(rest) || yamlKeyEnd(rest) >= 0:
+			// 列表项中的列表或映射：把本行改写为它的第一行
+			p.lines[p.pos] = yamlLine{no: line.no, indent: indent + len(line.text) - len(rest), text: rest}
+			item, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		default:
+			p.pos++
+			item, err := p.value(rest, line.no)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || line.indent == indent && isYAMLListItem(line.text) {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("第%d行: 缩进不正确", line.no)
+		}
+		end := yamlKeyEnd(line.text)
+		if end < 0 {
+			return nil, fmt.Errorf("第%d行: 应为 键: 值", line.no)
+		}
+		key := unquoteYAMLScalar(strings.TrimSpace(line.text[:end]))
+		if _, exists := result[key]; exists {
+			return nil, fmt.Errorf("第%d行: 重复的键 %s", line.no, key)
+		}
+		rest := strings.TrimSpace(line.text[end+1:])
+		p.pos++
+		if rest != "" {
+			value, err := p.value(rest, line.no)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = value
+			continue
+		}
+		// 值在下面的行中；列表可以与键对齐
+		if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLListItem(p.lines[p.pos].text) {
+			value, err := p.list(indent)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = value
+			continue
+		}
+		value, err := p.nested(indent)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// nested 读取缩进大于parent的下一级，没有下一级时为空值
+func (p *yamlParser) nested(parent int) (interface{}, error) {
+	if p.pos >= len(p.lines) || p.lines[p.pos].indent <= parent {
+		return nil, nil
+	}
+	return p.block(p.lines[p.pos].indent)
+}
+
+// value 解析键或列表项后面的值，[ 开头的列表可以延续到后面的行
+func (p *yamlParser) value(text string, lineNo int) (interface{}, error) {
+	if strings.HasPrefix(text, "{") {
+		return nil, fmt.Errorf("第%d行: 不支持 {} 形式的映射", lineNo)
+	}
+	if !strings.HasPrefix(text, "[") {
+		return parseYAMLScalar(text), nil
+	}
+	for !strings.HasSuffix(text, "]") {
+		if p.pos >= len(p.lines) {
+			return nil, fmt.Errorf("第%d行: 列表缺少 ]", lineNo)
+		}
+		text += " " + p.lines[p.pos].text
+		p.pos++
+	}
+	inner := strings.TrimSpace(text[1 : len(text)-1])
+	items := []interface{}{}
+	if inner == "" {
+		return items, nil
+	}
+	for _, part := range splitYAMLFlow(inner) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			// 允许末尾的逗号
+			continue
+		}
+		if strings.HasPrefix(part, "[") || strings.HasPrefix(part, "{") {
+			return nil, fmt.Errorf("第%d行: 不支持嵌套的 [] 或 {}", lineNo)
+		}
+		items = append(items, parseYAMLScalar(part))
+	}
+	return items, nil
+}
+
+// yamlKeyEnd 返回 "键:" 中冒号的位置，冒号后必须是空格或行尾，引号内的冒号不算；不是键值对时返回-1
+func yamlKeyEnd(text string) int {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 {
+				quote = c
+			}
+		case c == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			return i
+		case c == '[' || c == '{':
+			return -1
+		}
+	}
+	return -1
+}
+
+// stripYAMLComment 去掉引号外、行首或空白之后的 # 注释
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && yamlTokenStart(line, i):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// yamlTokenStart 判断位置i是否为一项的开头，只有开头的引号才是字符串的引号（don't 中的不是）
+func yamlTokenStart(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	return strings.IndexByte(" \t[,:", text[i-1]) >= 0
+}
+
+// splitYAMLFlow 按引号外的逗号拆分 [a, "b, c"] 中的各项
+func splitYAMLFlow(text string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && yamlTokenStart(text, i):
+			quote = c
+		case c == ',':
+			parts = append(parts, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, text[start:])
+}
+
+// parseYAMLScalar 解析标量：带引号的为字符串，否则识别 true/false、null/~ 和数字
+func parseYAMLScalar(text string) interface{} {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		return unquoteYAMLScalar(text)
+	}
+	switch strings.ToLower(text) {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null", "~":
+		return nil
+	}
+	if number, err := strconv.ParseFloat(text, 64); err == nil {
+		return number
+	}
+	return text
+}
+
+// unquoteYAMLScalar 去掉引号：双引号内按转义序列解析，单引号内两个连续的单引号表示一个单引号
+func unquoteYAMLScalar(text string) string {
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		if value, err := strconv.Unquote(text); err == nil {
+			return value
+		}
+		return text[1 : len(text)-1]
+	}
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'")
+	}
+	return text
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string // 按JSON比较
+	}{
+		{"mapping and scalars",
+			"name: 财务  # 注释\npriority: 5\nthreshold: 1.5\naction: true\nnote: ~\n",
+			`{"action":true,"name":"财务","note":null,"priority":5,"threshold":1.5}`},
+		{"block list of mappings",
+			"categories:\n  - name: a\n    rules:\n      - words: [x]\n  - name: b\n",
+			`{"categories":[{"name":"a","rules":[{"words":["x"]}]},{"name":"b"}]}`},
+		{"list aligned with its key",
+			"words:\n- approve\n- '批准'\nregex: []\n",
+			`{"regex":[],"words":["approve","批准"]}`},
+		{"flow list over several lines with trailing comma",
+			"contains: [发票, \"a, b\",\n   'don''t', don't,\n]\n",
+			`{"contains":["发票","a, b","don't","don't"]}`},
+		{"quotes, colons and hashes inside values",
+			"regex: \"^(待办|TODO)[:：]\"\nurl: http://example.com/#top\n\"key: x\": '# not a comment'\n",
+			`{"key: x":"# not a comment","regex":"^(待办|TODO)[:：]","url":"http://example.com/#top"}`},
+		{"nested list items and empty values",
+			"---\n- - a\n  - b\n-\n  - c\n- empty:\n",
+			`[["a","b"],["c"],{"empty":null}]`},
+		{"CRLF and BOM", "\ufeffdefault: 其他\r\n", `{"default":"其他"}`},
+		{"empty document", "# 只有注释\n", `null`},
+	}
+	for _, tt := range tests {
+		value, err := parseYAML([]byte(tt.yaml))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		data, _ := json.Marshal(value)
+		if string(data) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, data, tt.want)
+		}
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := map[string]string{
+		"a: 1\n  b: 2\n":         "第2行: 缩进不正确",
+		"a: 1\nb\n":              "第2行: 应为 键: 值",
+		"a: 1\na: 2\n":           "第2行: 重复的键 a",
+		"a: {b: 1}\n":            "不支持 {} 形式的映射",
+		"a: [1, 2\n":             "第1行: 列表缺少 ]",
+		"a: [[1]]\n":             "不支持嵌套",
+		"a:\n\t- b\n":            "第2行: 不能用制表符缩进",
+		"- a\nb: 1\n":            "第2行: 缩进不正确",
+		"categories:\n  - a\n x": "第3行",
+	}
+	for yaml, want := range tests {
+		if _, err := parseYAML([]byte(yaml)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err = %v, want %q", yaml, err, want)
+		}
+	}
+}